@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/vrnvu/temp/pkg/client"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// formatArchive is the NDJSON archive of the server, the other formats are the ones of quiz.Formats
+const formatArchive = "ndjson"
+
+// runExport writes the archive, or the questions in the format, to the file or to stdout without one.
+// A failed export removes the file rather than leaving part of the archive in it.
+func runExport(ctx context.Context, c *client.Client, stdout io.Writer, path string, format string) error {
+	export := func(w io.Writer) error {
+		if format == formatArchive {
+			return c.Export(ctx, w)
+		}
+		questions, err := c.ExportQuestions(ctx)
+		if err != nil {
+			return err
+		}
+		return quiz.WriteQuestions(format, w, questions)
+	}
+
+	if path == "" {
+		if err := export(stdout); err != nil {
+			return fmt.Errorf("exporting: %w", err)
+		}
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating archive: %w", err)
+	}
+	err = export(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("exporting: %w", err)
+	}
+	return nil
+}
+
+// runImport sends the archive, or the questions in the format, of the file or of stdin without one,
+// and prints what it changed. Questions are only sent once every one of them is valid.
+func runImport(ctx context.Context, c *client.Client, stdin io.Reader, out io.Writer, path string, format string, opts client.ImportOptions) error {
+	r := stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("opening archive: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var summary quiz.ImportSummary
+	if format == formatArchive {
+		var err error
+		if summary, err = c.Import(ctx, r, opts); err != nil {
+			return fmt.Errorf("importing: %w", err)
+		}
+	} else {
+		questions, err := quiz.ParseQuestions(format, r)
+		if err != nil {
+			return fmt.Errorf("reading questions:\n%w", err)
+		}
+		if summary, err = c.ImportQuestions(ctx, questions, opts); err != nil {
+			return fmt.Errorf("importing: %w", err)
+		}
+	}
+
+	printImportSummary(out, summary)
+	return nil
+}
+
+func printImportSummary(out io.Writer, summary quiz.ImportSummary) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	printer.Fprintf(w, "RECORDS\tCREATED\tOVERWRITTEN\tSKIPPED\t\n")
+	for _, kind := range []struct {
+		name   string
+		counts quiz.ImportCounts
+	}{
+		{name: "blobs", counts: summary.Blobs},
+		{name: "questions", counts: summary.Questions},
+		{name: "users", counts: summary.Users},
+		{name: "results", counts: summary.Results},
+		{name: "attempts", counts: summary.Attempts},
+	} {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t\n", printer.Sprintf(kind.name), kind.counts.Created, kind.counts.Overwritten, kind.counts.Skipped)
+	}
+	w.Flush()
+
+	if summary.DryRun {
+		printer.Fprintf(out, "\nDry run of %d records, nothing was imported\n", summary.Records)
+		return
+	}
+	printer.Fprintf(out, "\n%d records read\n", summary.Records)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/client"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// runAudit prints the latest events of the audit log, the latest first
+func runAudit(ctx context.Context, c *client.Client, out io.Writer) error {
+	events, err := c.AuditLog(ctx, client.AuditFilter{})
+	if err != nil {
+		return fmt.Errorf("reading the audit log: %w", err)
+	}
+
+	printAuditEvents(out, events)
+	return nil
+}
+
+func printAuditEvents(out io.Writer, events []quiz.AuditEvent) {
+	if len(events) == 0 {
+		printer.Fprintf(out, "No audit events\n")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	printer.Fprintf(w, "ID\tTIME\tACTOR\tACTION\tTARGET\tOUTCOME\tSOURCE\n")
+	for _, e := range events {
+		actor := e.Actor
+		switch {
+		case e.Admin:
+			actor = "(admin)"
+		case actor == "":
+			actor = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.Time.Local().Format(time.DateTime), actor, e.Action, e.Target, e.Outcome, e.SourceIP)
+	}
+	w.Flush()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// bell is ignored by readline but still triggers a redraw of the prompt,
+// we send one every second so the countdown in the label moves.
+const bell = 7
+
+// countdown is used as a promptui label, it is rendered again on every redraw
+type countdown struct {
+	text     string
+	deadline time.Time
+}
+
+func (c countdown) String() string {
+	remaining := max(time.Until(c.deadline), 0).Round(time.Second)
+	return fmt.Sprintf("[%s] %s", remaining, c.text)
+}
+
+// stdinPump is the only reader of the terminal, so a prompt that times out
+// does not leave a goroutine behind eating the keys of the next prompt.
+type stdinPump struct {
+	chunks  chan []byte
+	lock    sync.Mutex
+	pending []byte
+}
+
+func newStdinPump(r io.Reader) *stdinPump {
+	p := &stdinPump{chunks: make(chan []byte)}
+	go func() {
+		for {
+			b := make([]byte, 1024)
+			n, err := r.Read(b)
+			if n > 0 {
+				p.chunks <- b[:n]
+			}
+			if err != nil {
+				close(p.chunks)
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *stdinPump) unread(b []byte) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.pending = append(b, p.pending...)
+}
+
+func (p *stdinPump) readPending(b []byte) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+	return n
+}
+
+// timedStdin feeds one prompt until its deadline, then answers io.EOF
+type timedStdin struct {
+	pump      *stdinPump
+	deadline  <-chan time.Time
+	tick      *time.Ticker
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (p *stdinPump) until(deadline time.Time) *timedStdin {
+	return &timedStdin{
+		pump:     p,
+		deadline: time.After(time.Until(deadline)),
+		tick:     time.NewTicker(time.Second),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *timedStdin) Read(b []byte) (int, error) {
+	if n := s.pump.readPending(b); n > 0 {
+		return n, nil
+	}
+
+	select {
+	case <-s.done:
+		return 0, io.EOF
+	case <-s.deadline:
+		return 0, io.EOF
+	case <-s.tick.C:
+		b[0] = bell
+		return 1, nil
+	case chunk, ok := <-s.pump.chunks:
+		if !ok {
+			return 0, io.EOF
+		}
+		select {
+		case <-s.done:
+			// the prompt is gone, keep the keys for the next one
+			s.pump.unread(chunk)
+			return 0, io.EOF
+		default:
+		}
+		n := copy(b, chunk)
+		s.pump.unread(chunk[n:])
+		return n, nil
+	}
+}
+
+func (s *timedStdin) Close() error {
+	s.closeOnce.Do(func() {
+		s.tick.Stop()
+		close(s.done)
+	})
+	return nil
+}
@@ -1,40 +1,73 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"crypto/tls"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
-	"net/http"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/manifoldco/promptui"
+	"github.com/vrnvu/temp/internal/tracing"
+	"github.com/vrnvu/temp/pkg/client"
 	"github.com/vrnvu/temp/pkg/quiz"
+	"go.opentelemetry.io/otel/codes"
 )
 
-const (
-	apiURL             = "https://localhost:8080"
-	pathGetQuiz        = "quiz"
-	pathGetQuizResults = "quiz/%s"
-	pathPutQuizAnswer  = "quiz/%s"
-	pathGetStatistics  = "statistics/%s"
-)
+const apiURL = "https://localhost:8080"
+
+// printer of the messages in the language of --lang, English until main parses it
+var printer = quiz.NewPrinter()
 
 const usage = `
 Quiz CLI
 Usage:
-	cli --user <token> <command>
+	cli --user <user> [--lang <language>] [--category <category>] [--time-limit <duration>] [--question-time-limit <duration>] <command> [--name <quiz>] [--code <room>]
+	cli --user <admin-token> admin export|import [--format <format>] [--mode skip|overwrite|fail] [--dry-run] [<file>]
+	cli --user <admin-token> admin upload <image>
+	cli --user <admin-token> admin regrade [--dry-run] <question>
+	cli --user <admin-token> admin audit
 
 Commands:
-	quiz      Take a quiz
-	results   Show quiz results
-	statistics Show statistics
+	quiz      Take a quiz, --name takes a named quiz
+	practice  Review the questions due today
+	results   Show quiz results, --name only of a named quiz
+	statistics Show statistics, --name only of a named quiz
+	host      Open a live room of the named quiz --name, players join with its code
+	join      Play in the live room --code
+	admin export  Write the archive of every user, question, result and attempt to the file, or stdout.
+	              With --format gift, moodle, csv or markdown, only the questions in that format
+	admin import  Read an archive or, with --format, questions from the file, or stdin.
+	              --mode tells what to do with the records that already exist
+	admin upload  Store a PNG, JPEG, GIF or WebP image of up to 1 MiB and print its id,
+	              the questions of an archive attach it by that id
+	admin regrade Score the answers of the question again against its latest version,
+	              after a fix of its answer, and print the users whose results changed
+	admin audit   Show the latest admin and security relevant requests, the latest first
 Example:
 	cli --user user quiz
+	cli --user user practice
+	cli --user user --category geography quiz
+	cli --user user --lang es quiz
+	cli --user user --time-limit 1m --question-time-limit 15s quiz
+	cli --user user quiz --name security-101
+	cli --user user results --name security-101
 	cli --user user results
 	cli --user user statistics
+	cli --user user --question-time-limit 20s host --name security-101
+	cli --user alice join --code ABC123
+	cli --user admin-token admin export backup.ndjson
+	cli --user admin-token admin import --mode skip --dry-run backup.ndjson
+	cli --user admin-token admin import --format gift --mode overwrite questions.txt
+	cli --user admin-token admin upload diagram.png
+	cli --user admin-token admin regrade --dry-run 2
+	cli --user admin-token admin audit
 `
 
 func main() {
@@ -43,10 +76,19 @@ func main() {
 	flag.Usage = func() { fmt.Fprintf(os.Stderr, "%s\n", usage) }
 
 	var userKey string
-	flag.StringVar(&userKey, "user", "", "User authentication token")
-	flag.StringVar(&userKey, "u", "", "User authentication token")
+	flag.StringVar(&userKey, "user", "", "User name, or the admin token of the admin commands")
+	flag.StringVar(&userKey, "u", "", "User name, or the admin token of the admin commands")
+	var category string
+	flag.StringVar(&category, "category", "", "Only ask questions of this category")
+	var limits quizLimits
+	flag.DurationVar(&limits.timeLimit, "time-limit", 0, "Time limit of the whole quiz")
+	flag.DurationVar(&limits.questionTimeLimit, "question-time-limit", 0, "Time limit of every question")
+	var lang string
+	flag.StringVar(&lang, "lang", envLanguage(), "Language of the questions and messages, e.g. es, by default the one of LANG")
 	flag.Parse()
 
+	printer = quiz.NewPrinter(quiz.ParseLocales(lang)...)
+
 	if userKey == "" {
 		logger.Error("Error: --user is required")
 		flag.Usage()
@@ -60,138 +102,342 @@ func main() {
 		os.Exit(1)
 	}
 
-	command := args[0]
+	command, commandArgs := args[0], args[1:]
+	if command == "admin" && len(commandArgs) > 0 {
+		command, commandArgs = command+" "+commandArgs[0], commandArgs[1:]
+	}
+	commandFlags := flag.NewFlagSet(command, flag.ExitOnError)
+	commandFlags.Usage = flag.Usage
+	var quizName string
+	commandFlags.StringVar(&quizName, "name", "", "Named quiz, e.g. security-101")
+	var roomCode string
+	commandFlags.StringVar(&roomCode, "code", "", "Code of a live room")
+	var format string
+	commandFlags.StringVar(&format, "format", formatArchive, "Format of the file: ndjson, gift, moodle, csv or markdown")
+	var importOpts client.ImportOptions
+	commandFlags.StringVar(&importOpts.Mode, "mode", quiz.ImportFail, "What to do with existing records: skip, overwrite or fail")
+	commandFlags.BoolVar(&importOpts.DryRun, "dry-run", false, "Only report what the import or the regrade would change")
+	commandFlags.Parse(commandArgs)
+
+	tracerProvider, shutdownTracing, err := tracing.FromEnv(context.Background(), "quiz-cli")
+	if err != nil {
+		logger.Error("Error configuring the traces", "error", err)
+		os.Exit(1)
+	}
+
+	// answers are sent with an Idempotency-Key, so a submission that timed out is retried safely
+	c, err := client.FromConfig(&client.Config{
+		BaseURL:        apiURL,
+		Token:          userKey,
+		TLS:            &tls.Config{InsecureSkipVerify: true},
+		AttemptTimeout: 10 * time.Second,
+		Language:       lang,
+		TracerProvider: tracerProvider,
+	})
+	if err != nil {
+		logger.Error("Error creating the client", "error", err)
+		os.Exit(1)
+	}
+
+	// the calls of the command are spans of its trace, which the server continues
+	ctx, span := tracerProvider.Tracer("github.com/vrnvu/temp/cmd/cli").Start(context.Background(), "cli "+command)
+	// the command itself reports the server being down
+	if err := c.Discover(ctx); errors.Is(err, client.ErrUnsupportedVersions) {
+		logger.Error("Error discovering the api version, upgrade the cli", "error", err)
+		os.Exit(1)
+	}
+
 	switch command {
 	case "quiz":
-		runQuiz(userKey)
+		err = runQuiz(ctx, c, userKey, category, limits, quizName)
+	case "practice":
+		err = runPractice(ctx, c, os.Stdout, userKey, category)
 	case "results":
-		showResults(userKey)
+		err = showResults(ctx, c, os.Stdout, userKey, quizName)
 	case "statistics":
-		showStatistics(userKey)
+		err = showStatistics(ctx, c, os.Stdout, userKey, quizName)
+	case "host":
+		if quizName == "" {
+			logger.Error("Error: host needs --name")
+			flag.Usage()
+			os.Exit(1)
+		}
+		err = runHost(ctx, c, quizName, limits)
+	case "join":
+		if roomCode == "" {
+			logger.Error("Error: join needs --code")
+			flag.Usage()
+			os.Exit(1)
+		}
+		err = runJoin(ctx, c, userKey, roomCode)
+	case "admin export":
+		err = runExport(ctx, c, os.Stdout, commandFlags.Arg(0), format)
+	case "admin import":
+		err = runImport(ctx, c, os.Stdin, os.Stdout, commandFlags.Arg(0), format, importOpts)
+	case "admin upload":
+		if commandFlags.Arg(0) == "" {
+			logger.Error("Error: upload needs an image")
+			flag.Usage()
+			os.Exit(1)
+		}
+		err = runUpload(ctx, c, os.Stdout, commandFlags.Arg(0))
+	case "admin regrade":
+		if commandFlags.Arg(0) == "" {
+			logger.Error("Error: regrade needs a question")
+			flag.Usage()
+			os.Exit(1)
+		}
+		err = runRegrade(ctx, c, os.Stdout, commandFlags.Arg(0), importOpts.DryRun)
+	case "admin audit":
+		err = runAudit(ctx, c, os.Stdout)
 	default:
 		logger.Error("Unknown command", "command", command)
 		flag.Usage()
 		os.Exit(1)
 	}
+
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	// the spans not sent yet are lost on exit
+	if err := shutdownTracing(context.Background()); err != nil {
+		logger.Error("Error sending the traces", "error", err)
+	}
+
+	if err != nil {
+		printProblem(os.Stdout, err)
+		os.Exit(1)
+	}
 }
 
-func newHTTPSClient() *http.Client {
-	return &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
+// envLanguage of the locale of the environment like `es_ES.UTF-8` as a language tag like `es-ES`,
+// empty for the C and POSIX locales
+func envLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		value, _, _ = strings.Cut(value, ".")
+		value, _, _ = strings.Cut(value, "@")
+		if value == "C" || value == "POSIX" {
+			return ""
+		}
+		return strings.ReplaceAll(value, "_", "-")
 	}
+	return ""
 }
 
-func runQuiz(userKey string) {
-	client := newHTTPSClient()
-	url := fmt.Sprintf("%s/%s", apiURL, pathGetQuiz)
-	resp, err := client.Get(url)
+type quizLimits struct {
+	timeLimit         time.Duration
+	questionTimeLimit time.Duration
+}
+
+func runQuiz(ctx context.Context, c *client.Client, userKey string, category string, limits quizLimits, quizName string) error {
+	session, err := c.StartSession(ctx, userKey, client.SessionOptions{
+		Quiz:              quizName,
+		Filter:            client.Filter{Category: category},
+		TimeLimit:         limits.timeLimit,
+		QuestionTimeLimit: limits.questionTimeLimit,
+	})
 	if err != nil {
-		fmt.Printf("Error getting questions: %v\n", err)
-		return
+		return fmt.Errorf("starting quiz: %w", err)
 	}
-	defer resp.Body.Close()
 
-	var questions []quiz.Question
-	err = json.NewDecoder(resp.Body).Decode(&questions)
-	if err != nil {
-		fmt.Printf("Error decoding questions: %v\n", err)
-		return
+	// the server enforces the limits with its own clock, the local one is only for display
+	var quizDeadline time.Time
+	if session.TimeLimit() > 0 {
+		quizDeadline = time.Now().Add(session.TimeLimit())
 	}
 
-	answers := make(map[uint64]string)
-	for _, q := range questions {
-		prompt := promptui.Select{
-			Label: q.Text,
-			Items: q.Options,
+	var pump *stdinPump
+	if session.TimeLimit() > 0 || session.QuestionTimeLimit() > 0 {
+		pump = newStdinPump(os.Stdin)
+	}
+
+	late := 0
+	for i, q := range session.Questions {
+		deadline := quizDeadline
+		if session.QuestionTimeLimit() > 0 {
+			questionDeadline := time.Now().Add(session.QuestionTimeLimit())
+			if deadline.IsZero() || questionDeadline.Before(deadline) {
+				deadline = questionDeadline
+			}
 		}
 
-		_, value, err := prompt.Run()
+		answers := quiz.QuizAnswer{}
+		over := false
+		label := showQuestion(os.Stdout, c, q)
+		index, err := askTimedQuestion(label, q.Options, pump, deadline)
+		switch {
+		case err == nil:
+			answers[q.ID] = q.OptionIDs[index]
+		case !deadline.IsZero() && !time.Now().Before(deadline):
+			printer.Printf("Time is up!\n")
+			answers[q.ID] = ""
+			// nothing left to answer once the whole quiz is over
+			over = !quizDeadline.IsZero() && !time.Now().Before(quizDeadline)
+			if over {
+				for _, remaining := range session.Questions[i+1:] {
+					answers[remaining.ID] = ""
+				}
+			}
+		default:
+			return fmt.Errorf("prompt failed: %w", err)
+		}
+
+		records, err := c.SubmitSessionAnswers(ctx, userKey, session.ID, answers)
 		if err != nil {
-			fmt.Printf("Prompt failed %v\n", err)
-			return
+			return fmt.Errorf("submitting answers: %w", err)
+		}
+		for _, record := range records {
+			if record.Late {
+				late++
+			}
 		}
+		printExplanations(os.Stdout, records)
 
-		answers[q.ID] = value
+		if over {
+			break
+		}
 	}
 
-	body := bytes.NewBuffer(nil)
-	err = json.NewEncoder(body).Encode(answers)
-	if err != nil {
-		fmt.Printf("Error marshalling answers: %v\n", err)
-		return
+	if late > 0 {
+		printer.Printf("\n%d answers arrived too late and score zero\n", late)
+	}
+	printer.Printf("\nAnswers submitted successfully!\n")
+	return nil
+}
+
+// askTimedQuestion shows a live countdown and gives up at the deadline, a zero deadline means no limit.
+// It returns the index of the option chosen, in the order the server sent them.
+func askTimedQuestion(label string, options []string, pump *stdinPump, deadline time.Time) (int, error) {
+	if deadline.IsZero() {
+		prompt := promptui.Select{Label: label, Items: options}
+		index, _, err := prompt.Run()
+		return index, err
 	}
 
-	url = fmt.Sprintf("%s/%s", apiURL, fmt.Sprintf(pathPutQuizAnswer, userKey))
-	req, err := http.NewRequest(http.MethodPut, url, body)
+	stdin := pump.until(deadline)
+	defer stdin.Close()
+
+	prompt := promptui.Select{
+		Label: countdown{text: label, deadline: deadline},
+		Items: options,
+		Stdin: stdin,
+	}
+	index, _, err := prompt.Run()
+	return index, err
+}
+
+func runPractice(ctx context.Context, c *client.Client, out io.Writer, userKey string, category string) error {
+	questions, err := c.ReviewQuestions(ctx, userKey, client.Filter{Category: category})
 	if err != nil {
-		fmt.Printf("Error creating request: %v\n", err)
-		return
+		return fmt.Errorf("getting questions: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err = client.Do(req)
+	if len(questions) == 0 {
+		printer.Fprintf(out, "Nothing to review, come back later!\n")
+		return nil
+	}
+
+	printer.Fprintf(out, "%d questions to review\n", len(questions))
+	answers, err := askQuestions(c, out, questions)
 	if err != nil {
-		fmt.Printf("Error submitting answers: %v\n", err)
-		return
+		return fmt.Errorf("prompt failed: %w", err)
 	}
-	defer resp.Body.Close()
 
-	fmt.Println("\nAnswers submitted successfully!")
+	if err := c.SubmitAnswers(ctx, userKey, answers); err != nil {
+		return fmt.Errorf("submitting answers: %w", err)
+	}
+
+	printer.Fprintf(out, "\nPractice submitted successfully!\n")
+	return nil
 }
 
-func showResults(userKey string) {
-	client := newHTTPSClient()
-	url := fmt.Sprintf("%s/%s", apiURL, fmt.Sprintf(pathGetQuizResults, userKey))
-	resp, err := client.Get(url)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		switch resp.StatusCode {
-		case http.StatusNotFound:
-			fmt.Println("User not found")
-		case http.StatusBadRequest:
-			fmt.Println("bad request")
-		default:
-			fmt.Printf("Error getting results: %v\n", err)
+func askQuestions(c *client.Client, out io.Writer, questions []quiz.Question) (quiz.QuizAnswer, error) {
+	answers := make(quiz.QuizAnswer)
+	for _, q := range questions {
+		prompt := promptui.Select{
+			Label: showQuestion(out, c, q),
+			Items: q.Options,
 		}
-		return
+
+		index, _, err := prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+
+		// the ID of the option, its text may be translated or repeated
+		answers[q.ID] = q.OptionIDs[index]
 	}
-	defer resp.Body.Close()
+	return answers, nil
+}
 
-	var results quiz.QuizResults
-	err = json.NewDecoder(resp.Body).Decode(&results)
+func showResults(ctx context.Context, c *client.Client, out io.Writer, userKey string, quizName string) error {
+	results, err := c.Results(ctx, userKey, quizName)
 	if err != nil {
-		fmt.Printf("Error decoding results: %v\n", err)
-		return
+		return fmt.Errorf("getting results: %w", err)
 	}
 
-	fmt.Println(results)
+	printResults(out, results)
+	return nil
 }
 
-func showStatistics(userKey string) {
-	client := newHTTPSClient()
-	url := fmt.Sprintf("%s/%s", apiURL, fmt.Sprintf(pathGetStatistics, userKey))
-	resp, err := client.Get(url)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		switch resp.StatusCode {
-		case http.StatusNotFound:
-			fmt.Println("user not found")
-		case http.StatusBadRequest:
-			fmt.Println("bad request, not enough users for statistics")
-		default:
-			fmt.Printf("Error getting statistics: %v\n", err)
-		}
+func printResults(out io.Writer, results quiz.QuizResults) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	printer.Fprintf(w, "CATEGORY\tCORRECT\tTOTAL\tACCURACY\t\n")
+	for _, c := range results.Categories {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t\n", c.Category, c.Correct, c.Total, accuracy(c.Correct, c.Total))
+	}
+	fmt.Fprintf(w, "%s\t%d\t%d\t%s\t\n", printer.Sprintf("all"), results.Correct, results.Total, accuracy(results.Correct, results.Total))
+	w.Flush()
+
+	if len(results.Attempts) == 0 {
 		return
 	}
 
-	var statistics quiz.StatisticsResults
-	err = json.NewDecoder(resp.Body).Decode(&statistics)
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	printer.Fprintf(w, "ATTEMPT\tSTARTED\tCORRECT\tTOTAL\tPASSED\t\n")
+	for i, a := range results.Attempts {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%t\t\n", i+1, a.StartedAt.Format(time.DateTime), a.Correct, a.Total, a.Passed)
+	}
+	w.Flush()
+}
+
+func accuracy(correct uint64, total uint64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", 100*float64(correct)/float64(total))
+}
+
+// averageAccuracy of the other users, their averages are not whole answers
+func averageAccuracy(avgCorrect float64, avgTotal float64) string {
+	if avgTotal == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", 100*avgCorrect/avgTotal)
+}
+
+func showStatistics(ctx context.Context, c *client.Client, out io.Writer, userKey string, quizName string) error {
+	statistics, err := c.Statistics(ctx, userKey, client.StatisticsOptions{Quiz: quizName})
 	if err != nil {
-		fmt.Printf("Error decoding statistics: %v\n", err)
-		return
+		return fmt.Errorf("getting statistics: %w", err)
 	}
 
-	fmt.Println(statistics)
+	printStatistics(out, statistics)
+	return nil
+}
+
+func printStatistics(out io.Writer, statistics quiz.StatisticsResults) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	printer.Fprintf(w, "CATEGORY\tCORRECT\tTOTAL\tACCURACY\tAVG\t\n")
+	for _, c := range statistics.Categories {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t\n", c.Category, c.Correct, c.Total, accuracy(c.Correct, c.Total), averageAccuracy(c.AvgCorrect, c.AvgTotal))
+	}
+	fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t\n", printer.Sprintf("all"), statistics.Correct, statistics.Total, accuracy(statistics.Correct, statistics.Total), averageAccuracy(statistics.AvgCorrect, statistics.AvgTotal))
+	w.Flush()
 }
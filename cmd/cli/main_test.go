@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/temp/internal/server"
+	"github.com/vrnvu/temp/pkg/client"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const testAdminToken = "admin"
+
+func testClient(t *testing.T, userKey string) *client.Client {
+	handler, err := server.FromConfig(&server.Config{
+		Slog:               slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		RequestIDGenerator: func() string { return "123" },
+		AdminToken:         testAdminToken,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	s := httptest.NewTLSServer(handler)
+	t.Cleanup(s.Close)
+
+	c, err := client.FromConfig(&client.Config{
+		BaseURL: s.URL,
+		Token:   userKey,
+		TLS:     s.Client().Transport.(*http.Transport).TLSClientConfig,
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if err := c.Discover(context.Background()); err != nil {
+		t.Fatalf("failed to discover the api: %v", err)
+	}
+	return c
+}
+
+func TestShowResults(t *testing.T) {
+	t.Parallel()
+	c := testClient(t, "user")
+	ctx := context.Background()
+
+	if err := c.SubmitAnswers(ctx, "user", quiz.QuizAnswer{0: "Paris", 2: "1"}); err != nil {
+		t.Fatalf("failed to submit answers: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := showResults(ctx, c, &out, "user", ""); err != nil {
+		t.Fatalf("showResults() got: %v", err)
+	}
+	if !strings.Contains(out.String(), "geography") || !strings.Contains(out.String(), "50%") {
+		t.Fatalf("expected the results per category, got:\n%s", out.String())
+	}
+
+	err := showResults(ctx, c, &out, "unknown", "")
+	if !client.HasCode(err, quiz.CodeUserNotFound) {
+		t.Fatalf("showResults() of an unknown user got: %v", err)
+	}
+}
+
+func TestShowStatistics(t *testing.T) {
+	t.Parallel()
+	c := testClient(t, "user")
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	err := showStatistics(ctx, c, &out, "user", "")
+	if !client.HasCode(err, quiz.CodeNotEnoughUsers) {
+		t.Fatalf("showStatistics() of a single user got: %v", err)
+	}
+
+	printProblem(&out, err)
+	if out.String() != "Not enough users for statistics yet\n" {
+		t.Fatalf("expected the problem explained, got: %s", out.String())
+	}
+
+	if _, err := c.CreateUser(ctx, "other"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	out.Reset()
+	if err := showStatistics(ctx, c, &out, "user", ""); err != nil || !strings.Contains(out.String(), "ACCURACY") || !strings.Contains(out.String(), "all") {
+		t.Fatalf("showStatistics() got: %v, %s", err, out.String())
+	}
+}
+
+func TestRunPracticeUnknownUser(t *testing.T) {
+	t.Parallel()
+	c := testClient(t, "unknown")
+
+	var out bytes.Buffer
+	err := runPractice(context.Background(), c, &out, "unknown", "")
+	if !client.HasCode(err, quiz.CodeUserNotFound) || out.Len() != 0 {
+		t.Fatalf("runPractice() of an unknown user got: %v, %s", err, out.String())
+	}
+}
+
+func TestPrintProblem(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "known code", err: &quiz.Problem{Code: quiz.CodeQuizClosed}, want: "Quiz is closed\n"},
+		{name: "other code", err: &quiz.Problem{Code: quiz.CodeInvalidOption, Detail: "invalid option"}, want: "Error invalid_option: invalid option\n"},
+		{name: "not a problem", err: io.EOF, want: "Error EOF\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printProblem(&out, test.err)
+			if out.String() != test.want {
+				t.Fatalf("printProblem() got: %q, want: %q", out.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestPrintProblemTranslated(t *testing.T) {
+	t.Parallel()
+	english := printer
+	t.Cleanup(func() { printer = english })
+	printer = quiz.NewPrinter(quiz.ParseLocales("es-ES")...)
+
+	var out bytes.Buffer
+	printProblem(&out, &quiz.Problem{Code: quiz.CodeQuizClosed})
+	if want := "El cuestionario está cerrado\n"; out.String() != want {
+		t.Fatalf("printProblem() got: %q, want: %q", out.String(), want)
+	}
+}
+
+func TestRunExportImport(t *testing.T) {
+	t.Parallel()
+	c := testClient(t, testAdminToken)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "backup.ndjson")
+	if err := runExport(ctx, c, io.Discard, path, formatArchive); err != nil {
+		t.Fatalf("runExport() got: %v", err)
+	}
+
+	var out bytes.Buffer
+	err := runImport(ctx, c, nil, &out, path, formatArchive, client.ImportOptions{Mode: quiz.ImportSkip, DryRun: true})
+	if err != nil {
+		t.Fatalf("runImport() got: %v", err)
+	}
+	if !strings.Contains(out.String(), "questions") || !strings.Contains(out.String(), "nothing was imported") {
+		t.Fatalf("expected the summary of the dry run, got:\n%s", out.String())
+	}
+
+	err = runImport(ctx, c, nil, &out, path, formatArchive, client.ImportOptions{})
+	if !client.HasCode(err, quiz.CodeImportConflict) {
+		t.Fatalf("runImport() of existing records got: %v", err)
+	}
+
+	err = runExport(ctx, testClient(t, "user"), io.Discard, filepath.Join(t.TempDir(), "backup.ndjson"), formatArchive)
+	if !client.HasCode(err, quiz.CodeUnauthorized) {
+		t.Fatalf("runExport() without the admin token got: %v", err)
+	}
+}
+
+func TestRunExportImportQuestions(t *testing.T) {
+	t.Parallel()
+	c := testClient(t, testAdminToken)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "questions.txt")
+	if err := runExport(ctx, c, io.Discard, path, quiz.FormatGIFT); err != nil {
+		t.Fatalf("runExport() got: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runImport(ctx, c, nil, &out, path, quiz.FormatGIFT, client.ImportOptions{Mode: quiz.ImportOverwrite}); err != nil {
+		t.Fatalf("runImport() got: %v", err)
+	}
+	if !slices.ContainsFunc(strings.Split(out.String(), "\n"), func(line string) bool {
+		return slices.Equal(strings.Fields(line), []string{"questions", "0", "5", "0"})
+	}) {
+		t.Fatalf("expected every question overwritten, got:\n%s", out.String())
+	}
+
+	invalid := strings.NewReader("What is 2 + 2? {=4 ~5}\n\nWhy? {}\n")
+	err := runImport(ctx, c, invalid, &out, "", quiz.FormatGIFT, client.ImportOptions{})
+	var errs quiz.ParseErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Line != 3 {
+		t.Fatalf("runImport() of an invalid file got: %v", err)
+	}
+}
+
+func TestShowQuestion(t *testing.T) {
+	t.Parallel()
+	c := testClient(t, "user")
+
+	var out bytes.Buffer
+	if label := showQuestion(&out, c, quiz.Question{Text: "What is 1 + 1?"}); label != "What is 1 + 1?" || out.Len() != 0 {
+		t.Fatalf("expected a single line in the label, got %q and:\n%s", label, out.String())
+	}
+
+	id := quiz.BlobID([]byte("image"))
+	label := showQuestion(&out, c, quiz.Question{Text: "What does it print?\n```go\nfmt.Println(1)\n```", Attachments: []string{id}})
+	if label != "Your answer" || !strings.Contains(out.String(), "fmt.Println(1)") || !strings.Contains(out.String(), "/blobs/"+id) {
+		t.Fatalf("expected the question above the prompt, got %q and:\n%s", label, out.String())
+	}
+}
+
+func TestRunUpload(t *testing.T) {
+	t.Parallel()
+	c := testClient(t, testAdminToken)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "image.png")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if err := os.WriteFile(path, png, 0o600); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runUpload(ctx, c, &out, path); err != nil {
+		t.Fatalf("runUpload() got: %v", err)
+	}
+	if !strings.Contains(out.String(), quiz.BlobID(png)) {
+		t.Fatalf("expected the id of the blob, got:\n%s", out.String())
+	}
+}
+
+func TestRunRegrade(t *testing.T) {
+	t.Parallel()
+	c := testClient(t, testAdminToken)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := runRegrade(ctx, c, &out, "0", true); err != nil {
+		t.Fatalf("runRegrade() got: %v", err)
+	}
+	if !strings.Contains(out.String(), "Dry run of 0 answers against version 1") {
+		t.Fatalf("expected the report of the dry run, got:\n%s", out.String())
+	}
+
+	if err := runRegrade(ctx, c, &out, "99", false); !client.HasCode(err, quiz.CodeQuestionNotFound) {
+		t.Fatalf("runRegrade() of an unknown question got: %v", err)
+	}
+}
+
+func TestRunAudit(t *testing.T) {
+	t.Parallel()
+	c := testClient(t, testAdminToken)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := runRegrade(ctx, c, &out, "0", true); err != nil {
+		t.Fatalf("runRegrade() got: %v", err)
+	}
+
+	out.Reset()
+	if err := runAudit(ctx, c, &out); err != nil {
+		t.Fatalf("runAudit() got: %v", err)
+	}
+	for _, want := range []string{"(admin)", "regradeQuestion", "question=0", "success"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in the audit log, got:\n%s", want, out.String())
+		}
+	}
+
+	if err := runAudit(ctx, testClient(t, "user"), &out); !client.HasCode(err, quiz.CodeUnauthorized) {
+		t.Fatalf("runAudit() without the admin token got: %v", err)
+	}
+}
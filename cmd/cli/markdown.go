@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/vrnvu/temp/pkg/client"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// markdownWidth the rendered text is wrapped at
+const markdownWidth = 80
+
+// showQuestion prints the question above the prompt when it does not fit in its label and returns
+// the label. Questions of a single line without attachments stay in the label like before.
+func showQuestion(out io.Writer, c *client.Client, q quiz.Question) string {
+	if !strings.Contains(q.Text, "\n") && len(q.Attachments) == 0 {
+		return q.Text
+	}
+
+	printMarkdown(out, q.Text)
+	for _, id := range q.Attachments {
+		printer.Fprintf(out, "Attachment: %s\n", c.BlobURL(id))
+	}
+	return printer.Sprintf("Your answer")
+}
+
+// printMarkdown renders the text for the terminal, with the code blocks highlighted, or prints it as
+// it is when it can't be rendered
+func printMarkdown(out io.Writer, text string) {
+	renderer, err := glamour.NewTermRenderer(glamour.WithAutoStyle(), glamour.WithWordWrap(markdownWidth))
+	if err == nil {
+		var rendered string
+		if rendered, err = renderer.Render(text); err == nil {
+			fmt.Fprint(out, rendered)
+			return
+		}
+	}
+	fmt.Fprintln(out, text)
+}
+
+// printExplanations of the answers the server recorded, the questions without one print nothing
+func printExplanations(out io.Writer, records []quiz.AnswerRecord) {
+	for _, record := range records {
+		if record.Explanation != "" {
+			printMarkdown(out, record.Explanation)
+		}
+	}
+}
+
+// runUpload stores the image of the file as a blob and prints its id, for the questions of an archive
+// to attach
+func runUpload(ctx context.Context, c *client.Client, out io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening image: %w", err)
+	}
+	defer f.Close()
+
+	blob, err := c.UploadBlob(ctx, f)
+	if err != nil {
+		return fmt.Errorf("uploading: %w", err)
+	}
+	printer.Fprintf(out, "Uploaded %s of %d bytes as %s\n", blob.MediaType, blob.Size, blob.ID)
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// printProblem explains the problems a user can act on, and shows the others as they are
+func printProblem(out io.Writer, err error) {
+	var problem *quiz.Problem
+	if !errors.As(err, &problem) {
+		fmt.Fprintf(out, "Error %v\n", err)
+		return
+	}
+
+	switch problem.Code {
+	case quiz.CodeUserNotFound:
+		printer.Fprintf(out, "User not found, answer a quiz first\n")
+	case quiz.CodeNotEnoughUsers:
+		printer.Fprintf(out, "Not enough users for statistics yet\n")
+	case quiz.CodeQuizNotFound:
+		printer.Fprintf(out, "Quiz not found\n")
+	case quiz.CodeQuizClosed:
+		printer.Fprintf(out, "Quiz is closed\n")
+	case quiz.CodeMaxAttemptsReached:
+		printer.Fprintf(out, "No attempts left for this quiz\n")
+	case quiz.CodeRoomNotFound:
+		printer.Fprintf(out, "Room not found, check the code\n")
+	default:
+		fmt.Fprintf(out, "Error %v\n", err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/vrnvu/temp/pkg/client"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// runRegrade scores the answers of the question again after a fix of its answer and prints the users
+// whose results changed
+func runRegrade(ctx context.Context, c *client.Client, out io.Writer, rawQuestion string, dryRun bool) error {
+	questionID, err := strconv.ParseUint(rawQuestion, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid question `%s`", rawQuestion)
+	}
+
+	report, err := c.Regrade(ctx, questionID, dryRun)
+	if err != nil {
+		return fmt.Errorf("regrading: %w", err)
+	}
+
+	printRegradeReport(out, report)
+	return nil
+}
+
+func printRegradeReport(out io.Writer, report quiz.RegradeReport) {
+	if len(report.Users) > 0 {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+		printer.Fprintf(w, "USER\tBEFORE\tAFTER\tTOTAL\t\n")
+		for _, u := range report.Users {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t\n", u.User, u.CorrectBefore, u.CorrectAfter, u.Total)
+		}
+		w.Flush()
+		fmt.Fprintln(out)
+	}
+
+	if report.DryRun {
+		printer.Fprintf(out, "Dry run of %d answers against version %d, nothing was regraded\n", report.Answers, report.Version)
+		return
+	}
+	printer.Fprintf(out, "%d answers regraded against version %d\n", report.Answers, report.Version)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/client"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// runHost opens a room of the named quiz and starts it when the host presses enter
+func runHost(ctx context.Context, c *client.Client, quizName string, limits quizLimits) error {
+	room, err := c.HostRoom(ctx, quizName, limits.questionTimeLimit)
+	if err != nil {
+		return fmt.Errorf("opening room: %w", err)
+	}
+	defer room.Close()
+
+	enter := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			enter <- struct{}{}
+		}
+	}()
+
+	messages := room.Messages()
+	for {
+		select {
+		case <-enter:
+			if err := room.Start(); err != nil {
+				return fmt.Errorf("starting room: %w", err)
+			}
+		case m, ok := <-messages:
+			if !ok {
+				printer.Printf("Room closed\n")
+				return nil
+			}
+
+			switch m.Type {
+			case quiz.RoomMessageRoom:
+				printer.Printf("Room code: %s, %d questions of %s each\n", m.Code, m.Count, m.TimeLimit())
+				printer.Printf("Press enter to start once everyone joined\n")
+			case quiz.RoomMessagePlayers:
+				printer.Printf("Players: %s\n", strings.Join(m.Players, ", "))
+			case quiz.RoomMessageQuestion:
+				printer.Printf("\nQuestion %d/%d: %s\n", m.Number, m.Count, m.Question.Text)
+			case quiz.RoomMessageRanking:
+				printRanking(os.Stdout, m.Ranking)
+			case quiz.RoomMessageFinished:
+				printer.Printf("\nFinal ranking\n")
+				printRanking(os.Stdout, m.Ranking)
+				return nil
+			case quiz.RoomMessageError:
+				fmt.Printf("Error: %s\n", m.Error)
+			}
+		}
+	}
+}
+
+// runJoin plays in a room, every question shows a countdown like a timed quiz
+func runJoin(ctx context.Context, c *client.Client, userKey string, code string) error {
+	room, err := c.JoinRoom(ctx, code, userKey)
+	if err != nil {
+		return fmt.Errorf("joining room: %w", err)
+	}
+	defer room.Close()
+
+	pump := newStdinPump(os.Stdin)
+	chosen := -1
+	for m := range room.Messages() {
+		switch m.Type {
+		case quiz.RoomMessagePlayers:
+			printer.Printf("Waiting for the host to start, players: %s\n", strings.Join(m.Players, ", "))
+		case quiz.RoomMessageQuestion:
+			printer.Printf("\nQuestion %d/%d\n", m.Number, m.Count)
+			label := showQuestion(os.Stdout, c, *m.Question)
+			index, err := askTimedQuestion(label, m.Question.Options, pump, time.Now().Add(m.TimeLimit()))
+			if err != nil {
+				printer.Printf("Time is up!\n")
+				chosen = -1
+				continue
+			}
+
+			chosen = index
+			if err := room.Answer(m.Number, index); err != nil {
+				return fmt.Errorf("submitting answer: %w", err)
+			}
+			printer.Printf("Waiting for the others...\n")
+		case quiz.RoomMessageRanking:
+			if chosen == *m.Option {
+				printer.Printf("Correct!\n")
+			} else {
+				printer.Printf("Wrong!\n")
+			}
+			printRanking(os.Stdout, m.Ranking)
+		case quiz.RoomMessageFinished:
+			printer.Printf("\nFinal ranking\n")
+			printRanking(os.Stdout, m.Ranking)
+			return nil
+		case quiz.RoomMessageError:
+			return fmt.Errorf("room: %s", m.Error)
+		}
+	}
+	printer.Printf("Room closed\n")
+	return nil
+}
+
+func printRanking(out io.Writer, ranking []quiz.RoomScore) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	printer.Fprintf(w, "RANK\tPLAYER\tSCORE\tCORRECT\tPOINTS\t\n")
+	for _, s := range ranking {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t+%d\t\n", s.Rank, s.Player, s.Score, s.Correct, s.Points)
+	}
+	w.Flush()
+}
@@ -4,14 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/jaevor/go-nanoid"
 	"github.com/vrnvu/temp/internal/server"
+	"github.com/vrnvu/temp/internal/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func fromEnvPort() string {
@@ -39,7 +44,56 @@ func fromEnvSlog() (*slog.Logger, error) {
 		}
 	}
 
-	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})), nil
+	return slog.New(server.TraceLogHandler{Handler: slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})}), nil
+}
+
+// fromEnvAuditLog opens AUDIT_LOG, rotated once it grows past AUDIT_LOG_MAX_SIZE megabytes
+// keeping AUDIT_LOG_BACKUPS old files. Without AUDIT_LOG the audit events are only kept in memory.
+func fromEnvAuditLog() (*server.RotatingFile, error) {
+	path, ok := os.LookupEnv("AUDIT_LOG")
+	if !ok {
+		return nil, nil
+	}
+
+	maxSize, backups := 100, 5
+	for name, value := range map[string]*int{"AUDIT_LOG_MAX_SIZE": &maxSize, "AUDIT_LOG_BACKUPS": &backups} {
+		if v, ok := os.LookupEnv(name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s: `%s`, use a number", name, v)
+			}
+			*value = n
+		}
+	}
+	return server.OpenRotatingFile(path, int64(maxSize)<<20, backups)
+}
+
+// fromEnvMetricsAddr is where /metrics is served, apart from the api and on localhost unless METRICS_ADDR
+// says otherwise, so that it is not exposed with it
+func fromEnvMetricsAddr() string {
+	addr, ok := os.LookupEnv("METRICS_ADDR")
+	if !ok {
+		addr = "localhost:9100"
+	}
+	return addr
+}
+
+func serveGRPC(slog *slog.Logger, quizHandler *server.Handler, port string) {
+	creds, err := credentials.NewServerTLSFromFile("localhost.pem", "localhost-key.pem")
+	if err != nil {
+		panic(err)
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		panic(err)
+	}
+
+	slog.Info("starting grpc server", "port", port)
+	if err := server.NewGRPCServer(quizHandler, grpc.Creds(creds)).Serve(listener); err != nil {
+		slog.Error("grpc server error", "error", err)
+		os.Exit(1)
+	}
 }
 
 // TODO tls/https
@@ -55,22 +109,64 @@ func main() {
 		panic(err)
 	}
 
-	quizHandler, err := server.FromConfig(&server.Config{
+	tracerProvider, shutdownTracing, err := tracing.FromEnv(context.Background(), "quiz-server")
+	if err != nil {
+		panic(err)
+	}
+
+	config := &server.Config{
 		Slog:               slog,
 		RequestIDGenerator: requestIDGenerator,
-	})
+		AdminToken:         os.Getenv("ADMIN_TOKEN"),
+		TracerProvider:     tracerProvider,
+	}
+	auditLog, err := fromEnvAuditLog()
 	if err != nil {
 		panic(err)
 	}
+	if auditLog != nil {
+		defer auditLog.Close()
+		config.AuditLog = auditLog
+	}
+
+	quizHandler, err := server.FromConfig(config)
+	if err != nil {
+		panic(err)
+	}
+
+	webhooks, stopWebhooks := context.WithCancel(context.Background())
+	defer stopWebhooks()
+	go quizHandler.RunWebhooks(webhooks)
+
+	// gRPC shares the TLS listener, GRPC_PORT also serves it on a port of its own
+	grpcServer := server.NewGRPCServer(quizHandler)
+	if grpcPort, ok := os.LookupEnv("GRPC_PORT"); ok {
+		go serveGRPC(slog, quizHandler, grpcPort)
+	}
 
 	server := &http.Server{
 		Addr:              ":" + port,
-		Handler:           quizHandler,
+		Handler:           server.WithGRPC(grpcServer, quizHandler),
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       10 * time.Second,
 		WriteTimeout:      10 * time.Second,
 	}
 
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", quizHandler.MetricsHandler())
+	metricsServer := &http.Server{
+		Addr:              fromEnvMetricsAddr(),
+		Handler:           metricsMux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		slog.Info("starting metrics server", "addr", metricsServer.Addr)
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("metrics server error", "error", err)
+			os.Exit(1)
+		}
+	}()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	go func() {
@@ -83,12 +179,19 @@ func main() {
 
 	<-stop
 	slog.Info("shutting down server...")
+	stopWebhooks()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
 		slog.Error("server forced to shutdown", "error", err)
 	}
+	if err := metricsServer.Shutdown(ctx); err != nil {
+		slog.Error("metrics server forced to shutdown", "error", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("tracing forced to shutdown", "error", err)
+	}
 
 	slog.Info("server exited properly")
 }
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	queryActor   = "actor"
+	queryAction  = "action"
+	queryOutcome = "outcome"
+	queryBefore  = "before"
+	queryLimit   = "limit"
+
+	extensionAudit = "x-audit"
+
+	// events kept in memory, the audit log file keeps every one of them
+	auditMemory       = 10000
+	defaultAuditLimit = 100
+)
+
+// auditLog is append only, the events in memory are a ring whose oldest event is overwritten once
+// there are max of them
+type auditLog struct {
+	lock sync.Mutex
+	// events grow up to max, then head is the oldest of them and the next one to overwrite
+	events []quiz.AuditEvent
+	head   int
+	max    int
+	nextID uint64
+	// file gets every event as a line of JSON, it may be nil
+	file io.Writer
+	slog *slog.Logger
+	now  func() time.Time
+}
+
+func newAuditLog(slog *slog.Logger, file io.Writer) *auditLog {
+	return &auditLog{events: []quiz.AuditEvent{}, max: auditMemory, nextID: 1, file: file, slog: slog, now: time.Now}
+}
+
+func (a *auditLog) record(event quiz.AuditEvent) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	event.ID = a.nextID
+	event.Time = a.now()
+	a.nextID++
+	if len(a.events) < a.max {
+		a.events = append(a.events, event)
+	} else {
+		a.events[a.head] = event
+		a.head = (a.head + 1) % a.max
+	}
+
+	if a.file == nil {
+		return
+	}
+	line, err := json.Marshal(event)
+	if err == nil {
+		_, err = a.file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		a.slog.Error("audit log write error", "error", err, headerXRequestID, event.RequestID)
+	}
+}
+
+// auditFilter of the events, empty fields match any event
+type auditFilter struct {
+	Actor   string
+	Action  string
+	Outcome string
+	// Before is the ID of the last event of the previous page, zero is the latest event
+	Before uint64
+	Limit  int
+}
+
+// query the events matching the filter, the latest first
+func (a *auditLog) query(filter auditFilter) []quiz.AuditEvent {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	events := []quiz.AuditEvent{}
+	for i := len(a.events) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		e := a.events[(a.head+i)%len(a.events)]
+		switch {
+		case filter.Before != 0 && e.ID >= filter.Before:
+		case filter.Actor != "" && e.Actor != filter.Actor:
+		case filter.Action != "" && e.Action != filter.Action:
+		case filter.Outcome != "" && e.Outcome != filter.Outcome:
+		default:
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// audited operations are the ones that take a bearer token, every admin operation among them, and
+// the ones marked with `x-audit` in the OpenAPI document, like the creation of users
+func audited(route *routers.Route) bool {
+	if marked, _ := route.Operation.Extensions[extensionAudit].(bool); marked {
+		return true
+	}
+	return route.Operation.Security != nil && len(*route.Operation.Security) > 0
+}
+
+// withAudit records who asked for the operation of the route, on what and how it was answered.
+// A request rejected before it reaches the operation, like one without a valid token, is recorded too.
+func (h *Handler) withAudit(route *routers.Route, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event := quiz.AuditEvent{Action: route.Operation.OperationID, Target: auditTarget(route, r), RequestID: fromContext(r, xRequestIDHeaderKey), SourceIP: sourceIP(r)}
+		event.Actor, event.Admin = h.auditActor(route, r)
+
+		sw := &statusWriter{ResponseWriter: w}
+		answered := false
+		defer func() {
+			event.Status = sw.status
+			if event.Status == 0 {
+				// a panic is answered as an internal error by net/http
+				event.Status = http.StatusOK
+				if !answered {
+					event.Status = http.StatusInternalServerError
+				}
+			}
+			event.Outcome = quiz.AuditOutcome(event.Status)
+			h.audit.record(event)
+		}()
+		next.ServeHTTP(sw, r)
+		answered = true
+	}
+}
+
+// auditActor is the user of the bearer token and whether it is the admin token. Tokens are secrets,
+// one that is neither the admin token nor the token of a user is never recorded.
+func (h *Handler) auditActor(route *routers.Route, r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get(headerAuthorization), "Bearer ")
+	switch {
+	case !ok || token == "":
+		return "", false
+	case h.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1:
+		return "", true
+	case strings.HasPrefix(route.Path, "/admin/"):
+		return "", false
+	default:
+		user, _ := h.db.AuthenticateUser(r.Context(), token)
+		return user, false
+	}
+}
+
+// auditTarget are the path parameters in the order of the path, like `group=go user=alice`
+func auditTarget(route *routers.Route, r *http.Request) string {
+	target := []string{}
+	for _, p := range route.PathItem.Parameters {
+		if p.Value != nil && p.Value.In == openapi3.ParameterInPath {
+			target = append(target, p.Value.Name+"="+r.PathValue(p.Value.Name))
+		}
+	}
+	return strings.Join(target, " ")
+}
+
+// sourceIP is the peer of the connection, forwarded headers can be set by anyone and are not trusted
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// getAuditLog answers the events the latest first, `?before=` the ID of the last event pages back
+func (h *Handler) getAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := auditFilter{Actor: query.Get(queryActor), Action: query.Get(queryAction), Outcome: query.Get(queryOutcome), Limit: defaultAuditLimit}
+
+	if filter.Outcome != "" && !slices.Contains(quiz.AuditOutcomes, filter.Outcome) {
+		h.writeError(w, r, http.StatusBadRequest, &quiz.FieldError{Field: queryOutcome, Reason: fmt.Sprintf("`%s`, try: %v", filter.Outcome, quiz.AuditOutcomes)})
+		return
+	}
+	if rawBefore := query.Get(queryBefore); rawBefore != "" {
+		before, err := strconv.ParseUint(rawBefore, 10, 64)
+		if err != nil {
+			h.writeError(w, r, http.StatusBadRequest, &quiz.FieldError{Field: queryBefore, Reason: fmt.Sprintf("`%s`", rawBefore)})
+			return
+		}
+		filter.Before = before
+	}
+	if rawLimit := query.Get(queryLimit); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > auditMemory {
+			h.writeError(w, r, http.StatusBadRequest, &quiz.FieldError{Field: queryLimit, Reason: fmt.Sprintf("`%s`, use 1 to %d", rawLimit, auditMemory)})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	h.writeData(w, r, h.audit.query(filter))
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerAudit(t *testing.T) {
+	t.Parallel()
+	file := &bytes.Buffer{}
+	handler, err := FromConfig(&Config{
+		Slog:               slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		RequestIDGenerator: func() string { return "123" },
+		AdminToken:         testAdminToken,
+		AuditLog:           file,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	request := func(method string, url string, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		r, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(`{"kind": "team"}`))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		r.RemoteAddr = "192.0.2.1:4321"
+		r.Header.Set(headerContentType, valueContentTypeJSON)
+		r.Header.Set("X-Forwarded-For", "198.51.100.1")
+		if token != "" {
+			r.Header.Set(headerAuthorization, "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.Mux.ServeHTTP(w, r)
+		return w
+	}
+
+	request(http.MethodGet, "/v1/quiz", "")
+	request(http.MethodGet, "/v1/admin/export", "not-the-admin-token")
+	request(http.MethodPut, "/v1/groups/go", testUserToken(t, handler.db, "user"))
+	request(http.MethodDelete, "/v1/admin/webhooks/missing", testAdminToken)
+	request(http.MethodPut, "/v1/users/alice", "")
+
+	audit := func(query string) []quiz.AuditEvent {
+		t.Helper()
+		w := request(http.MethodGet, "/v1/admin/audit"+query, testAdminToken)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var events []quiz.AuditEvent
+		if err := json.NewDecoder(w.Body).Decode(&events); err != nil {
+			t.Fatalf("failed to decode events: %v", err)
+		}
+		for i := range events {
+			events[i].Time = events[i].Time.UTC()
+		}
+		return events
+	}
+
+	events := audit("")
+	want := []quiz.AuditEvent{
+		{ID: 4, Action: "putUser", Target: "user=alice", RequestID: "123", SourceIP: "192.0.2.1", Outcome: quiz.AuditSuccess, Status: http.StatusOK},
+		{ID: 3, Admin: true, Action: "deleteWebhook", Target: "slug=missing", RequestID: "123", SourceIP: "192.0.2.1", Outcome: quiz.AuditFailure, Status: http.StatusNotFound},
+		{ID: 2, Actor: "user", Action: "putGroup", Target: "group=go", RequestID: "123", SourceIP: "192.0.2.1", Outcome: quiz.AuditSuccess, Status: http.StatusOK},
+		{ID: 1, Action: "exportArchive", RequestID: "123", SourceIP: "192.0.2.1", Outcome: quiz.AuditDenied, Status: http.StatusUnauthorized},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, the public routes are not audited, got %+v", len(want), events)
+	}
+	for i := range want {
+		if events[i].Time.IsZero() {
+			t.Fatalf("expected the time of event %d", events[i].ID)
+		}
+		events[i].Time = want[i].Time
+		if events[i] != want[i] {
+			t.Fatalf("expected event %+v, got %+v", want[i], events[i])
+		}
+	}
+	if strings.Contains(file.String(), "not-the-admin-token") {
+		t.Fatalf("expected a wrong admin token to never be recorded, got %s", file.String())
+	}
+
+	// the query itself is audited
+	if events := audit("?outcome=denied"); len(events) != 1 || events[0].ID != 1 {
+		t.Fatalf("expected the denied event, got %+v", events)
+	}
+	if events := audit("?action=getAuditLog&limit=1"); len(events) != 1 || events[0].ID != 6 {
+		t.Fatalf("expected the latest query of the audit log, got %+v", events)
+	}
+	if events := audit("?before=3&actor=user"); len(events) != 1 || events[0].ID != 2 {
+		t.Fatalf("expected the events before 3 of user, got %+v", events)
+	}
+	// users are created without a token, their creation is audited all the same
+	if events := audit("?action=putUser"); len(events) != 1 || events[0].Target != "user=alice" {
+		t.Fatalf("expected the creation of alice, got %+v", events)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event quiz.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("failed to decode line %d: %v", lines+1, err)
+		}
+		lines++
+		if event.ID != uint64(lines) {
+			t.Fatalf("expected the events in order, got %d at line %d", event.ID, lines)
+		}
+	}
+	if lines != 9 {
+		t.Fatalf("expected every event in the file, got %d lines", lines)
+	}
+}
+
+func TestAuditLogRing(t *testing.T) {
+	t.Parallel()
+	a := newAuditLog(slog.New(slog.NewJSONHandler(os.Stdout, nil)), nil)
+	a.max = 3
+
+	for range 5 {
+		a.record(quiz.AuditEvent{Action: "putUser"})
+	}
+	events := a.query(auditFilter{Limit: 10})
+	if len(events) != 3 || events[0].ID != 5 || events[2].ID != 3 {
+		t.Fatalf("expected the 3 latest events, the latest first, got %+v", events)
+	}
+	if events := a.query(auditFilter{Before: 5, Limit: 1}); len(events) != 1 || events[0].ID != 4 {
+		t.Fatalf("expected the event before 5, got %+v", events)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	f, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	if _, err := f.Write([]byte("six\n")); err == nil {
+		t.Fatalf("expected writes after close to fail")
+	}
+
+	// the oldest lines are dropped with the last backup
+	for name, want := range map[string]string{path: "four\nfive\n", path + ".1": "three\n", path + ".2": "one\ntwo\n"} {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(b) != want {
+			t.Fatalf("expected %s to have %q, got %q", name, want, b)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected 2 backups at most, got %v", err)
+	}
+
+	// it appends to what is there, which is already full
+	f, err = OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("failed to open again: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte("six\n")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	for name, want := range map[string]string{path: "six\n", path + ".1": "four\nfive\n", path + ".2": "three\n"} {
+		if b, _ := os.ReadFile(name); string(b) != want {
+			t.Fatalf("expected %s to have %q, got %q", name, want, b)
+		}
+	}
+}
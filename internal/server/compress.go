@@ -0,0 +1,154 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerContentEncoding = "Content-Encoding"
+	headerContentLength   = "Content-Length"
+	headerUpgrade         = "Upgrade"
+	encodingZstd          = "zstd"
+	encodingGzip          = "gzip"
+)
+
+// encodings the responses are compressed with, in order of preference
+var encodings = []string{encodingZstd, encodingGzip}
+
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+	Flush() error
+}
+
+// encoders are reused across responses, a zstd encoder in particular is expensive to create
+var encoders = map[string]*sync.Pool{
+	encodingZstd: {New: func() any {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+	encodingGzip: {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+// withCompression compresses the response with the encoding the `Accept-Encoding` of the request
+// weighs the most, zstd or gzip. Without one, or when the request only accepts other encodings,
+// the response is sent as is. Websocket upgrades are never compressed.
+func withCompression(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(headerUpgrade) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add(headerVary, headerAcceptEncoding)
+		// a malformed `Accept-Encoding` is answered as is, like one without a known encoding
+		ranges, err := parseAccept(r.Header.Get(headerAcceptEncoding))
+		if err != nil || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		encoding, ok := negotiate(ranges, encodings)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	}
+}
+
+// compressWriter only starts compressing once the status is known, responses without a body and
+// responses the handler already encoded are sent as is
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     encoder
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.wroteHeader = true
+
+	header := cw.Header()
+	// images are compressed already
+	compress := status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified && header.Get(headerContentEncoding) == "" &&
+		!strings.HasPrefix(header.Get(headerContentType), "image/")
+	// the compressed representation is another representation, a 304 confirms the one the caller has
+	if etag := header.Get(headerETag); etag != "" && (compress || status == http.StatusNotModified) {
+		header.Set(headerETag, withETagSuffix(etag, cw.encoding))
+	}
+	if compress {
+		header.Set(headerContentEncoding, cw.encoding)
+		header.Del(headerContentLength)
+		cw.encoder = encoders[cw.encoding].Get().(encoder)
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoder == nil {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.encoder.Write(b)
+}
+
+// Flush sends what was compressed so far, for the handlers that stream their response
+func (cw *compressWriter) Flush() {
+	if cw.encoder != nil {
+		cw.encoder.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close ends the compressed stream and returns the encoder to its pool
+func (cw *compressWriter) Close() error {
+	if cw.encoder == nil {
+		return nil
+	}
+	err := cw.encoder.Close()
+	cw.encoder.Reset(nil)
+	encoders[cw.encoding].Put(cw.encoder)
+	cw.encoder = nil
+	return err
+}
+
+// withETagSuffix tells apart the representations of the same version, `"v"` becomes `"v-gzip"`
+func withETagSuffix(etag string, suffix string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + suffix + `"`
+}
+
+// withoutEncodingSuffix is the ETag the handler set, before withCompression suffixed it
+func withoutEncodingSuffix(etag string) string {
+	for _, encoding := range encodings {
+		if trimmed, ok := strings.CutSuffix(etag, "-"+encoding+`"`); ok {
+			return trimmed + `"`
+		}
+	}
+	return etag
+}
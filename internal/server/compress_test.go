@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerCompression(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	ctx := context.Background()
+
+	get := func(url string, acceptEncoding string, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if acceptEncoding != "" {
+			r.Header.Set(headerAcceptEncoding, acceptEncoding)
+		}
+		if ifNoneMatch != "" {
+			r.Header.Set(headerIfNoneMatch, ifNoneMatch)
+		}
+
+		w := httptest.NewRecorder()
+		handler.Mux.ServeHTTP(w, r)
+		return w
+	}
+
+	decompress := func(w *httptest.ResponseRecorder) io.Reader {
+		t.Helper()
+		switch w.Header().Get(headerContentEncoding) {
+		case encodingGzip:
+			r, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("failed to read gzip: %v", err)
+			}
+			return r
+		case encodingZstd:
+			r, err := zstd.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("failed to read zstd: %v", err)
+			}
+			return r.IOReadCloser()
+		default:
+			return w.Body
+		}
+	}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{name: "identity", acceptEncoding: "", want: ""},
+		{name: "gzip", acceptEncoding: "gzip, deflate, br", want: encodingGzip},
+		{name: "zstd preferred", acceptEncoding: "gzip, zstd", want: encodingZstd},
+		{name: "weights", acceptEncoding: "zstd;q=0.5, gzip", want: encodingGzip},
+		{name: "any", acceptEncoding: "*", want: encodingZstd},
+		{name: "excluded", acceptEncoding: "*;q=0", want: ""},
+		{name: "unknown", acceptEncoding: "br", want: ""},
+		{name: "malformed", acceptEncoding: "gzip;q=x", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get("/quiz", tt.acceptEncoding, "")
+			if w.Code != http.StatusOK || w.Header().Get(headerContentEncoding) != tt.want {
+				t.Fatalf("expected %d encoded %q, got %d encoded %q", http.StatusOK, tt.want, w.Code, w.Header().Get(headerContentEncoding))
+			}
+			if !slices.Contains(w.Header().Values(headerVary), headerAcceptEncoding) {
+				t.Fatalf("expected to vary on %s, got %v", headerAcceptEncoding, w.Header().Values(headerVary))
+			}
+
+			var questions []quiz.Question
+			if err := json.NewDecoder(decompress(w)).Decode(&questions); err != nil || len(questions) == 0 {
+				t.Fatalf("expected questions, got %v", err)
+			}
+		})
+	}
+
+	// compressed responses are other representations, they revalidate with their own ETag
+	plain, compressed := get("/quiz/user", "", ""), get("/quiz/user", encodingGzip, "")
+	if want := withETagSuffix(plain.Header().Get(headerETag), encodingGzip); compressed.Header().Get(headerETag) != want {
+		t.Fatalf("expected ETag %s, got %s", want, compressed.Header().Get(headerETag))
+	}
+	w := get("/quiz/user", encodingGzip, compressed.Header().Get(headerETag))
+	if w.Code != http.StatusNotModified || w.Header().Get(headerETag) != compressed.Header().Get(headerETag) || w.Header().Get(headerContentEncoding) != "" || w.Body.Len() != 0 {
+		t.Fatalf("expected an unencoded 304 with ETag %s, got %d with headers %v", compressed.Header().Get(headerETag), w.Code, w.Header())
+	}
+
+	// problems are compressed too, without an ETag
+	w = get("/quiz/unknown", encodingZstd, "")
+	var p quiz.Problem
+	if err := json.NewDecoder(decompress(w)).Decode(&p); err != nil || w.Code != http.StatusBadRequest || p.Code != quiz.CodeUserNotFound || w.Header().Get(headerETag) != "" {
+		t.Fatalf("expected a compressed problem, got %d with %+v, %v", w.Code, p, err)
+	}
+}
@@ -2,75 +2,287 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"math/rand"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/vrnvu/temp/pkg/quiz"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 var ErrUserAlreadyExists = errors.New("user already exists")
 var ErrUserNotFound = errors.New("user not found")
+var ErrInvalidToken = errors.New("invalid user token")
 var ErrNotEnoughUsersForStatistics = errors.New("not enough users for statistics")
+var ErrSessionNotFound = errors.New("session not found")
+var ErrQuestionNotInSession = errors.New("question not in session")
+var ErrQuestionAlreadyAnswered = errors.New("question already answered")
+var ErrInvalidOption = errors.New("invalid option")
+var ErrBatchedAnswers = errors.New("answer one question at a time under a question time limit")
+var ErrQuestionNotFound = errors.New("question not found")
+var ErrQuizNotFound = errors.New("quiz not found")
+var ErrQuizClosed = errors.New("quiz is closed")
+var ErrMaxAttemptsReached = errors.New("max attempts reached")
+var ErrGroupNotFound = errors.New("group not found")
+var ErrGroupAlreadyExists = errors.New("group already exists")
+var ErrNotGroupOwner = errors.New("not a group owner")
+var ErrLastGroupOwner = errors.New("group needs at least one owner")
+var ErrWebhookNotFound = errors.New("webhook not found")
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// QuestionFilter narrows down the questions, zero value matches everything
+type QuestionFilter struct {
+	Category string
+	Tags     []string
+}
+
+func (f QuestionFilter) Match(q quiz.Question) bool {
+	if f.Category != "" && f.Category != q.Category {
+		return false
+	}
+	return q.HasTags(f.Tags...)
+}
 
 type InMemoryDB struct {
-	questions     []quiz.Question
-	lockQuestions sync.RWMutex
-	users         []quiz.User
-	lockUsers     sync.RWMutex
+	questions []quiz.Question
+	// every version of every question ID, the last one is the question in questions, guarded by lockQuestions
+	questionVersions map[uint64][]quiz.QuestionVersion
+	lockQuestions    sync.RWMutex
+	users            []quiz.User
+	// review state per user ID per question ID, guarded by lockUsers
+	reviews map[uint64]map[uint64]quiz.Review
+	// results per user ID per category, guarded by lockUsers
+	categories map[uint64]map[string]quiz.CategoryResults
+	// answers outside of sessions per user ID, so they can be regraded, guarded by lockUsers
+	answers      map[uint64][]quiz.AnswerRecord
+	lockUsers    sync.RWMutex
+	sessions     []session
+	lockSessions sync.RWMutex
+	quizzes      map[string]quiz.Quiz
+	lockQuizzes  sync.RWMutex
+	groups       map[string]quiz.Group
+	lockGroups   sync.RWMutex
+	webhooks     map[string]quiz.Webhook
+	// blobs by ID, a blob never changes once stored, guarded by lockBlobs
+	blobs     map[string]blob
+	lockBlobs sync.RWMutex
+	// pending and dead deliveries, delivered ones are forgotten, guarded by lockWebhooks
+	deliveries   map[uint64]*delivery
+	nextEvent    uint64
+	nextDelivery uint64
+	lockWebhooks sync.Mutex
+	// webhookSignal wakes up the worker of the deliveries, see Handler.RunWebhooks
+	webhookSignal chan struct{}
+	// version counts every write, userVersions only the writes to the results of a user ID,
+	// both guarded by lockVersions so they can be bumped holding the other locks
+	version      uint64
+	userVersions map[uint64]uint64
+	lockVersions sync.Mutex
+	now          func() time.Time
+	// operations times every exported operation and tracer traces it, see observe
+	operations *prometheus.HistogramVec
+	tracer     trace.Tracer
 }
 
 func NewInMemoryDB() (*InMemoryDB, error) {
+	capitals := map[string]string{"London": "Londres", "Paris": "París", "Berlin": "Berlín"}
 	questions := []quiz.Question{
-		{ID: 0, Text: "What is the capital of France?", Options: []string{"London", "Paris", "Berlin", "Madrid"}, Answer: "Paris"},
-		{ID: 1, Text: "What is the capital of Germany?", Options: []string{"Berlin", "Paris", "London", "Madrid"}, Answer: "Berlin"},
-		{ID: 2, Text: "What is 2 + 2?", Options: []string{"1", "2", "3", "4"}, Answer: "4"},
-		{ID: 3, Text: "What is 2 * 2?", Options: []string{"1", "2", "3", "4"}, Answer: "4"},
-		{ID: 4, Text: "What is 2 - 2?", Options: []string{"0", "1", "2", "3"}, Answer: "0"},
+		{ID: 0, Text: "What is the capital of France?", Options: []string{"London", "Paris", "Berlin", "Madrid"}, Answer: 1, Category: "geography", Tags: []string{"capitals", "europe"},
+			Explanation:  "Paris has been the capital of France since the 10th century.",
+			Translations: map[string]quiz.Translation{"es": {Text: "¿Cuál es la capital de Francia?", Options: capitals, Explanation: "París es la capital de Francia desde el siglo X."}}},
+		{ID: 1, Text: "What is the capital of Germany?", Options: []string{"Berlin", "Paris", "London", "Madrid"}, Answer: 0, Category: "geography", Tags: []string{"capitals", "europe"},
+			Explanation:  "Berlin is the capital of Germany since the reunification in 1990.",
+			Translations: map[string]quiz.Translation{"es": {Text: "¿Cuál es la capital de Alemania?", Options: capitals, Explanation: "Berlín es la capital de Alemania desde la reunificación en 1990."}}},
+		{ID: 2, Text: "What is 2 + 2?", Options: []string{"1", "2", "3", "4"}, Answer: 3, Category: "arithmetic", Tags: []string{"addition"},
+			Translations: map[string]quiz.Translation{"es": {Text: "¿Cuánto es 2 + 2?"}}},
+		{ID: 3, Text: "What is 2 * 2?", Options: []string{"1", "2", "3", "4"}, Answer: 3, Category: "arithmetic", Tags: []string{"multiplication"},
+			Translations: map[string]quiz.Translation{"es": {Text: "¿Cuánto es 2 * 2?"}}},
+		{ID: 4, Text: "What is 2 - 2?", Options: []string{"0", "1", "2", "3"}, Answer: 0, Category: "arithmetic", Tags: []string{"subtraction"},
+			Translations: map[string]quiz.Translation{"es": {Text: "¿Cuánto es 2 - 2?"}}},
 	}
 
 	users := []quiz.User{
 		{ID: 0, Name: "user", Correct: 0, Total: 0},
 	}
 
-	return &InMemoryDB{
-		questions: questions,
-		users:     users,
-	}, nil
+	db := &InMemoryDB{
+		questionVersions: map[uint64][]quiz.QuestionVersion{},
+		users:            users,
+		reviews:          map[uint64]map[uint64]quiz.Review{},
+		categories:       map[uint64]map[string]quiz.CategoryResults{},
+		answers:          map[uint64][]quiz.AnswerRecord{},
+		quizzes:          map[string]quiz.Quiz{},
+		groups:           map[string]quiz.Group{},
+		webhooks:         map[string]quiz.Webhook{},
+		blobs:            map[string]blob{},
+		deliveries:       map[uint64]*delivery{},
+		// buffered so publishing never waits, one pending signal is enough to wake up the worker
+		webhookSignal: make(chan struct{}, 1),
+		userVersions:  map[uint64]uint64{},
+		now:           time.Now,
+		tracer:        noop.NewTracerProvider().Tracer(""),
+	}
+	for _, q := range questions {
+		db.putQuestion(q)
+	}
+	return db, nil
 }
 
-func (db *InMemoryDB) GetQuestions(_ context.Context) ([]quiz.Question, error) {
+func (db *InMemoryDB) GetQuestions(ctx context.Context, filter QuestionFilter) ([]quiz.Question, error) {
+	defer db.observe(ctx, "GetQuestions")()
+
 	db.lockQuestions.RLock()
 	defer db.lockQuestions.RUnlock()
 
-	i1 := rand.Intn(len(db.questions))
-	i2 := rand.Intn(len(db.questions))
-	return []quiz.Question{db.questions[i1], db.questions[i2]}, nil
+	questions := []quiz.Question{}
+	for _, q := range db.questions {
+		if filter.Match(q) {
+			questions = append(questions, q.WithOptionIDs())
+		}
+	}
+
+	if len(questions) == 0 {
+		return questions, nil
+	}
+
+	i1 := rand.Intn(len(questions))
+	i2 := rand.Intn(len(questions))
+	return []quiz.Question{questions[i1], questions[i2]}, nil
 }
 
-func (db *InMemoryDB) InsertQuizAnswer(_ context.Context, user string, answer quiz.QuizAnswer) error {
+// InsertQuizAnswer scores answers outside of a session, given as option IDs or texts, see
+// quiz.Question.OptionIndex. A text that is the translation of several options scores zero.
+func (db *InMemoryDB) InsertQuizAnswer(ctx context.Context, user string, answer quiz.QuizAnswer) error {
+	defer db.observe(ctx, "InsertQuizAnswer")()
+
 	db.lockQuestions.RLock()
 	defer db.lockQuestions.RUnlock()
 
-	db.lockUsers.RLock()
-	defer db.lockUsers.RUnlock()
+	db.lockUsers.Lock()
+	defer db.lockUsers.Unlock()
 
 	userID, err := db.getUserID(user)
 	if err != nil {
 		return err
 	}
 
-	for questionID, userAnswer := range answer {
-		if db.questions[questionID].Answer == userAnswer {
-			db.users[userID].Correct++
+	// every question is checked before any answer is scored, so an unknown one scores none of them
+	for questionID := range answer {
+		if questionID >= uint64(len(db.questions)) {
+			return ErrQuestionNotFound
 		}
-		db.users[userID].Total++
+	}
+
+	now := db.now()
+	for questionID, userAnswer := range answer {
+		question := db.questions[questionID]
+		option := question.OptionIndex(userAnswer)
+		correct := question.IsCorrect(option)
+		db.score(userID, question, correct, now)
+		db.answers[userID] = append(db.answers[userID], quiz.AnswerRecord{QuestionID: questionID, SubmittedAt: now, Correct: correct, QuestionVersion: question.Version, Option: option})
 	}
 
 	return nil
 }
 
-func (db *InMemoryDB) GetResults(_ context.Context, user string) (quiz.QuizResults, error) {
+// score must be called holding lockUsers
+func (db *InMemoryDB) score(userID uint64, question quiz.Question, correct bool, now time.Time) {
+	if correct {
+		db.users[userID].Correct++
+	}
+	db.users[userID].Total++
+	db.review(userID, question.ID, correct, now)
+	db.categorize(userID, question.Category, correct)
+	db.bumpVersion(userID)
+}
+
+// review must be called holding lockUsers
+func (db *InMemoryDB) review(userID uint64, questionID uint64, correct bool, now time.Time) {
+	reviews, ok := db.reviews[userID]
+	if !ok {
+		reviews = map[uint64]quiz.Review{}
+		db.reviews[userID] = reviews
+	}
+
+	review, ok := reviews[questionID]
+	if !ok {
+		review = quiz.NewReview(questionID, now)
+	}
+	reviews[questionID] = review.Next(quiz.QualityOf(correct), now)
+}
+
+// categorize must be called holding lockUsers
+func (db *InMemoryDB) categorize(userID uint64, category string, correct bool) {
+	if category == "" {
+		return
+	}
+
+	categories, ok := db.categories[userID]
+	if !ok {
+		categories = map[string]quiz.CategoryResults{}
+		db.categories[userID] = categories
+	}
+
+	results := categories[category]
+	results.Category = category
+	if correct {
+		results.Correct++
+	}
+	results.Total++
+	categories[category] = results
+}
+
+// GetDueQuestions returns the questions the user has to review now, the most overdue first.
+// Questions the user never answered are always due.
+func (db *InMemoryDB) GetDueQuestions(ctx context.Context, user string, filter QuestionFilter) ([]quiz.Question, error) {
+	defer db.observe(ctx, "GetDueQuestions")()
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	userID, err := db.getUserID(user)
+	if err != nil {
+		return nil, err
+	}
+
+	now := db.now()
+	due := []quiz.Review{}
+	for _, question := range db.questions {
+		if !filter.Match(question) {
+			continue
+		}
+		review, ok := db.reviews[userID][question.ID]
+		if !ok {
+			review = quiz.NewReview(question.ID, now)
+		}
+		if review.IsDue(now) {
+			due = append(due, review)
+		}
+	}
+
+	slices.SortStableFunc(due, func(a, b quiz.Review) int {
+		return a.Due.Compare(b.Due)
+	})
+
+	questions := make([]quiz.Question, 0, len(due))
+	for _, review := range due {
+		questions = append(questions, db.questions[review.QuestionID].WithOptionIDs())
+	}
+	return questions, nil
+}
+
+func (db *InMemoryDB) GetResults(ctx context.Context, user string) (quiz.QuizResults, error) {
+	defer db.observe(ctx, "GetResults")()
+
 	db.lockUsers.RLock()
 	defer db.lockUsers.RUnlock()
 
@@ -79,12 +291,44 @@ func (db *InMemoryDB) GetResults(_ context.Context, user string) (quiz.QuizResul
 		return quiz.QuizResults{}, err
 	}
 
-	userResults := quiz.QuizResults{
-		Correct: db.users[userID].Correct,
-		Total:   db.users[userID].Total,
+	return db.results(userID), nil
+}
+
+// GetUsersResults returns the results of many users in one lookup, unknown users are left out
+func (db *InMemoryDB) GetUsersResults(ctx context.Context, users []string) (map[string]quiz.QuizResults, error) {
+	defer db.observe(ctx, "GetUsersResults")()
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	results := make(map[string]quiz.QuizResults, len(users))
+	for _, user := range users {
+		if userID, err := db.getUserID(user); err == nil {
+			results[user] = db.results(userID)
+		}
+	}
+	return results, nil
+}
+
+// results must be called holding lockUsers
+func (db *InMemoryDB) results(userID uint64) quiz.QuizResults {
+	return quiz.QuizResults{
+		Correct:    db.users[userID].Correct,
+		Total:      db.users[userID].Total,
+		Categories: db.categoryResults(userID),
 	}
+}
 
-	return userResults, nil
+// categoryResults must be called holding lockUsers
+func (db *InMemoryDB) categoryResults(userID uint64) []quiz.CategoryResults {
+	results := make([]quiz.CategoryResults, 0, len(db.categories[userID]))
+	for _, r := range db.categories[userID] {
+		results = append(results, r)
+	}
+	slices.SortFunc(results, func(a, b quiz.CategoryResults) int {
+		return strings.Compare(a.Category, b.Category)
+	})
+	return results
 }
 
 func (db *InMemoryDB) getUserID(username string) (uint64, error) {
@@ -97,20 +341,55 @@ func (db *InMemoryDB) getUserID(username string) (uint64, error) {
 	return 0, ErrUserNotFound
 }
 
-func (db *InMemoryDB) InsertUser(_ context.Context, user string) error {
+// InsertUser creates the user with a random bearer token, the token is only returned here
+func (db *InMemoryDB) InsertUser(ctx context.Context, user string) (string, error) {
+	defer db.observe(ctx, "InsertUser")()
+
 	if _, err := db.getUserID(user); err == nil {
-		return ErrUserAlreadyExists
+		return "", ErrUserAlreadyExists
 	}
 
 	db.lockUsers.Lock()
 	defer db.lockUsers.Unlock()
 
+	token := newUserToken()
 	userID := uint64(len(db.users))
-	db.users = append(db.users, quiz.User{ID: userID, Name: user, Correct: 0, Total: 0})
-	return nil
+	db.users = append(db.users, quiz.User{ID: userID, Name: user, Correct: 0, Total: 0, TokenHash: hashToken(token)})
+	db.bumpVersion()
+	db.publish(quiz.EventUserCreated, quiz.WebhookEventData{User: user})
+	return token, nil
+}
+
+// AuthenticateUser is the user of the bearer token, users without a token, like the ones of old
+// archives, can't be authenticated
+func (db *InMemoryDB) AuthenticateUser(ctx context.Context, token string) (string, error) {
+	defer db.observe(ctx, "AuthenticateUser")()
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	hash := hashToken(token)
+	for _, u := range db.users {
+		if u.TokenHash != "" && subtle.ConstantTimeCompare([]byte(u.TokenHash), []byte(hash)) == 1 {
+			return u.Name, nil
+		}
+	}
+	return "", ErrInvalidToken
 }
 
-func (db *InMemoryDB) GetStatistics(_ context.Context, userName string) (quiz.StatisticsResults, error) {
+// GetStatistics compares the user with everyone else, or only with the other members when group is set
+func (db *InMemoryDB) GetStatistics(ctx context.Context, userName string, group string) (quiz.StatisticsResults, error) {
+	defer db.observe(ctx, "GetStatistics")()
+
+	knownCategories := db.knownCategories()
+	members, err := db.groupMembers(ctx, group)
+	if err != nil {
+		return quiz.StatisticsResults{}, err
+	}
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
 	if len(db.users) < 2 {
 		return quiz.StatisticsResults{}, ErrNotEnoughUsersForStatistics
 	}
@@ -120,21 +399,95 @@ func (db *InMemoryDB) GetStatistics(_ context.Context, userName string) (quiz.St
 		return quiz.StatisticsResults{}, err
 	}
 
+	return db.statistics(userID, members, knownCategories)
+}
+
+// GetUsersStatistics compares many users in one lookup like GetStatistics, unknown users and users
+// without anyone to compare with are left out
+func (db *InMemoryDB) GetUsersStatistics(ctx context.Context, users []string, group string) (map[string]quiz.StatisticsResults, error) {
+	defer db.observe(ctx, "GetUsersStatistics")()
+
+	knownCategories := db.knownCategories()
+	members, err := db.groupMembers(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	statistics := make(map[string]quiz.StatisticsResults, len(users))
+	for _, user := range users {
+		userID, err := db.getUserID(user)
+		if err != nil {
+			continue
+		}
+		if s, err := db.statistics(userID, members, knownCategories); err == nil {
+			statistics[user] = s
+		}
+	}
+	return statistics, nil
+}
+
+// statistics must be called holding lockUsers, members is nil for everyone
+func (db *InMemoryDB) statistics(userID uint64, members []string, knownCategories []string) (quiz.StatisticsResults, error) {
 	user := db.users[userID]
 
+	peers := 0
 	statisticsCorrect := uint64(0)
 	statisticsTotal := uint64(0)
+	categoriesCorrect := map[string]uint64{}
+	categoriesTotal := map[string]uint64{}
 	for _, user := range db.users {
-		// since names must be unique
-		if user.Name == userName {
+		if user.ID == userID {
 			continue
 		}
+		if members != nil && !slices.Contains(members, user.Name) {
+			continue
+		}
+		peers++
 		statisticsCorrect += user.Correct
 		statisticsTotal += user.Total
+		for category, results := range db.categories[user.ID] {
+			categoriesCorrect[category] += results.Correct
+			categoriesTotal[category] += results.Total
+		}
+	}
+
+	if peers == 0 {
+		return quiz.StatisticsResults{}, ErrNotEnoughUsersForStatistics
 	}
 
-	avgCorrect := float64(statisticsCorrect) / float64(len(db.users)-1)
-	avgTotal := float64(statisticsTotal) / float64(len(db.users)-1)
+	others := float64(peers)
+	avgCorrect := float64(statisticsCorrect) / others
+	avgTotal := float64(statisticsTotal) / others
+
+	categories := []quiz.CategoryStatistics{}
+	for _, category := range knownCategories {
+		own := db.categories[userID][category]
+		categories = append(categories, quiz.CategoryStatistics{
+			Category:   category,
+			Correct:    own.Correct,
+			Total:      own.Total,
+			AvgCorrect: float64(categoriesCorrect[category]) / others,
+			AvgTotal:   float64(categoriesTotal[category]) / others,
+		})
+	}
 
-	return quiz.StatisticsResults{Correct: user.Correct, Total: user.Total, AvgCorrect: avgCorrect, AvgTotal: avgTotal}, nil
+	return quiz.StatisticsResults{Correct: user.Correct, Total: user.Total, AvgCorrect: avgCorrect, AvgTotal: avgTotal, Categories: categories}, nil
+}
+
+// knownCategories returns the sorted categories of all the questions
+func (db *InMemoryDB) knownCategories() []string {
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	categories := []string{}
+	for _, q := range db.questions {
+		if q.Category != "" && !slices.Contains(categories, q.Category) {
+			categories = append(categories, q.Category)
+		}
+	}
+	slices.Sort(categories)
+	return categories
 }
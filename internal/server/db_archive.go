@@ -0,0 +1,396 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+var ErrInvalidArchive = errors.New("invalid archive")
+var ErrImportConflict = errors.New("record already exists")
+
+// ExportArchive calls emit with every record of an archive, the header first. The records are a
+// snapshot taken at once, emit runs without holding any lock.
+func (db *InMemoryDB) ExportArchive(ctx context.Context, emit func(quiz.ArchiveRecord) error) error {
+	defer db.observe(ctx, "ExportArchive")()
+
+	records := db.archiveRecords()
+	for _, record := range records {
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *InMemoryDB) archiveRecords() []quiz.ArchiveRecord {
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	db.lockSessions.RLock()
+	defer db.lockSessions.RUnlock()
+
+	db.lockBlobs.RLock()
+	defer db.lockBlobs.RUnlock()
+
+	records := []quiz.ArchiveRecord{{Kind: quiz.RecordArchive, Archive: &quiz.ArchiveHeader{Version: quiz.ArchiveVersion, ExportedAt: db.now()}}}
+	for _, id := range slices.Sorted(maps.Keys(db.blobs)) {
+		b := db.blobs[id]
+		records = append(records, quiz.ArchiveRecord{Kind: quiz.RecordBlob, Blob: &quiz.ArchiveBlob{ID: b.ID, MediaType: b.MediaType, Data: b.data}})
+	}
+	for _, q := range db.questions {
+		question := quiz.NewArchiveQuestion(q)
+		records = append(records, quiz.ArchiveRecord{Kind: quiz.RecordQuestion, Question: &question})
+	}
+	for _, u := range db.users {
+		records = append(records, quiz.ArchiveRecord{Kind: quiz.RecordUser, User: &quiz.ArchiveUser{Name: u.Name, TokenHash: u.TokenHash}})
+	}
+	for _, u := range db.users {
+		if !db.hasResults(u.ID) {
+			continue
+		}
+		result := quiz.ArchiveResult{User: u.Name, Correct: u.Correct, Total: u.Total, Categories: db.categoryResults(u.ID), Reviews: []quiz.Review{}}
+		for _, review := range db.reviews[u.ID] {
+			result.Reviews = append(result.Reviews, review)
+		}
+		slices.SortFunc(result.Reviews, func(a, b quiz.Review) int {
+			return cmp.Compare(a.QuestionID, b.QuestionID)
+		})
+		records = append(records, quiz.ArchiveRecord{Kind: quiz.RecordResult, Result: &result})
+	}
+	for _, s := range db.sessions {
+		attempt := quiz.ArchiveAttempt{
+			User:                db.users[s.userID].Name,
+			Quiz:                s.Quiz,
+			StartedAt:           s.StartedAt,
+			TimeLimitMs:         s.TimeLimitMs,
+			QuestionTimeLimitMs: s.QuestionTimeLimitMs,
+			QuestionIDs:         make([]uint64, 0, len(s.Questions)),
+			Answers:             make([]quiz.ArchiveAnswer, 0, len(s.Answers)),
+		}
+		for _, q := range s.Questions {
+			attempt.QuestionIDs = append(attempt.QuestionIDs, q.ID)
+		}
+		for _, a := range s.Answers {
+			attempt.Answers = append(attempt.Answers, quiz.NewArchiveAnswer(a, db.scoredQuestion(a.QuestionID, a.QuestionVersion)))
+		}
+		records = append(records, quiz.ArchiveRecord{Kind: quiz.RecordAttempt, Attempt: &attempt})
+	}
+	return records
+}
+
+// hasResults must be called holding lockUsers
+func (db *InMemoryDB) hasResults(userID uint64) bool {
+	return db.users[userID].Total > 0 || len(db.reviews[userID]) > 0
+}
+
+// archiveImport applies the records of an archive one at a time, so an archive is never held in
+// memory. A dry run writes nothing, it remembers what its records would have created instead, so
+// the records after them are checked like in a real import.
+type archiveImport struct {
+	db      *InMemoryDB
+	summary quiz.ImportSummary
+	// created by the records of a dry run
+	blobs     map[string]bool
+	questions uint64
+	users     map[string]bool
+	results   map[string]bool
+	attempts  map[attemptKey]bool
+}
+
+// attemptKey tells attempts apart across servers, the IDs of their sessions do not
+type attemptKey struct {
+	user      string
+	quiz      string
+	startedAt int64
+}
+
+func newAttemptKey(a quiz.ArchiveAttempt) attemptKey {
+	return attemptKey{user: a.User, quiz: a.Quiz, startedAt: a.StartedAt.UnixNano()}
+}
+
+func (db *InMemoryDB) newArchiveImport(mode string, dryRun bool) *archiveImport {
+	return &archiveImport{
+		db:       db,
+		summary:  quiz.ImportSummary{Mode: mode, DryRun: dryRun},
+		blobs:    map[string]bool{},
+		users:    map[string]bool{},
+		results:  map[string]bool{},
+		attempts: map[attemptKey]bool{},
+	}
+}
+
+// importRecord fails with ErrInvalidArchive, or with ErrImportConflict when the record exists
+// and the mode is quiz.ImportFail. The records before it stay imported.
+func (i *archiveImport) importRecord(record quiz.ArchiveRecord) error {
+	if err := record.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	if header := record.Kind == quiz.RecordArchive; header != (i.summary.Version == 0) {
+		return fmt.Errorf("%w: the record of kind `%s` must be the first one, and only the first", ErrInvalidArchive, quiz.RecordArchive)
+	}
+
+	i.summary.Records++
+	switch record.Kind {
+	case quiz.RecordArchive:
+		i.summary.Version = record.Archive.Version
+		return nil
+	case quiz.RecordBlob:
+		return i.importBlob(*record.Blob)
+	case quiz.RecordQuestion:
+		return i.importQuestion(*record.Question)
+	case quiz.RecordUser:
+		return i.importUser(*record.User)
+	case quiz.RecordResult:
+		return i.importResult(*record.Result)
+	default:
+		return i.importAttempt(*record.Attempt)
+	}
+}
+
+// resolve counts the record and tells whether to write it, exists when it conflicts with what the
+// server has or with a record before it
+func (i *archiveImport) resolve(counts *quiz.ImportCounts, exists bool, record string) (bool, error) {
+	if !exists {
+		counts.Created++
+		return !i.summary.DryRun, nil
+	}
+
+	switch i.summary.Mode {
+	case quiz.ImportOverwrite:
+		counts.Overwritten++
+		return !i.summary.DryRun, nil
+	case quiz.ImportSkip:
+		counts.Skipped++
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: %s", ErrImportConflict, record)
+	}
+}
+
+// nextQuestionID must be called holding lockQuestions
+func (i *archiveImport) nextQuestionID() uint64 {
+	return uint64(len(i.db.questions)) + i.questions
+}
+
+// importBlob skips the blobs the server has in any mode, a blob with the same ID has the same content
+func (i *archiveImport) importBlob(b quiz.ArchiveBlob) error {
+	db := i.db
+	db.lockBlobs.Lock()
+	defer db.lockBlobs.Unlock()
+
+	if _, ok := db.blobs[b.ID]; ok || i.blobs[b.ID] {
+		i.summary.Blobs.Skipped++
+		return nil
+	}
+
+	i.summary.Blobs.Created++
+	if i.summary.DryRun {
+		i.blobs[b.ID] = true
+		return nil
+	}
+	db.putBlob(b.MediaType, b.Data)
+	return nil
+}
+
+// missingBlob of the IDs, one that is neither in the server nor in the records before
+func (i *archiveImport) missingBlob(ids []string) (string, bool) {
+	i.db.lockBlobs.RLock()
+	defer i.db.lockBlobs.RUnlock()
+
+	for _, id := range ids {
+		if _, ok := i.db.blobs[id]; !ok && !i.blobs[id] {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// importQuestion keeps the ID of the question, new questions must follow the ones of the server
+func (i *archiveImport) importQuestion(q quiz.ArchiveQuestion) error {
+	db := i.db
+	db.lockQuestions.Lock()
+	defer db.lockQuestions.Unlock()
+
+	next := i.nextQuestionID()
+	if q.ID > next {
+		return fmt.Errorf("%w: question `%d` leaves a gap, the next question is `%d`", ErrInvalidArchive, q.ID, next)
+	}
+	if id, ok := i.missingBlob(q.Attachments); ok {
+		return fmt.Errorf("%w: question `%d` attaches blob `%s`, which is not in the archive nor in the server", ErrInvalidArchive, q.ID, id)
+	}
+
+	write, err := i.resolve(&i.summary.Questions, q.ID < next, fmt.Sprintf("question `%d`", q.ID))
+	if err != nil {
+		return err
+	}
+	if q.ID == next && i.summary.DryRun {
+		i.questions++
+	}
+	if !write {
+		return nil
+	}
+
+	// an overwrite with the same question is not an edit, it keeps the version
+	if db.putQuestion(q.Question()) {
+		db.bumpVersion()
+	}
+	return nil
+}
+
+// importUser does not publish quiz.EventUserCreated, an import copies users created elsewhere
+func (i *archiveImport) importUser(u quiz.ArchiveUser) error {
+	db := i.db
+	db.lockUsers.Lock()
+	defer db.lockUsers.Unlock()
+
+	userID, err := db.getUserID(u.Name)
+	exists := err == nil || i.users[u.Name]
+	write, err := i.resolve(&i.summary.Users, exists, fmt.Sprintf("user `%s`", u.Name))
+	if err != nil {
+		return err
+	}
+	if !exists && i.summary.DryRun {
+		i.users[u.Name] = true
+	}
+	if !write {
+		return nil
+	}
+	// overwriting a user only replaces its token, when the archive has one
+	if exists {
+		if err == nil && u.TokenHash != "" {
+			db.users[userID].TokenHash = u.TokenHash
+		}
+		return nil
+	}
+
+	db.users = append(db.users, quiz.User{ID: uint64(len(db.users)), Name: u.Name, TokenHash: u.TokenHash})
+	db.bumpVersion()
+	return nil
+}
+
+// importResult replaces every result of the user, a user that answered anything has results
+func (i *archiveImport) importResult(r quiz.ArchiveResult) error {
+	db := i.db
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.Lock()
+	defer db.lockUsers.Unlock()
+
+	userID, err := db.getUserID(r.User)
+	if err != nil && !i.users[r.User] {
+		return fmt.Errorf("%w: results of user `%s`, who is not in the archive nor in the server", ErrInvalidArchive, r.User)
+	}
+	for _, review := range r.Reviews {
+		if review.QuestionID >= i.nextQuestionID() {
+			return fmt.Errorf("%w: results of user `%s` review question `%d`, which is not in the archive nor in the server", ErrInvalidArchive, r.User, review.QuestionID)
+		}
+	}
+
+	exists := i.results[r.User] || (err == nil && db.hasResults(userID))
+	write, err := i.resolve(&i.summary.Results, exists, fmt.Sprintf("results of user `%s`", r.User))
+	if err != nil {
+		return err
+	}
+	if !exists && i.summary.DryRun {
+		i.results[r.User] = true
+	}
+	if !write {
+		return nil
+	}
+
+	db.users[userID].Correct = r.Correct
+	db.users[userID].Total = r.Total
+	categories := make(map[string]quiz.CategoryResults, len(r.Categories))
+	for _, c := range r.Categories {
+		categories[c.Category] = c
+	}
+	db.categories[userID] = categories
+	reviews := make(map[uint64]quiz.Review, len(r.Reviews))
+	for _, review := range r.Reviews {
+		reviews[review.QuestionID] = review
+	}
+	db.reviews[userID] = reviews
+	// the answers outside of sessions are not archived, the imported results cannot be regraded
+	delete(db.answers, userID)
+	db.bumpVersion(userID)
+	return nil
+}
+
+// importAttempt records the session as it was, the results of the user are not scored again
+func (i *archiveImport) importAttempt(a quiz.ArchiveAttempt) error {
+	db := i.db
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	db.lockSessions.Lock()
+	defer db.lockSessions.Unlock()
+
+	userID, err := db.getUserID(a.User)
+	if err != nil && !i.users[a.User] {
+		return fmt.Errorf("%w: attempt of user `%s`, who is not in the archive nor in the server", ErrInvalidArchive, a.User)
+	}
+	for _, questionID := range a.QuestionIDs {
+		if questionID >= i.nextQuestionID() {
+			return fmt.Errorf("%w: attempt of user `%s` asks question `%d`, which is not in the archive nor in the server", ErrInvalidArchive, a.User, questionID)
+		}
+	}
+
+	existing := -1
+	if err == nil {
+		existing = slices.IndexFunc(db.sessions, func(s session) bool {
+			return s.userID == userID && s.Quiz == a.Quiz && s.StartedAt.Equal(a.StartedAt)
+		})
+	}
+	key := newAttemptKey(a)
+	exists := i.attempts[key] || existing >= 0
+	write, err := i.resolve(&i.summary.Attempts, exists, fmt.Sprintf("attempt of user `%s` started at %s", a.User, a.StartedAt.Format(time.RFC3339Nano)))
+	if err != nil {
+		return err
+	}
+	if !exists && i.summary.DryRun {
+		i.attempts[key] = true
+	}
+	if !write {
+		return nil
+	}
+
+	questions := make([]quiz.Question, 0, len(a.QuestionIDs))
+	for _, questionID := range a.QuestionIDs {
+		questions = append(questions, db.questions[questionID])
+	}
+	limits := SessionLimits{
+		TimeLimit:         time.Duration(a.TimeLimitMs) * time.Millisecond,
+		QuestionTimeLimit: time.Duration(a.QuestionTimeLimitMs) * time.Millisecond,
+	}
+
+	id := uint64(len(db.sessions))
+	if existing >= 0 {
+		id = uint64(existing)
+	}
+	s := newSession(id, userID, a.Quiz, questions, limits, a.StartedAt)
+	for _, answer := range a.Answers {
+		s.Answers = append(s.Answers, answer.AnswerRecord(db.scoredQuestion(answer.QuestionID, answer.QuestionVersion)))
+	}
+
+	if existing >= 0 {
+		db.sessions[id] = s
+	} else {
+		db.sessions = append(db.sessions, s)
+	}
+	db.bumpVersion()
+	return nil
+}
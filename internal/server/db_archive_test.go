@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func exportRecords(t *testing.T, db *InMemoryDB) []quiz.ArchiveRecord {
+	t.Helper()
+	records := []quiz.ArchiveRecord{}
+	err := db.ExportArchive(context.Background(), func(record quiz.ArchiveRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	return records
+}
+
+func importRecords(db *InMemoryDB, mode string, dryRun bool, records []quiz.ArchiveRecord) (quiz.ImportSummary, error) {
+	archive := db.newArchiveImport(mode, dryRun)
+	for _, record := range records {
+		if err := archive.importRecord(record); err != nil {
+			return archive.summary, err
+		}
+	}
+	return archive.summary, nil
+}
+
+func TestExportImportArchive(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	source, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+
+	token, err := source.InsertUser(ctx, "other")
+	if err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+	if err := source.InsertQuizAnswer(ctx, "other", quiz.QuizAnswer{0: "Paris", 2: "1"}); err != nil {
+		t.Fatalf("failed to insert answers: %v", err)
+	}
+	s, err := source.CreateSession(ctx, "other", QuestionFilter{}, SessionLimits{})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	q := s.Questions[0]
+	if _, err := source.InsertSessionAnswer(ctx, "other", s.ID, quiz.QuizAnswer{q.ID: q.OptionIDs[0]}); err != nil {
+		t.Fatalf("failed to answer session: %v", err)
+	}
+
+	records := exportRecords(t, source)
+	// header, 5 questions, 2 users, the results of other and its attempt
+	if len(records) != 10 || records[0].Kind != quiz.RecordArchive || records[len(records)-1].Kind != quiz.RecordAttempt {
+		t.Fatalf("expected 10 records from the header to the attempt, got %d", len(records))
+	}
+
+	target, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+
+	_, err = importRecords(target, quiz.ImportFail, true, records[6:])
+	if !errors.Is(err, ErrInvalidArchive) {
+		t.Fatalf("expected an archive without header to be invalid, got %v", err)
+	}
+
+	// a dry run counts the results of a user it would create, but writes nothing
+	summary, err := importRecords(target, quiz.ImportSkip, true, records)
+	if err != nil {
+		t.Fatalf("failed to dry run: %v", err)
+	}
+	want := quiz.ImportSummary{Mode: quiz.ImportSkip, DryRun: true, Version: quiz.ArchiveVersion, Records: 10,
+		Questions: quiz.ImportCounts{Skipped: 5}, Users: quiz.ImportCounts{Created: 1, Skipped: 1},
+		Results: quiz.ImportCounts{Created: 1}, Attempts: quiz.ImportCounts{Created: 1}}
+	if summary != want {
+		t.Fatalf("expected %+v, got %+v", want, summary)
+	}
+	if _, err := target.GetResults(ctx, "other"); err != ErrUserNotFound {
+		t.Fatalf("expected the dry run to write nothing, got %v", err)
+	}
+
+	if _, err := importRecords(target, quiz.ImportFail, false, records); !errors.Is(err, ErrImportConflict) {
+		t.Fatalf("expected the existing questions to conflict, got %v", err)
+	}
+	if _, err := importRecords(target, quiz.ImportSkip, false, records); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+
+	// the token hash travels with the user, the token keeps working
+	if user, err := target.AuthenticateUser(ctx, token); err != nil || user != "other" {
+		t.Fatalf("expected the token of other, got %q, %v", user, err)
+	}
+
+	exported, err := source.GetResults(ctx, "other")
+	if err != nil {
+		t.Fatalf("failed to get results: %v", err)
+	}
+	got, err := target.GetResults(ctx, "other")
+	if err != nil || got.Correct != exported.Correct || got.Total != exported.Total || len(got.Categories) != len(exported.Categories) {
+		t.Fatalf("expected the results %+v, got %+v, %v", exported, got, err)
+	}
+	answered, err := source.GetSession(ctx, "other", s.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	imported, err := target.GetSession(ctx, "other", 0)
+	if err != nil || !imported.StartedAt.Equal(answered.StartedAt) || len(imported.Answers) != 1 || imported.Correct() != answered.Correct() {
+		t.Fatalf("expected the attempt %+v, got %+v, %v", answered, imported, err)
+	}
+
+	// importing again changes nothing, the attempt is told apart by its user and start
+	summary, err = importRecords(target, quiz.ImportOverwrite, false, records)
+	if err != nil || summary.Attempts.Overwritten != 1 || summary.Results.Overwritten != 1 {
+		t.Fatalf("expected the attempt and the results overwritten, got %+v, %v", summary, err)
+	}
+	if _, err := target.GetSession(ctx, "other", 1); err != ErrSessionNotFound {
+		t.Fatalf("expected no second session, got %v", err)
+	}
+}
+
+func TestImportArchiveInvalid(t *testing.T) {
+	t.Parallel()
+	header := quiz.ArchiveRecord{Kind: quiz.RecordArchive, Archive: &quiz.ArchiveHeader{Version: quiz.ArchiveVersion}}
+
+	tests := []struct {
+		name   string
+		record quiz.ArchiveRecord
+	}{
+		{name: "second header", record: header},
+		{name: "kind without its field", record: quiz.ArchiveRecord{Kind: quiz.RecordUser}},
+		{name: "question gap", record: quiz.ArchiveRecord{Kind: quiz.RecordQuestion, Question: &quiz.ArchiveQuestion{ID: 9, Text: "?", Options: []string{"a"}, Answer: "a"}}},
+		{name: "answer not an option", record: quiz.ArchiveRecord{Kind: quiz.RecordQuestion, Question: &quiz.ArchiveQuestion{ID: 5, Text: "?", Options: []string{"a"}, Answer: "b"}}},
+		{name: "translation shown like an untranslated option", record: quiz.ArchiveRecord{Kind: quiz.RecordQuestion, Question: &quiz.ArchiveQuestion{ID: 5, Text: "?", Options: []string{"a", "b"}, Answer: "a",
+			Translations: map[string]quiz.Translation{"es": {Options: map[string]string{"a": "b"}}}}}},
+		{name: "results of unknown user", record: quiz.ArchiveRecord{Kind: quiz.RecordResult, Result: &quiz.ArchiveResult{User: "unknown"}}},
+		{name: "attempt of unknown question", record: quiz.ArchiveRecord{Kind: quiz.RecordAttempt, Attempt: &quiz.ArchiveAttempt{User: "user", QuestionIDs: []uint64{9}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := NewInMemoryDB()
+			if err != nil {
+				t.Fatalf("failed to create db: %v", err)
+			}
+			if _, err := importRecords(db, quiz.ImportOverwrite, false, []quiz.ArchiveRecord{header, tt.record}); !errors.Is(err, ErrInvalidArchive) {
+				t.Fatalf("expected ErrInvalidArchive, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+type blob struct {
+	quiz.Blob
+	data []byte
+}
+
+// PutBlob stores the data under its SHA-256, storing the same data again changes nothing
+func (db *InMemoryDB) PutBlob(ctx context.Context, mediaType string, data []byte) (quiz.Blob, error) {
+	defer db.observe(ctx, "PutBlob")()
+
+	db.lockBlobs.Lock()
+	defer db.lockBlobs.Unlock()
+
+	return db.putBlob(mediaType, data), nil
+}
+
+// putBlob must be called holding lockBlobs
+func (db *InMemoryDB) putBlob(mediaType string, data []byte) quiz.Blob {
+	id := quiz.BlobID(data)
+	if b, ok := db.blobs[id]; ok {
+		return b.Blob
+	}
+
+	b := blob{Blob: quiz.Blob{ID: id, MediaType: mediaType, Size: int64(len(data))}, data: slices.Clone(data)}
+	db.blobs[id] = b
+	return b.Blob
+}
+
+// GetBlob and its data, which the caller must not modify
+func (db *InMemoryDB) GetBlob(ctx context.Context, id string) (quiz.Blob, []byte, error) {
+	defer db.observe(ctx, "GetBlob")()
+
+	db.lockBlobs.RLock()
+	defer db.lockBlobs.RUnlock()
+
+	b, ok := db.blobs[id]
+	if !ok {
+		return quiz.Blob{}, nil, ErrBlobNotFound
+	}
+	return b.Blob, b.data, nil
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// InsertGroup creates the group with its creator as the first owner
+func (db *InMemoryDB) InsertGroup(ctx context.Context, g quiz.Group, owner string) (quiz.Group, error) {
+	defer db.observe(ctx, "InsertGroup")()
+
+	db.lockUsers.RLock()
+	_, err := db.getUserID(owner)
+	db.lockUsers.RUnlock()
+	if err != nil {
+		return quiz.Group{}, err
+	}
+
+	db.lockGroups.Lock()
+	defer db.lockGroups.Unlock()
+
+	if _, ok := db.groups[g.Slug]; ok {
+		return quiz.Group{}, ErrGroupAlreadyExists
+	}
+
+	g.Owners = []string{owner}
+	g.Members = []string{owner}
+	db.groups[g.Slug] = g
+	db.bumpVersion()
+	return g, nil
+}
+
+func (db *InMemoryDB) GetGroup(ctx context.Context, slug string) (quiz.Group, error) {
+	defer db.observe(ctx, "GetGroup")()
+
+	db.lockGroups.RLock()
+	defer db.lockGroups.RUnlock()
+
+	g, ok := db.groups[slug]
+	if !ok {
+		return quiz.Group{}, ErrGroupNotFound
+	}
+	return g, nil
+}
+
+func (db *InMemoryDB) GetGroups(ctx context.Context) ([]quiz.Group, error) {
+	defer db.observe(ctx, "GetGroups")()
+
+	db.lockGroups.RLock()
+	defer db.lockGroups.RUnlock()
+
+	groups := make([]quiz.Group, 0, len(db.groups))
+	for _, g := range db.groups {
+		groups = append(groups, g)
+	}
+	slices.SortFunc(groups, func(a, b quiz.Group) int {
+		return strings.Compare(a.Slug, b.Slug)
+	})
+	return groups, nil
+}
+
+// PutGroupMember adds the user to the group, owners can also make other members owners.
+// The actor must own the group, or be empty when the admin acts.
+func (db *InMemoryDB) PutGroupMember(ctx context.Context, slug string, actor string, user string, owner bool) (quiz.Group, error) {
+	defer db.observe(ctx, "PutGroupMember")()
+
+	db.lockUsers.RLock()
+	_, err := db.getUserID(user)
+	db.lockUsers.RUnlock()
+	if err != nil {
+		return quiz.Group{}, err
+	}
+
+	db.lockGroups.Lock()
+	defer db.lockGroups.Unlock()
+
+	g, err := db.getGroupAs(slug, actor)
+	if err != nil {
+		return quiz.Group{}, err
+	}
+
+	if !g.IsMember(user) {
+		g.Members = append(slices.Clone(g.Members), user)
+	}
+	if owner && !g.IsOwner(user) {
+		g.Owners = append(slices.Clone(g.Owners), user)
+	}
+	db.groups[slug] = g
+	db.bumpVersion()
+	return g, nil
+}
+
+func (db *InMemoryDB) DeleteGroupMember(ctx context.Context, slug string, actor string, user string) (quiz.Group, error) {
+	defer db.observe(ctx, "DeleteGroupMember")()
+
+	db.lockGroups.Lock()
+	defer db.lockGroups.Unlock()
+
+	g, err := db.getGroupAs(slug, actor)
+	if err != nil {
+		return quiz.Group{}, err
+	}
+
+	if !g.IsMember(user) {
+		return quiz.Group{}, ErrUserNotFound
+	}
+	if g.IsOwner(user) && len(g.Owners) == 1 {
+		return quiz.Group{}, ErrLastGroupOwner
+	}
+
+	g.Members = slices.DeleteFunc(slices.Clone(g.Members), func(m string) bool { return m == user })
+	g.Owners = slices.DeleteFunc(slices.Clone(g.Owners), func(o string) bool { return o == user })
+	db.groups[slug] = g
+	db.bumpVersion()
+	return g, nil
+}
+
+// getGroupAs must be called holding lockGroups
+func (db *InMemoryDB) getGroupAs(slug string, actor string) (quiz.Group, error) {
+	g, ok := db.groups[slug]
+	if !ok {
+		return quiz.Group{}, ErrGroupNotFound
+	}
+	if actor != "" && !g.IsOwner(actor) {
+		return quiz.Group{}, ErrNotGroupOwner
+	}
+	return g, nil
+}
+
+// GetLeaderboard ranks users by correct answers then accuracy, only members when group is set
+func (db *InMemoryDB) GetLeaderboard(ctx context.Context, group string) ([]quiz.LeaderboardEntry, error) {
+	defer db.observe(ctx, "GetLeaderboard")()
+
+	members, err := db.groupMembers(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	leaderboard := []quiz.LeaderboardEntry{}
+	for _, u := range db.users {
+		if members != nil && !slices.Contains(members, u.Name) {
+			continue
+		}
+		leaderboard = append(leaderboard, quiz.LeaderboardEntry{User: u.Name, Correct: u.Correct, Total: u.Total, Accuracy: quiz.Accuracy(u.Correct, u.Total)})
+	}
+
+	slices.SortStableFunc(leaderboard, func(a, b quiz.LeaderboardEntry) int {
+		return cmp.Or(cmp.Compare(b.Correct, a.Correct), cmp.Compare(b.Accuracy, a.Accuracy), strings.Compare(a.User, b.User))
+	})
+	for i := range leaderboard {
+		leaderboard[i].Rank = uint64(i + 1)
+	}
+	return leaderboard, nil
+}
+
+// GetGroupStatistics aggregates every group so they can be compared, best accuracy first
+func (db *InMemoryDB) GetGroupStatistics(ctx context.Context) ([]quiz.GroupStatistics, error) {
+	defer db.observe(ctx, "GetGroupStatistics")()
+
+	groups, err := db.GetGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	statistics := make([]quiz.GroupStatistics, 0, len(groups))
+	for _, g := range groups {
+		s := quiz.GroupStatistics{Group: g.Slug, Kind: g.Kind, Members: uint64(len(g.Members))}
+		for _, member := range g.Members {
+			userID, err := db.getUserID(member)
+			if err != nil {
+				return nil, err
+			}
+			s.Correct += db.users[userID].Correct
+			s.Total += db.users[userID].Total
+		}
+		if s.Members > 0 {
+			s.AvgCorrect = float64(s.Correct) / float64(s.Members)
+			s.AvgTotal = float64(s.Total) / float64(s.Members)
+		}
+		s.Accuracy = quiz.Accuracy(s.Correct, s.Total)
+		statistics = append(statistics, s)
+	}
+
+	slices.SortStableFunc(statistics, func(a, b quiz.GroupStatistics) int {
+		return cmp.Compare(b.Accuracy, a.Accuracy)
+	})
+	return statistics, nil
+}
+
+// groupMembers returns nil when no group is given, meaning everyone
+func (db *InMemoryDB) groupMembers(ctx context.Context, group string) ([]string, error) {
+	if group == "" {
+		return nil, nil
+	}
+
+	g, err := db.GetGroup(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	return g.Members, nil
+}
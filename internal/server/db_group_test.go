@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func testGroupDB(t *testing.T) *InMemoryDB {
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	for _, user := range []string{"a", "b", "c"} {
+		if _, err := db.InsertUser(context.Background(), user); err != nil {
+			t.Fatalf("Error inserting user: %v", err)
+		}
+	}
+
+	_, err = db.InsertGroup(context.Background(), quiz.Group{Slug: "team-a", Kind: "team"}, "user")
+	if err != nil {
+		t.Fatalf("Error inserting group: %v", err)
+	}
+	return db
+}
+
+func TestGroupMembers(t *testing.T) {
+	t.Parallel()
+	db := testGroupDB(t)
+
+	_, err := db.InsertGroup(context.Background(), quiz.Group{Slug: "team-a", Kind: "team"}, "a")
+	if err != ErrGroupAlreadyExists {
+		t.Fatalf("Expected ErrGroupAlreadyExists, got %v", err)
+	}
+
+	_, err = db.InsertGroup(context.Background(), quiz.Group{Slug: "team-b", Kind: "team"}, "unknown")
+	if err != ErrUserNotFound {
+		t.Fatalf("Expected ErrUserNotFound, got %v", err)
+	}
+
+	_, err = db.PutGroupMember(context.Background(), "team-a", "a", "b", false)
+	if err != ErrNotGroupOwner {
+		t.Fatalf("Expected ErrNotGroupOwner, got %v", err)
+	}
+
+	_, err = db.PutGroupMember(context.Background(), "unknown", "user", "b", false)
+	if err != ErrGroupNotFound {
+		t.Fatalf("Expected ErrGroupNotFound, got %v", err)
+	}
+
+	group, err := db.PutGroupMember(context.Background(), "team-a", "user", "a", true)
+	if err != nil {
+		t.Fatalf("Error putting group member: %v", err)
+	}
+
+	if !group.IsOwner("a") || !group.IsMember("a") {
+		t.Fatalf("Expected a to be owner and member, got %+v", group)
+	}
+
+	// new owners manage members too, the admin acts as anyone
+	_, err = db.PutGroupMember(context.Background(), "team-a", "a", "b", false)
+	if err != nil {
+		t.Fatalf("Error putting group member: %v", err)
+	}
+
+	group, err = db.PutGroupMember(context.Background(), "team-a", "", "c", false)
+	if err != nil {
+		t.Fatalf("Error putting group member: %v", err)
+	}
+
+	if !slices.Equal(group.Members, []string{"user", "a", "b", "c"}) {
+		t.Fatalf("Expected members user, a, b and c, got %v", group.Members)
+	}
+
+	group, err = db.DeleteGroupMember(context.Background(), "team-a", "a", "user")
+	if err != nil {
+		t.Fatalf("Error deleting group member: %v", err)
+	}
+
+	if group.IsMember("user") || group.IsOwner("user") {
+		t.Fatalf("Expected user to be gone, got %+v", group)
+	}
+
+	_, err = db.DeleteGroupMember(context.Background(), "team-a", "a", "a")
+	if err != ErrLastGroupOwner {
+		t.Fatalf("Expected ErrLastGroupOwner, got %v", err)
+	}
+}
+
+func TestGroupStatisticsAndLeaderboard(t *testing.T) {
+	t.Parallel()
+	db := testGroupDB(t)
+
+	_, err := db.PutGroupMember(context.Background(), "team-a", "user", "a", false)
+	if err != nil {
+		t.Fatalf("Error putting group member: %v", err)
+	}
+
+	_, err = db.InsertGroup(context.Background(), quiz.Group{Slug: "team-b", Kind: "team"}, "b")
+	if err != nil {
+		t.Fatalf("Error inserting group: %v", err)
+	}
+
+	for user, answer := range map[string]quiz.QuizAnswer{
+		"user": {0: "Paris", 1: "Berlin"},
+		"a":    {0: "London"},
+		"b":    {0: "Paris", 2: "1"},
+		"c":    {0: "Paris", 1: "Berlin", 2: "4"},
+	} {
+		if err := db.InsertQuizAnswer(context.Background(), user, answer); err != nil {
+			t.Fatalf("Error inserting quiz answer: %v", err)
+		}
+	}
+
+	// only compared with a inside team-a
+	statistics, err := db.GetStatistics(context.Background(), "user", "team-a")
+	if err != nil {
+		t.Fatalf("Error getting statistics: %v", err)
+	}
+
+	if statistics.AvgCorrect != 0 || statistics.AvgTotal != 1 {
+		t.Fatalf("Expected avg 0 correct out of 1, got %f out of %f", statistics.AvgCorrect, statistics.AvgTotal)
+	}
+
+	_, err = db.GetStatistics(context.Background(), "b", "team-b")
+	if err != ErrNotEnoughUsersForStatistics {
+		t.Fatalf("Expected ErrNotEnoughUsersForStatistics, got %v", err)
+	}
+
+	_, err = db.GetStatistics(context.Background(), "user", "unknown")
+	if err != ErrGroupNotFound {
+		t.Fatalf("Expected ErrGroupNotFound, got %v", err)
+	}
+
+	leaderboard, err := db.GetLeaderboard(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Error getting leaderboard: %v", err)
+	}
+
+	users := []string{}
+	for _, entry := range leaderboard {
+		users = append(users, entry.User)
+	}
+	if !slices.Equal(users, []string{"c", "user", "b", "a"}) {
+		t.Fatalf("Expected ranking c, user, b, a, got %v", users)
+	}
+
+	leaderboard, err = db.GetLeaderboard(context.Background(), "team-a")
+	if err != nil {
+		t.Fatalf("Error getting leaderboard: %v", err)
+	}
+
+	if len(leaderboard) != 2 || leaderboard[0].User != "user" || leaderboard[0].Rank != 1 || leaderboard[1].Rank != 2 {
+		t.Fatalf("Expected user first of 2, got %+v", leaderboard)
+	}
+
+	groups, err := db.GetGroupStatistics(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting group statistics: %v", err)
+	}
+
+	want := []quiz.GroupStatistics{
+		{Group: "team-a", Kind: "team", Members: 2, Correct: 2, Total: 3, AvgCorrect: 1, AvgTotal: 1.5, Accuracy: 2.0 / 3},
+		{Group: "team-b", Kind: "team", Members: 1, Correct: 1, Total: 2, AvgCorrect: 1, AvgTotal: 2, Accuracy: 0.5},
+	}
+	if !slices.Equal(groups, want) {
+		t.Fatalf("Expected group statistics %+v, got %+v", want, groups)
+	}
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// putQuestion records the question as its next version, or as the first one of a new question, the
+// ID of a new question must be the next one. Putting the latest version again changes nothing.
+// It must be called holding lockQuestions and tells whether the question changed.
+func (db *InMemoryDB) putQuestion(q quiz.Question) bool {
+	versions := db.questionVersions[q.ID]
+	if len(versions) > 0 && sameQuestion(db.questions[q.ID], q) {
+		return false
+	}
+
+	q.Version = uint64(len(versions)) + 1
+	db.questionVersions[q.ID] = append(versions, quiz.QuestionVersion{Version: q.Version, EditedAt: db.now(), Question: quiz.NewArchiveQuestion(q)})
+	if q.ID == uint64(len(db.questions)) {
+		db.questions = append(db.questions, q)
+	} else {
+		db.questions[q.ID] = q
+	}
+	return true
+}
+
+// sameQuestion compares what an edit can change, as it is exported, so that a nil and an empty
+// list of tags are the same
+func sameQuestion(a, b quiz.Question) bool {
+	encodedA, errA := json.Marshal(quiz.NewArchiveQuestion(a))
+	encodedB, errB := json.Marshal(quiz.NewArchiveQuestion(b))
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+// scoredQuestion is the version of the question an answer was scored against, the latest one when
+// the version is unknown. It must be called holding lockQuestions.
+func (db *InMemoryDB) scoredQuestion(questionID uint64, version uint64) quiz.Question {
+	versions := db.questionVersions[questionID]
+	if version == 0 || version > uint64(len(versions)) {
+		return db.questions[questionID]
+	}
+	q := versions[version-1].Question.Question()
+	q.Version = version
+	return q
+}
+
+// PutQuestion edits the question into its next version, and answers that version. Questions are
+// created by the imports. The answers record their option by its index, so an edit keeps the
+// options in place and a fix of the answer is regraded with Regrade.
+func (db *InMemoryDB) PutQuestion(ctx context.Context, q quiz.Question) (quiz.QuestionVersion, error) {
+	defer db.observe(ctx, "PutQuestion")()
+
+	db.lockQuestions.Lock()
+	defer db.lockQuestions.Unlock()
+
+	if q.ID >= uint64(len(db.questions)) {
+		return quiz.QuestionVersion{}, ErrQuestionNotFound
+	}
+	if !db.hasBlobs(q.Attachments) {
+		return quiz.QuestionVersion{}, ErrBlobNotFound
+	}
+
+	// putting the latest version again is not an edit, it keeps the version
+	if db.putQuestion(q) {
+		db.bumpVersion()
+	}
+	versions := db.questionVersions[q.ID]
+	return versions[len(versions)-1], nil
+}
+
+// hasBlobs of every ID
+func (db *InMemoryDB) hasBlobs(ids []string) bool {
+	db.lockBlobs.RLock()
+	defer db.lockBlobs.RUnlock()
+
+	for _, id := range ids {
+		if _, ok := db.blobs[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// GetQuestionVersions of the question, the first one first
+func (db *InMemoryDB) GetQuestionVersions(ctx context.Context, questionID uint64) ([]quiz.QuestionVersion, error) {
+	defer db.observe(ctx, "GetQuestionVersions")()
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	versions, ok := db.questionVersions[questionID]
+	if !ok {
+		return nil, ErrQuestionNotFound
+	}
+	return slices.Clone(versions), nil
+}
+
+// Regrade scores again, against the latest version of the question, its answers that were scored
+// against an older version, in attempts or outside of them, and moves the results of their users by
+// the difference. Answers of unknown version are left as they are, and so is the review state,
+// which only schedules the questions again.
+func (db *InMemoryDB) Regrade(ctx context.Context, questionID uint64, dryRun bool) (quiz.RegradeReport, error) {
+	defer db.observe(ctx, "Regrade")()
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.Lock()
+	defer db.lockUsers.Unlock()
+
+	db.lockSessions.Lock()
+	defer db.lockSessions.Unlock()
+
+	if questionID >= uint64(len(db.questions)) {
+		return quiz.RegradeReport{}, ErrQuestionNotFound
+	}
+	latest := db.questions[questionID]
+
+	report := quiz.RegradeReport{QuestionID: questionID, Version: latest.Version, DryRun: dryRun, Users: []quiz.RegradeUser{}}
+	changed := map[uint64]*quiz.RegradeUser{}
+	outdated := func(a quiz.AnswerRecord) bool {
+		return a.QuestionID == questionID && a.QuestionVersion != 0 && a.QuestionVersion != latest.Version
+	}
+	// regrade the answer of the user, in the session when it has one, and answer it scored again
+	regrade := func(userID uint64, a quiz.AnswerRecord, sessionID *uint64) quiz.AnswerRecord {
+		report.Answers++
+
+		// an answer moves from the category it was scored in to the one of the latest version, its
+		// option is the same index in the options of the latest version
+		category := db.scoredQuestion(questionID, a.QuestionVersion).Category
+		correct := !a.Late && latest.IsCorrect(a.Option)
+		if correct != a.Correct {
+			user, ok := changed[userID]
+			if !ok {
+				u := db.users[userID]
+				user = &quiz.RegradeUser{User: u.Name, CorrectBefore: u.Correct, CorrectAfter: u.Correct, Total: u.Total, Sessions: []uint64{}}
+				changed[userID] = user
+			}
+			if correct {
+				user.CorrectAfter++
+			} else if user.CorrectAfter > 0 {
+				user.CorrectAfter--
+			}
+			if sessionID != nil && !slices.Contains(user.Sessions, *sessionID) {
+				user.Sessions = append(user.Sessions, *sessionID)
+			}
+		}
+		if !dryRun {
+			db.rescore(userID, category, a.Correct, latest.Category, correct)
+		}
+
+		a.Correct = correct
+		a.QuestionVersion = latest.Version
+		return a
+	}
+
+	for i, s := range db.sessions {
+		var answers []quiz.AnswerRecord
+		for j, a := range s.Answers {
+			if !outdated(a) {
+				continue
+			}
+			regraded := regrade(s.userID, a, &s.ID)
+			if dryRun {
+				continue
+			}
+
+			// the answers are shared with the sessions already read
+			if answers == nil {
+				answers = slices.Clone(s.Answers)
+			}
+			answers[j] = regraded
+		}
+		if answers != nil {
+			db.sessions[i].Answers = answers
+		}
+	}
+
+	for userID, answers := range db.answers {
+		for j, a := range answers {
+			if !outdated(a) {
+				continue
+			}
+			regraded := regrade(userID, a, nil)
+			if !dryRun {
+				answers[j] = regraded
+			}
+		}
+	}
+
+	for _, user := range changed {
+		report.Users = append(report.Users, *user)
+	}
+	slices.SortFunc(report.Users, func(a, b quiz.RegradeUser) int {
+		return cmp.Compare(a.User, b.User)
+	})
+	if !dryRun && report.Answers > 0 {
+		db.bumpVersion(slices.Collect(maps.Keys(changed))...)
+	}
+	return report, nil
+}
+
+// rescore an answer that scored correct in category as newCorrect in newCategory, it must be called
+// holding lockUsers. Results that were imported may not add up, so they never go below zero.
+func (db *InMemoryDB) rescore(userID uint64, category string, correct bool, newCategory string, newCorrect bool) {
+	user := &db.users[userID]
+	if correct && user.Correct > 0 {
+		user.Correct--
+	}
+	if newCorrect {
+		user.Correct++
+	}
+
+	if results, ok := db.categories[userID][category]; ok && category != "" {
+		if correct && results.Correct > 0 {
+			results.Correct--
+		}
+		if results.Total > 0 {
+			results.Total--
+		}
+		if results.Total == 0 {
+			delete(db.categories[userID], category)
+		} else {
+			db.categories[userID][category] = results
+		}
+	}
+	db.categorize(userID, newCategory, newCorrect)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestRegrade(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+
+	// the key of "What is 2 + 2?" is 4, the user answers 3
+	session, err := db.CreateSession(ctx, "user", QuestionFilter{Tags: []string{"addition"}}, SessionLimits{})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	q := session.Questions[0]
+	records, err := db.InsertSessionAnswer(ctx, "user", session.ID, quiz.QuizAnswer{q.ID: q.OptionIDs[slices.Index(q.Options, "3")]})
+	if err != nil || records[0].Correct || records[0].QuestionVersion != 1 {
+		t.Fatalf("expected a wrong answer to version 1, got %+v, %v", records, err)
+	}
+
+	// fixing the key makes version 2, overwriting it with the same question does not make a third
+	fixed := quiz.NewArchiveQuestion(db.questions[q.ID])
+	fixed.Answer = "3"
+	header := quiz.ArchiveRecord{Kind: quiz.RecordArchive, Archive: &quiz.ArchiveHeader{Version: quiz.ArchiveVersion}}
+	for range 2 {
+		if _, err := importRecords(db, quiz.ImportOverwrite, false, []quiz.ArchiveRecord{header, {Kind: quiz.RecordQuestion, Question: &fixed}}); err != nil {
+			t.Fatalf("failed to import: %v", err)
+		}
+	}
+	versions, err := db.GetQuestionVersions(ctx, q.ID)
+	if err != nil || len(versions) != 2 || versions[0].Question.Answer != "4" || versions[1].Question.Answer != "3" {
+		t.Fatalf("expected the versions before and after the fix, got %+v, %v", versions, err)
+	}
+
+	want := quiz.RegradeReport{QuestionID: q.ID, Version: 2, DryRun: true, Answers: 1, Users: []quiz.RegradeUser{
+		{User: "user", CorrectBefore: 0, CorrectAfter: 1, Total: 1, Sessions: []uint64{session.ID}},
+	}}
+	report, err := db.Regrade(ctx, q.ID, true)
+	if err != nil || !reflect.DeepEqual(report, want) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v, %v", want, report, err)
+	}
+	if results, _ := db.GetResults(ctx, "user"); results.Correct != 0 {
+		t.Fatalf("expected the dry run to change nothing, got %+v", results)
+	}
+
+	want.DryRun = false
+	if report, err = db.Regrade(ctx, q.ID, false); err != nil || !reflect.DeepEqual(report, want) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v, %v", want, report, err)
+	}
+	results, _ := db.GetResults(ctx, "user")
+	if results.Correct != 1 || results.Total != 1 || !reflect.DeepEqual(results.Categories, []quiz.CategoryResults{{Category: "arithmetic", Correct: 1, Total: 1}}) {
+		t.Fatalf("expected the answer scored right, got %+v", results)
+	}
+	if s, _ := db.GetSession(ctx, "user", session.ID); s.Correct() != 1 || s.Answers[0].QuestionVersion != 2 {
+		t.Fatalf("expected the attempt scored against version 2, got %+v", s.Answers)
+	}
+
+	if report, err = db.Regrade(ctx, q.ID, false); err != nil || report.Answers != 0 || len(report.Users) != 0 {
+		t.Fatalf("expected nothing left to regrade, got %+v, %v", report, err)
+	}
+	if _, err := db.Regrade(ctx, 99, false); err != ErrQuestionNotFound {
+		t.Fatalf("expected ErrQuestionNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,375 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func (db *InMemoryDB) PutQuiz(ctx context.Context, q quiz.Quiz) error {
+	defer db.observe(ctx, "PutQuiz")()
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	for _, questionID := range q.QuestionIDs {
+		if questionID >= uint64(len(db.questions)) {
+			return ErrQuestionNotFound
+		}
+	}
+
+	db.lockQuizzes.Lock()
+	defer db.lockQuizzes.Unlock()
+
+	db.quizzes[q.Slug] = q
+	db.bumpVersion()
+	return nil
+}
+
+func (db *InMemoryDB) GetQuiz(ctx context.Context, slug string) (quiz.Quiz, error) {
+	defer db.observe(ctx, "GetQuiz")()
+
+	db.lockQuizzes.RLock()
+	defer db.lockQuizzes.RUnlock()
+
+	q, ok := db.quizzes[slug]
+	if !ok {
+		return quiz.Quiz{}, ErrQuizNotFound
+	}
+	return q, nil
+}
+
+func (db *InMemoryDB) GetQuizzes(ctx context.Context) ([]quiz.Quiz, error) {
+	defer db.observe(ctx, "GetQuizzes")()
+
+	db.lockQuizzes.RLock()
+	defer db.lockQuizzes.RUnlock()
+
+	quizzes := make([]quiz.Quiz, 0, len(db.quizzes))
+	for _, q := range db.quizzes {
+		quizzes = append(quizzes, q)
+	}
+	slices.SortFunc(quizzes, func(a, b quiz.Quiz) int {
+		return strings.Compare(a.Slug, b.Slug)
+	})
+	return quizzes, nil
+}
+
+func (db *InMemoryDB) DeleteQuiz(ctx context.Context, slug string) error {
+	defer db.observe(ctx, "DeleteQuiz")()
+
+	db.lockQuizzes.Lock()
+	defer db.lockQuizzes.Unlock()
+
+	if _, ok := db.quizzes[slug]; !ok {
+		return ErrQuizNotFound
+	}
+	delete(db.quizzes, slug)
+	db.bumpVersion()
+	return nil
+}
+
+// StartQuiz opens a session of a named quiz, taking the questions and limits from its definition
+func (db *InMemoryDB) StartQuiz(ctx context.Context, user string, slug string) (quiz.Session, error) {
+	defer db.observe(ctx, "StartQuiz")()
+
+	q, err := db.GetQuiz(ctx, slug)
+	if err != nil {
+		return quiz.Session{}, err
+	}
+
+	if !q.IsOpen(db.now()) {
+		return quiz.Session{}, ErrQuizClosed
+	}
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.RLock()
+	userID, err := db.getUserID(user)
+	db.lockUsers.RUnlock()
+	if err != nil {
+		return quiz.Session{}, err
+	}
+
+	db.lockSessions.Lock()
+	defer db.lockSessions.Unlock()
+
+	if q.MaxAttempts > 0 && uint64(len(db.attempts(userID, slug))) >= q.MaxAttempts {
+		return quiz.Session{}, ErrMaxAttemptsReached
+	}
+
+	limits := SessionLimits{
+		TimeLimit:         time.Duration(q.TimeLimitMs) * time.Millisecond,
+		QuestionTimeLimit: time.Duration(q.QuestionTimeLimitMs) * time.Millisecond,
+	}
+	return db.createSession(userID, slug, db.quizQuestions(q), limits), nil
+}
+
+// GetQuizQuestions picks the questions of a named quiz the same way StartQuiz does, without opening a session
+func (db *InMemoryDB) GetQuizQuestions(ctx context.Context, slug string) (quiz.Quiz, []quiz.Question, error) {
+	defer db.observe(ctx, "GetQuizQuestions")()
+
+	q, err := db.GetQuiz(ctx, slug)
+	if err != nil {
+		return quiz.Quiz{}, nil, err
+	}
+
+	if !q.IsOpen(db.now()) {
+		return quiz.Quiz{}, nil, ErrQuizClosed
+	}
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	return q, db.quizQuestions(q), nil
+}
+
+// quizQuestions must be called holding lockQuestions, fixed questions keep their order
+func (db *InMemoryDB) quizQuestions(q quiz.Quiz) []quiz.Question {
+	var questions []quiz.Question
+	if len(q.QuestionIDs) > 0 {
+		for _, questionID := range q.QuestionIDs {
+			questions = append(questions, db.questions[questionID])
+		}
+		return questions
+	}
+
+	size := int(q.Sample)
+	if size == 0 {
+		size = sessionSize
+	}
+	return db.sample(QuestionFilter{Category: q.Category, Tags: q.Tags}, size)
+}
+
+// GetQuizResults returns the results of the user for one named quiz only
+func (db *InMemoryDB) GetQuizResults(ctx context.Context, user string, slug string) (quiz.QuizResults, error) {
+	defer db.observe(ctx, "GetQuizResults")()
+
+	q, err := db.GetQuiz(ctx, slug)
+	if err != nil {
+		return quiz.QuizResults{}, err
+	}
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	db.lockSessions.RLock()
+	defer db.lockSessions.RUnlock()
+
+	userID, err := db.getUserID(user)
+	if err != nil {
+		return quiz.QuizResults{}, err
+	}
+
+	return db.quizResults(userID, q), nil
+}
+
+// GetUsersQuizResults returns the results of many users for one named quiz in one lookup,
+// unknown users are left out
+func (db *InMemoryDB) GetUsersQuizResults(ctx context.Context, users []string, slug string) (map[string]quiz.QuizResults, error) {
+	defer db.observe(ctx, "GetUsersQuizResults")()
+
+	q, err := db.GetQuiz(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	db.lockSessions.RLock()
+	defer db.lockSessions.RUnlock()
+
+	results := make(map[string]quiz.QuizResults, len(users))
+	for _, user := range users {
+		if userID, err := db.getUserID(user); err == nil {
+			results[user] = db.quizResults(userID, q)
+		}
+	}
+	return results, nil
+}
+
+// GetQuizStatistics compares the user with the other users who attempted the named quiz,
+// only with the other members when group is set
+func (db *InMemoryDB) GetQuizStatistics(ctx context.Context, userName string, slug string, group string) (quiz.StatisticsResults, error) {
+	defer db.observe(ctx, "GetQuizStatistics")()
+
+	q, err := db.GetQuiz(ctx, slug)
+	if err != nil {
+		return quiz.StatisticsResults{}, err
+	}
+
+	members, err := db.groupMembers(ctx, group)
+	if err != nil {
+		return quiz.StatisticsResults{}, err
+	}
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	db.lockSessions.RLock()
+	defer db.lockSessions.RUnlock()
+
+	userID, err := db.getUserID(userName)
+	if err != nil {
+		return quiz.StatisticsResults{}, err
+	}
+
+	return db.quizStatistics(userID, q, members)
+}
+
+// GetUsersQuizStatistics compares many users in one lookup like GetQuizStatistics, unknown users
+// and users without anyone to compare with are left out
+func (db *InMemoryDB) GetUsersQuizStatistics(ctx context.Context, users []string, slug string, group string) (map[string]quiz.StatisticsResults, error) {
+	defer db.observe(ctx, "GetUsersQuizStatistics")()
+
+	q, err := db.GetQuiz(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := db.groupMembers(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	db.lockSessions.RLock()
+	defer db.lockSessions.RUnlock()
+
+	statistics := make(map[string]quiz.StatisticsResults, len(users))
+	for _, user := range users {
+		userID, err := db.getUserID(user)
+		if err != nil {
+			continue
+		}
+		if s, err := db.quizStatistics(userID, q, members); err == nil {
+			statistics[user] = s
+		}
+	}
+	return statistics, nil
+}
+
+// quizStatistics must be called holding lockQuestions, lockUsers and lockSessions
+func (db *InMemoryDB) quizStatistics(userID uint64, q quiz.Quiz, members []string) (quiz.StatisticsResults, error) {
+	others := []quiz.QuizResults{}
+	for _, user := range db.users {
+		if user.ID == userID || len(db.attempts(user.ID, q.Slug)) == 0 {
+			continue
+		}
+		if members != nil && !slices.Contains(members, user.Name) {
+			continue
+		}
+		others = append(others, db.quizResults(user.ID, q))
+	}
+
+	if len(others) == 0 {
+		return quiz.StatisticsResults{}, ErrNotEnoughUsersForStatistics
+	}
+
+	return compare(db.quizResults(userID, q), others), nil
+}
+
+// quizResults must be called holding lockQuestions and lockSessions
+func (db *InMemoryDB) quizResults(userID uint64, q quiz.Quiz) quiz.QuizResults {
+	results := quiz.QuizResults{Categories: []quiz.CategoryResults{}, Attempts: []quiz.AttemptResults{}}
+	categories := map[string]quiz.CategoryResults{}
+	for _, s := range db.attempts(userID, q.Slug) {
+		for _, answer := range s.Answers {
+			category := db.questions[answer.QuestionID].Category
+			c := categories[category]
+			c.Category = category
+			if answer.Correct {
+				results.Correct++
+				c.Correct++
+			}
+			results.Total++
+			c.Total++
+			categories[category] = c
+		}
+
+		correct, total := s.Correct(), uint64(len(s.Questions))
+		results.Attempts = append(results.Attempts, quiz.AttemptResults{
+			Session:   s.ID,
+			StartedAt: s.StartedAt,
+			Correct:   correct,
+			Total:     total,
+			Passed:    q.Passed(correct, total),
+		})
+	}
+
+	for _, c := range categories {
+		if c.Category != "" {
+			results.Categories = append(results.Categories, c)
+		}
+	}
+	slices.SortFunc(results.Categories, func(a, b quiz.CategoryResults) int {
+		return strings.Compare(a.Category, b.Category)
+	})
+	return results
+}
+
+// attempts must be called holding lockSessions
+func (db *InMemoryDB) attempts(userID uint64, slug string) []session {
+	attempts := []session{}
+	for _, s := range db.sessions {
+		if s.userID == userID && s.Quiz == slug {
+			attempts = append(attempts, s)
+		}
+	}
+	return attempts
+}
+
+// compare averages the results of others, category by category
+func compare(own quiz.QuizResults, others []quiz.QuizResults) quiz.StatisticsResults {
+	n := float64(len(others))
+	statistics := quiz.StatisticsResults{Correct: own.Correct, Total: own.Total, Categories: []quiz.CategoryStatistics{}}
+
+	categories := map[string]*quiz.CategoryStatistics{}
+	category := func(name string) *quiz.CategoryStatistics {
+		if _, ok := categories[name]; !ok {
+			categories[name] = &quiz.CategoryStatistics{Category: name}
+		}
+		return categories[name]
+	}
+
+	for _, c := range own.Categories {
+		category(c.Category).Correct = c.Correct
+		category(c.Category).Total = c.Total
+	}
+
+	for _, other := range others {
+		statistics.AvgCorrect += float64(other.Correct) / n
+		statistics.AvgTotal += float64(other.Total) / n
+		for _, c := range other.Categories {
+			category(c.Category).AvgCorrect += float64(c.Correct) / n
+			category(c.Category).AvgTotal += float64(c.Total) / n
+		}
+	}
+
+	for _, c := range categories {
+		statistics.Categories = append(statistics.Categories, *c)
+	}
+	slices.SortFunc(statistics.Categories, func(a, b quiz.CategoryStatistics) int {
+		return strings.Compare(a.Category, b.Category)
+	})
+	return statistics
+}
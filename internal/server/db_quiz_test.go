@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestPutAndGetQuiz(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	err = db.PutQuiz(context.Background(), quiz.Quiz{Slug: "capitals", QuestionIDs: []uint64{0, 99}})
+	if err != ErrQuestionNotFound {
+		t.Fatalf("Expected ErrQuestionNotFound, got %v", err)
+	}
+
+	err = db.PutQuiz(context.Background(), quiz.Quiz{Slug: "capitals", QuestionIDs: []uint64{0, 1}})
+	if err != nil {
+		t.Fatalf("Error putting quiz: %v", err)
+	}
+
+	err = db.PutQuiz(context.Background(), quiz.Quiz{Slug: "arithmetic", Category: "arithmetic", Sample: 3})
+	if err != nil {
+		t.Fatalf("Error putting quiz: %v", err)
+	}
+
+	quizzes, err := db.GetQuizzes(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting quizzes: %v", err)
+	}
+
+	if len(quizzes) != 2 || quizzes[0].Slug != "arithmetic" || quizzes[1].Slug != "capitals" {
+		t.Fatalf("Expected quizzes arithmetic and capitals, got %+v", quizzes)
+	}
+
+	err = db.DeleteQuiz(context.Background(), "capitals")
+	if err != nil {
+		t.Fatalf("Error deleting quiz: %v", err)
+	}
+
+	_, err = db.GetQuiz(context.Background(), "capitals")
+	if err != ErrQuizNotFound {
+		t.Fatalf("Expected ErrQuizNotFound, got %v", err)
+	}
+
+	err = db.DeleteQuiz(context.Background(), "capitals")
+	if err != ErrQuizNotFound {
+		t.Fatalf("Expected ErrQuizNotFound, got %v", err)
+	}
+}
+
+func TestStartQuiz(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.now = func() time.Time { return now }
+
+	closesAt := now.Add(time.Hour)
+	err = db.PutQuiz(context.Background(), quiz.Quiz{
+		Slug:        "capitals",
+		QuestionIDs: []uint64{1, 0},
+		TimeLimitMs: time.Minute.Milliseconds(),
+		MaxAttempts: 1,
+		ClosesAt:    &closesAt,
+	})
+	if err != nil {
+		t.Fatalf("Error putting quiz: %v", err)
+	}
+
+	_, err = db.StartQuiz(context.Background(), "user", "unknown")
+	if err != ErrQuizNotFound {
+		t.Fatalf("Expected ErrQuizNotFound, got %v", err)
+	}
+
+	session, err := db.StartQuiz(context.Background(), "user", "capitals")
+	if err != nil {
+		t.Fatalf("Error starting quiz: %v", err)
+	}
+
+	if session.Quiz != "capitals" || session.TimeLimitMs != time.Minute.Milliseconds() {
+		t.Fatalf("Expected session of capitals with one minute, got %+v", session)
+	}
+
+	// fixed questions keep their order
+	if len(session.Questions) != 2 || session.Questions[0].ID != 1 || session.Questions[1].ID != 0 {
+		t.Fatalf("Expected questions 1 and 0, got %+v", session.Questions)
+	}
+
+	_, err = db.StartQuiz(context.Background(), "user", "capitals")
+	if err != ErrMaxAttemptsReached {
+		t.Fatalf("Expected ErrMaxAttemptsReached, got %v", err)
+	}
+
+	_, err = db.InsertUser(context.Background(), "late")
+	if err != nil {
+		t.Fatalf("Error inserting user: %v", err)
+	}
+
+	now = closesAt
+	_, err = db.StartQuiz(context.Background(), "late", "capitals")
+	if err != ErrQuizClosed {
+		t.Fatalf("Expected ErrQuizClosed, got %v", err)
+	}
+}
+
+func TestQuizResultsAndStatistics(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	err = db.PutQuiz(context.Background(), quiz.Quiz{Slug: "capitals", QuestionIDs: []uint64{0, 1}, PassMark: 1})
+	if err != nil {
+		t.Fatalf("Error putting quiz: %v", err)
+	}
+
+	_, err = db.InsertUser(context.Background(), "other")
+	if err != nil {
+		t.Fatalf("Error inserting user: %v", err)
+	}
+
+	_, err = db.GetQuizStatistics(context.Background(), "user", "capitals", "")
+	if err != ErrNotEnoughUsersForStatistics {
+		t.Fatalf("Expected ErrNotEnoughUsersForStatistics, got %v", err)
+	}
+
+	for user, answers := range map[string]map[uint64]string{
+		"user":  {0: "Paris", 1: "Berlin"},
+		"other": {0: "Paris", 1: "Paris"},
+	} {
+		session, err := db.StartQuiz(context.Background(), user, "capitals")
+		if err != nil {
+			t.Fatalf("Error starting quiz: %v", err)
+		}
+
+		answer := quiz.QuizAnswer{}
+		for _, q := range session.Questions {
+			answer[q.ID] = optionID(t, q, answers[q.ID])
+		}
+
+		_, err = db.InsertSessionAnswer(context.Background(), user, session.ID, answer)
+		if err != nil {
+			t.Fatalf("Error inserting session answer: %v", err)
+		}
+	}
+
+	// answers outside the quiz do not count
+	err = db.InsertQuizAnswer(context.Background(), "user", quiz.QuizAnswer{2: "4"})
+	if err != nil {
+		t.Fatalf("Error inserting quiz answer: %v", err)
+	}
+
+	results, err := db.GetQuizResults(context.Background(), "user", "capitals")
+	if err != nil {
+		t.Fatalf("Error getting quiz results: %v", err)
+	}
+
+	if results.Correct != 2 || results.Total != 2 {
+		t.Fatalf("Expected 2 correct out of 2, got %d out of %d", results.Correct, results.Total)
+	}
+
+	if len(results.Attempts) != 1 || !results.Attempts[0].Passed {
+		t.Fatalf("Expected one passed attempt, got %+v", results.Attempts)
+	}
+
+	results, err = db.GetQuizResults(context.Background(), "other", "capitals")
+	if err != nil {
+		t.Fatalf("Error getting quiz results: %v", err)
+	}
+
+	if len(results.Attempts) != 1 || results.Attempts[0].Passed {
+		t.Fatalf("Expected one failed attempt, got %+v", results.Attempts)
+	}
+
+	statistics, err := db.GetQuizStatistics(context.Background(), "user", "capitals", "")
+	if err != nil {
+		t.Fatalf("Error getting quiz statistics: %v", err)
+	}
+
+	want := quiz.StatisticsResults{Correct: 2, Total: 2, AvgCorrect: 1, AvgTotal: 2}
+	if statistics.Correct != want.Correct || statistics.Total != want.Total || statistics.AvgCorrect != want.AvgCorrect || statistics.AvgTotal != want.AvgTotal {
+		t.Fatalf("Expected statistics %+v, got %+v", want, statistics)
+	}
+
+	if len(statistics.Categories) != 1 || statistics.Categories[0].Category != "geography" {
+		t.Fatalf("Expected geography statistics only, got %+v", statistics.Categories)
+	}
+
+	usersResults, err := db.GetUsersQuizResults(context.Background(), []string{"user", "other", "unknown"}, "capitals")
+	if err != nil || len(usersResults) != 2 || usersResults["other"].Correct != 1 {
+		t.Fatalf("Expected the quiz results of the known users, got %+v, %v", usersResults, err)
+	}
+
+	usersStatistics, err := db.GetUsersQuizStatistics(context.Background(), []string{"user", "other"}, "capitals", "")
+	if err != nil || len(usersStatistics) != 2 || usersStatistics["user"].AvgCorrect != 1 {
+		t.Fatalf("Expected the quiz statistics of both users, got %+v, %v", usersStatistics, err)
+	}
+
+	if _, err := db.GetUsersQuizResults(context.Background(), []string{"user"}, "unknown"); err != ErrQuizNotFound {
+		t.Fatalf("Expected ErrQuizNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	sessionSize = 2
+	// network slack given to answers arriving right at the deadline
+	deadlineGrace = time.Second
+	unanswered    = -1
+)
+
+type session struct {
+	quiz.Session
+	userID uint64
+	// canonical index of every option ID per question ID
+	options map[uint64]map[string]int
+}
+
+// SessionLimits are optional, zero means no limit
+type SessionLimits struct {
+	TimeLimit         time.Duration
+	QuestionTimeLimit time.Duration
+}
+
+func (db *InMemoryDB) CreateSession(ctx context.Context, user string, filter QuestionFilter, limits SessionLimits) (quiz.Session, error) {
+	defer db.observe(ctx, "CreateSession")()
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.RLock()
+	userID, err := db.getUserID(user)
+	db.lockUsers.RUnlock()
+	if err != nil {
+		return quiz.Session{}, err
+	}
+
+	db.lockSessions.Lock()
+	defer db.lockSessions.Unlock()
+
+	return db.createSession(userID, "", db.sample(filter, sessionSize), limits), nil
+}
+
+// sample must be called holding lockQuestions
+func (db *InMemoryDB) sample(filter QuestionFilter, size int) []quiz.Question {
+	questions := []quiz.Question{}
+	for _, q := range db.questions {
+		if filter.Match(q) {
+			questions = append(questions, q)
+		}
+	}
+	rand.Shuffle(len(questions), func(i, j int) {
+		questions[i], questions[j] = questions[j], questions[i]
+	})
+	return questions[:min(size, len(questions))]
+}
+
+// createSession must be called holding lockSessions
+func (db *InMemoryDB) createSession(userID uint64, slug string, questions []quiz.Question, limits SessionLimits) quiz.Session {
+	s := newSession(uint64(len(db.sessions)), userID, slug, questions, limits, db.now())
+	db.sessions = append(db.sessions, s)
+	db.bumpVersion()
+	return s.Session
+}
+
+// newSession shuffles the options of every question, nothing is answered yet
+func newSession(id uint64, userID uint64, slug string, questions []quiz.Question, limits SessionLimits, startedAt time.Time) session {
+	questions = slices.Clone(questions)
+	options := make(map[uint64]map[string]int, len(questions))
+	for i, q := range questions {
+		questions[i], options[q.ID] = shuffleOptions(q)
+	}
+
+	return session{
+		Session: quiz.Session{
+			ID:                  id,
+			Quiz:                slug,
+			Questions:           questions,
+			StartedAt:           startedAt,
+			TimeLimitMs:         limits.TimeLimit.Milliseconds(),
+			QuestionTimeLimitMs: limits.QuestionTimeLimit.Milliseconds(),
+			Answers:             []quiz.AnswerRecord{},
+		},
+		userID:  userID,
+		options: options,
+	}
+}
+
+func (db *InMemoryDB) GetSession(ctx context.Context, user string, sessionID uint64) (quiz.Session, error) {
+	defer db.observe(ctx, "GetSession")()
+
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	db.lockSessions.RLock()
+	defer db.lockSessions.RUnlock()
+
+	s, err := db.getSession(user, sessionID)
+	if err != nil {
+		return quiz.Session{}, err
+	}
+	return s.Session, nil
+}
+
+// InsertSessionAnswer scores the answers with the server clock. Answers arriving after the
+// session deadline, or taking longer than the question time limit, are recorded but score zero.
+// Under a question time limit every answer is timed from the previous one, so only one
+// question can be answered per submission; unanswered questions can still be sent together.
+// Without a limit the time since the last activity is split between the answers.
+func (db *InMemoryDB) InsertSessionAnswer(ctx context.Context, user string, sessionID uint64, answer quiz.QuizAnswer) ([]quiz.AnswerRecord, error) {
+	defer db.observe(ctx, "InsertSessionAnswer")()
+
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+
+	db.lockUsers.Lock()
+	defer db.lockUsers.Unlock()
+
+	db.lockSessions.Lock()
+	defer db.lockSessions.Unlock()
+
+	s, err := db.getSession(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	chosen := make(map[uint64]int, len(answer))
+	answered := 0
+	for questionID, userAnswer := range answer {
+		if !s.HasQuestion(questionID) {
+			return nil, ErrQuestionNotInSession
+		}
+		if s.IsAnswered(questionID) {
+			return nil, ErrQuestionAlreadyAnswered
+		}
+		if chosen[questionID], err = s.option(questionID, userAnswer); err != nil {
+			return nil, err
+		}
+		if chosen[questionID] != unanswered {
+			answered++
+		}
+	}
+	if s.QuestionTimeLimit() > 0 && answered > 1 {
+		return nil, ErrBatchedAnswers
+	}
+
+	now := db.now()
+	timeTaken := now.Sub(s.LastActivity()) / time.Duration(max(answered, 1))
+	late := false
+	if deadline, ok := s.Deadline(); ok && now.After(deadline.Add(deadlineGrace)) {
+		late = true
+	}
+	if limit := s.QuestionTimeLimit(); limit > 0 && timeTaken > limit+deadlineGrace {
+		late = true
+	}
+
+	records := make([]quiz.AnswerRecord, 0, len(answer))
+	for _, q := range s.Questions {
+		option, ok := chosen[q.ID]
+		if !ok {
+			continue
+		}
+		question := db.questions[q.ID]
+		correct := !late && question.IsCorrect(option)
+		db.score(s.userID, question, correct, now)
+		records = append(records, quiz.AnswerRecord{QuestionID: q.ID, SubmittedAt: now, TimeTakenMs: timeTaken.Milliseconds(), Late: late, Correct: correct, QuestionVersion: question.Version, Option: option})
+	}
+
+	db.sessions[sessionID].Answers = append(db.sessions[sessionID].Answers, records...)
+	if completed := db.sessions[sessionID].Session; len(completed.Answers) == len(completed.Questions) {
+		db.publishAttempt(user, completed)
+	}
+	return records, nil
+}
+
+// publishAttempt of a completed session, named quizzes that are passed publish quiz.passed too
+func (db *InMemoryDB) publishAttempt(user string, s quiz.Session) {
+	correct, total := s.Correct(), uint64(len(s.Questions))
+	attempt := quiz.AttemptResults{Session: s.ID, StartedAt: s.StartedAt, Correct: correct, Total: total}
+	if s.Quiz != "" {
+		db.lockQuizzes.RLock()
+		q, ok := db.quizzes[s.Quiz]
+		db.lockQuizzes.RUnlock()
+		attempt.Passed = ok && q.Passed(correct, total)
+	}
+
+	data := quiz.WebhookEventData{User: user, Quiz: s.Quiz, Attempt: &attempt}
+	db.publish(quiz.EventAttemptSubmitted, data)
+	if attempt.Passed {
+		db.publish(quiz.EventQuizPassed, data)
+	}
+}
+
+// shuffleOptions gives every option a random ID and a random position, so neither
+// the position nor the ID of the answer can be memorized between sessions
+func shuffleOptions(q quiz.Question) (quiz.Question, map[string]int) {
+	ids := make(map[string]int, len(q.Options))
+	order := rand.Perm(len(q.Options))
+
+	shuffled := q
+	shuffled.Answer = unanswered
+	shuffled.Options = make([]string, len(q.Options))
+	shuffled.OptionIDs = make([]string, len(q.Options))
+	for i, canonical := range order {
+		var id string
+		for {
+			id = newOptionID()
+			if _, taken := ids[id]; !taken {
+				break
+			}
+		}
+		ids[id] = canonical
+		shuffled.Options[i] = q.Options[canonical]
+		shuffled.OptionIDs[i] = id
+	}
+	return shuffled, ids
+}
+
+func newOptionID() string {
+	return fmt.Sprintf("opt_%08x", rand.Uint32())
+}
+
+// option resolves an answer given as an option ID, or as the index of the option in the
+// order shown in this session, into the canonical index. An empty answer is a timeout.
+func (s session) option(questionID uint64, answer string) (int, error) {
+	if answer == "" {
+		return unanswered, nil
+	}
+
+	if canonical, ok := s.options[questionID][answer]; ok {
+		return canonical, nil
+	}
+
+	for _, q := range s.Questions {
+		if q.ID != questionID {
+			continue
+		}
+		index, err := strconv.Atoi(answer)
+		if err != nil || index < 0 || index >= len(q.OptionIDs) {
+			return 0, ErrInvalidOption
+		}
+		return s.options[questionID][q.OptionIDs[index]], nil
+	}
+	return 0, ErrQuestionNotInSession
+}
+
+// getSession must be called holding lockUsers and lockSessions
+func (db *InMemoryDB) getSession(user string, sessionID uint64) (session, error) {
+	userID, err := db.getUserID(user)
+	if err != nil {
+		return session{}, err
+	}
+
+	// do not leak sessions of other users
+	if sessionID >= uint64(len(db.sessions)) || db.sessions[sessionID].userID != userID {
+		return session{}, ErrSessionNotFound
+	}
+	return db.sessions[sessionID], nil
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestCreateSession(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	session, err := db.CreateSession(context.Background(), "user", QuestionFilter{}, SessionLimits{TimeLimit: time.Minute})
+	if err != nil {
+		t.Fatalf("Error creating session: %v", err)
+	}
+
+	if len(session.Questions) != sessionSize {
+		t.Fatalf("Expected %d questions, got %d", sessionSize, len(session.Questions))
+	}
+
+	if session.Questions[0].ID == session.Questions[1].ID {
+		t.Fatalf("Expected different questions, got %d twice", session.Questions[0].ID)
+	}
+
+	if session.TimeLimitMs != time.Minute.Milliseconds() {
+		t.Fatalf("Expected time limit %d, got %d", time.Minute.Milliseconds(), session.TimeLimitMs)
+	}
+
+	_, err = db.CreateSession(context.Background(), "unknown", QuestionFilter{}, SessionLimits{})
+	if err != ErrUserNotFound {
+		t.Fatalf("Expected ErrUserNotFound, got %v", err)
+	}
+
+	// other users cannot see the session
+	_, err = db.InsertUser(context.Background(), "other")
+	if err != nil {
+		t.Fatalf("Error inserting user: %v", err)
+	}
+
+	_, err = db.GetSession(context.Background(), "other", session.ID)
+	if err != ErrSessionNotFound {
+		t.Fatalf("Expected ErrSessionNotFound, got %v", err)
+	}
+
+	_, err = db.GetSession(context.Background(), "user", 99)
+	if err != ErrSessionNotFound {
+		t.Fatalf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestInsertSessionAnswer(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.now = func() time.Time { return now }
+
+	limits := SessionLimits{TimeLimit: time.Minute, QuestionTimeLimit: 10 * time.Second}
+	session, err := db.CreateSession(context.Background(), "user", QuestionFilter{Category: "geography"}, limits)
+	if err != nil {
+		t.Fatalf("Error creating session: %v", err)
+	}
+
+	answers := map[uint64]string{0: "Paris", 1: "Berlin"}
+	first, second := session.Questions[0], session.Questions[1]
+
+	// answers can't share the time since the last one under a question time limit
+	_, err = db.InsertSessionAnswer(context.Background(), "user", session.ID, quiz.QuizAnswer{first.ID: optionID(t, first, answers[first.ID]), second.ID: optionID(t, second, answers[second.ID])})
+	if err != ErrBatchedAnswers {
+		t.Fatalf("Expected ErrBatchedAnswers, got %v", err)
+	}
+
+	// in time
+	now = now.Add(5 * time.Second)
+	records, err := db.InsertSessionAnswer(context.Background(), "user", session.ID, quiz.QuizAnswer{first.ID: optionID(t, first, answers[first.ID])})
+	if err != nil {
+		t.Fatalf("Error inserting session answer: %v", err)
+	}
+
+	if len(records) != 1 || records[0].Late || records[0].TimeTakenMs != 5000 {
+		t.Fatalf("Expected one answer on time taking 5000ms, got %+v", records)
+	}
+
+	_, err = db.InsertSessionAnswer(context.Background(), "user", session.ID, quiz.QuizAnswer{first.ID: optionID(t, first, answers[first.ID])})
+	if err != ErrQuestionAlreadyAnswered {
+		t.Fatalf("Expected ErrQuestionAlreadyAnswered, got %v", err)
+	}
+
+	_, err = db.InsertSessionAnswer(context.Background(), "user", session.ID, quiz.QuizAnswer{4: "0"})
+	if err != ErrQuestionNotInSession {
+		t.Fatalf("Expected ErrQuestionNotInSession, got %v", err)
+	}
+
+	// over the question time limit, the right answer scores zero
+	now = now.Add(20 * time.Second)
+	records, err = db.InsertSessionAnswer(context.Background(), "user", session.ID, quiz.QuizAnswer{second.ID: optionID(t, second, answers[second.ID])})
+	if err != nil {
+		t.Fatalf("Error inserting session answer: %v", err)
+	}
+
+	if len(records) != 1 || !records[0].Late || records[0].TimeTakenMs != 20000 {
+		t.Fatalf("Expected one late answer taking 20000ms, got %+v", records)
+	}
+
+	results, err := db.GetResults(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("Error getting quiz results: %v", err)
+	}
+
+	if results.Correct != 1 || results.Total != 2 {
+		t.Fatalf("Expected 1 correct out of 2, got %d out of %d", results.Correct, results.Total)
+	}
+
+	got, err := db.GetSession(context.Background(), "user", session.ID)
+	if err != nil {
+		t.Fatalf("Error getting session: %v", err)
+	}
+
+	if len(got.Answers) != 2 {
+		t.Fatalf("Expected 2 recorded answers, got %d", len(got.Answers))
+	}
+}
+
+func TestInsertSessionAnswerAfterDeadline(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.now = func() time.Time { return now }
+
+	session, err := db.CreateSession(context.Background(), "user", QuestionFilter{Category: "geography"}, SessionLimits{TimeLimit: time.Minute})
+	if err != nil {
+		t.Fatalf("Error creating session: %v", err)
+	}
+
+	answers := quiz.QuizAnswer{}
+	for _, q := range session.Questions {
+		answers[q.ID] = optionID(t, q, db.questions[q.ID].OptionAt(db.questions[q.ID].Answer))
+	}
+
+	now = now.Add(2 * time.Minute)
+	records, err := db.InsertSessionAnswer(context.Background(), "user", session.ID, answers)
+	if err != nil {
+		t.Fatalf("Error inserting session answer: %v", err)
+	}
+
+	// time is split between the answers sent together
+	for _, record := range records {
+		if !record.Late || record.TimeTakenMs != time.Minute.Milliseconds() {
+			t.Fatalf("Expected late answer taking one minute, got %+v", record)
+		}
+	}
+
+	results, err := db.GetResults(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("Error getting quiz results: %v", err)
+	}
+
+	if results.Correct != 0 || results.Total != 2 {
+		t.Fatalf("Expected 0 correct out of 2, got %d out of %d", results.Correct, results.Total)
+	}
+}
+
+func TestSessionShuffledOptions(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	session, err := db.CreateSession(context.Background(), "user", QuestionFilter{Category: "arithmetic", Tags: []string{"addition"}}, SessionLimits{})
+	if err != nil {
+		t.Fatalf("Error creating session: %v", err)
+	}
+
+	q := session.Questions[0]
+	if len(q.OptionIDs) != len(q.Options) {
+		t.Fatalf("Expected %d option IDs, got %d", len(q.Options), len(q.OptionIDs))
+	}
+
+	canonical := db.questions[q.ID].Options
+	for _, option := range canonical {
+		if !slices.Contains(q.Options, option) {
+			t.Fatalf("Expected option %s in %v", option, q.Options)
+		}
+	}
+
+	// the answer is never exposed
+	if q.Answer != unanswered {
+		t.Fatalf("Expected no answer, got %d", q.Answer)
+	}
+
+	_, err = db.InsertSessionAnswer(context.Background(), "user", session.ID, quiz.QuizAnswer{q.ID: "opt_unknown"})
+	if err != ErrInvalidOption {
+		t.Fatalf("Expected ErrInvalidOption, got %v", err)
+	}
+
+	_, err = db.InsertSessionAnswer(context.Background(), "user", session.ID, quiz.QuizAnswer{q.ID: "4"})
+	if err != ErrInvalidOption {
+		t.Fatalf("Expected ErrInvalidOption, got %v", err)
+	}
+
+	// answer by the index shown in this session
+	index := slices.Index(q.Options, "4")
+	_, err = db.InsertSessionAnswer(context.Background(), "user", session.ID, quiz.QuizAnswer{q.ID: strconv.Itoa(index)})
+	if err != nil {
+		t.Fatalf("Error inserting session answer: %v", err)
+	}
+
+	results, err := db.GetResults(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("Error getting quiz results: %v", err)
+	}
+
+	if results.Correct != 1 || results.Total != 1 {
+		t.Fatalf("Expected 1 correct out of 1, got %d out of %d", results.Correct, results.Total)
+	}
+}
+
+func TestShuffleOptionsSameText(t *testing.T) {
+	t.Parallel()
+	// same display text in two languages, only the option ID tells them apart
+	q := quiz.Question{ID: 0, Options: []string{"Gift", "Gift", "Present"}, Answer: 2}
+
+	shuffled, ids := shuffleOptions(q)
+	for i, id := range shuffled.OptionIDs {
+		canonical := ids[id]
+		if shuffled.Options[i] != q.Options[canonical] {
+			t.Fatalf("Expected option %s for ID %s, got %s", q.Options[canonical], id, shuffled.Options[i])
+		}
+	}
+
+	if len(ids) != len(q.Options) {
+		t.Fatalf("Expected %d unique option IDs, got %d", len(q.Options), len(ids))
+	}
+}
+
+// optionID finds the ID the session gave to the option with this text
+func optionID(t *testing.T, q quiz.Question, text string) string {
+	t.Helper()
+	i := slices.Index(q.Options, text)
+	if i < 0 {
+		t.Fatalf("Option %s not found in %v", text, q.Options)
+	}
+	return q.OptionIDs[i]
+}
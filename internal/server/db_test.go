@@ -2,12 +2,15 @@ package server
 
 import (
 	"context"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/vrnvu/temp/pkg/quiz"
 )
 
 func TestInMemoryDB(t *testing.T) {
+	t.Parallel()
 	_, err := NewInMemoryDB()
 	if err != nil {
 		t.Fatalf("Error creating in-memory database: %v", err)
@@ -15,6 +18,7 @@ func TestInMemoryDB(t *testing.T) {
 }
 
 func TestGetUserID(t *testing.T) {
+	t.Parallel()
 	db, err := NewInMemoryDB()
 	if err != nil {
 		t.Fatalf("Error creating in-memory database: %v", err)
@@ -48,12 +52,13 @@ func TestGetUserID(t *testing.T) {
 }
 
 func TestGetQuestions(t *testing.T) {
+	t.Parallel()
 	db, err := NewInMemoryDB()
 	if err != nil {
 		t.Fatalf("Error creating in-memory database: %v", err)
 	}
 
-	questions, err := db.GetQuestions(context.Background())
+	questions, err := db.GetQuestions(context.Background(), QuestionFilter{})
 	if err != nil {
 		t.Fatalf("Error getting questions: %v", err)
 	}
@@ -64,6 +69,7 @@ func TestGetQuestions(t *testing.T) {
 }
 
 func TestInsertQuizAnswerAndGetResults(t *testing.T) {
+	t.Parallel()
 	db, err := NewInMemoryDB()
 	if err != nil {
 		t.Fatalf("Error creating in-memory database: %v", err)
@@ -104,13 +110,47 @@ func TestInsertQuizAnswerAndGetResults(t *testing.T) {
 	}
 }
 
+func TestInsertQuizAnswerSameText(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	// both options are a gift in Spanish, only the right one scores
+	id := uint64(len(db.questions))
+	db.putQuestion(quiz.Question{ID: id, Text: "Which one is a gift?", Options: []string{"Gift", "Present"}, Answer: 1,
+		Translations: map[string]quiz.Translation{"es": {Options: map[string]string{"Gift": "Regalo", "Present": "Regalo"}}}})
+
+	questions, err := db.GetQuestions(context.Background(), QuestionFilter{})
+	if err != nil || !slices.Equal(questions[0].OptionIDs, questions[0].WithOptionIDs().OptionIDs) {
+		t.Fatalf("Expected the canonical option IDs, got %+v, %v", questions, err)
+	}
+
+	for _, answer := range []string{"Regalo", "opt_0", "opt_1"} {
+		if err := db.InsertQuizAnswer(context.Background(), "user", quiz.QuizAnswer{id: answer}); err != nil {
+			t.Fatalf("Error inserting quiz answer: %v", err)
+		}
+	}
+
+	results, err := db.GetResults(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("Error getting quiz results: %v", err)
+	}
+
+	if results.Correct != 1 || results.Total != 3 {
+		t.Fatalf("Expected 1 correct out of 3, got %d out of %d", results.Correct, results.Total)
+	}
+}
+
 func TestInsertUser(t *testing.T) {
+	t.Parallel()
 	db, err := NewInMemoryDB()
 	if err != nil {
 		t.Fatalf("Error creating in-memory database: %v", err)
 	}
 
-	err = db.InsertUser(context.Background(), "newUser")
+	_, err = db.InsertUser(context.Background(), "newUser")
 	if err != nil {
 		t.Fatalf("Error inserting user: %v", err)
 	}
@@ -129,19 +169,20 @@ func TestInsertUser(t *testing.T) {
 	}
 
 	// insert user already exists is err
-	err = db.InsertUser(context.Background(), "user")
+	_, err = db.InsertUser(context.Background(), "user")
 	if err != ErrUserAlreadyExists {
 		t.Fatalf("Expected ErrUserAlreadyExists, got %v", err)
 	}
 }
 
 func TestGetStatistics(t *testing.T) {
+	t.Parallel()
 	db, err := NewInMemoryDB()
 	if err != nil {
 		t.Fatalf("Error creating in-memory database: %v", err)
 	}
 
-	statistics, err := db.GetStatistics(context.Background(), "user")
+	statistics, err := db.GetStatistics(context.Background(), "user", "")
 	if err != ErrNotEnoughUsersForStatistics {
 		t.Fatalf("Expected error, not enough users, got %v", err)
 	}
@@ -152,7 +193,7 @@ func TestGetStatistics(t *testing.T) {
 	db.InsertUser(context.Background(), "c")
 	db.InsertUser(context.Background(), "d")
 
-	statistics, err = db.GetStatistics(context.Background(), "user")
+	statistics, err = db.GetStatistics(context.Background(), "user", "")
 	if err != nil {
 		t.Fatalf("Error getting statistics: %v", err)
 	}
@@ -173,3 +214,160 @@ func TestGetStatistics(t *testing.T) {
 		t.Fatalf("Expected 0 avg total answer, got %f", statistics.AvgTotal)
 	}
 }
+
+func TestGetDueQuestions(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.now = func() time.Time { return now }
+
+	// never answered, everything is due
+	questions, err := db.GetDueQuestions(context.Background(), "user", QuestionFilter{})
+	if err != nil {
+		t.Fatalf("Error getting due questions: %v", err)
+	}
+
+	if len(questions) != len(db.questions) {
+		t.Fatalf("Expected %d due questions, got %d", len(db.questions), len(questions))
+	}
+
+	err = db.InsertQuizAnswer(context.Background(), "user", quiz.QuizAnswer{0: "Paris", 1: "wrong answer"})
+	if err != nil {
+		t.Fatalf("Error inserting quiz answer: %v", err)
+	}
+
+	questions, err = db.GetDueQuestions(context.Background(), "user", QuestionFilter{})
+	if err != nil {
+		t.Fatalf("Error getting due questions: %v", err)
+	}
+
+	if len(questions) != len(db.questions)-2 {
+		t.Fatalf("Expected %d due questions, got %d", len(db.questions)-2, len(questions))
+	}
+
+	// a day later the wrong one comes back, the right one too as it was its first repetition
+	now = now.Add(24 * time.Hour)
+	questions, err = db.GetDueQuestions(context.Background(), "user", QuestionFilter{})
+	if err != nil {
+		t.Fatalf("Error getting due questions: %v", err)
+	}
+
+	if len(questions) != len(db.questions) {
+		t.Fatalf("Expected %d due questions, got %d", len(db.questions), len(questions))
+	}
+
+	// answer right again, the question fades out for 6 days
+	err = db.InsertQuizAnswer(context.Background(), "user", quiz.QuizAnswer{0: "Paris"})
+	if err != nil {
+		t.Fatalf("Error inserting quiz answer: %v", err)
+	}
+
+	now = now.Add(24 * time.Hour)
+	questions, err = db.GetDueQuestions(context.Background(), "user", QuestionFilter{})
+	if err != nil {
+		t.Fatalf("Error getting due questions: %v", err)
+	}
+
+	for _, q := range questions {
+		if q.ID == 0 {
+			t.Fatalf("Expected question 0 not to be due")
+		}
+	}
+
+	_, err = db.GetDueQuestions(context.Background(), "unknown", QuestionFilter{})
+	if err != ErrUserNotFound {
+		t.Fatalf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestCategoryResultsAndStatistics(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	_, err = db.InsertUser(context.Background(), "other")
+	if err != nil {
+		t.Fatalf("Error inserting user: %v", err)
+	}
+
+	// strong in geography, weak in arithmetic
+	err = db.InsertQuizAnswer(context.Background(), "user", quiz.QuizAnswer{0: "Paris", 1: "Berlin", 2: "1"})
+	if err != nil {
+		t.Fatalf("Error inserting quiz answer: %v", err)
+	}
+
+	err = db.InsertQuizAnswer(context.Background(), "other", quiz.QuizAnswer{2: "4", 3: "4"})
+	if err != nil {
+		t.Fatalf("Error inserting quiz answer: %v", err)
+	}
+
+	results, err := db.GetResults(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("Error getting quiz results: %v", err)
+	}
+
+	wantResults := []quiz.CategoryResults{
+		{Category: "arithmetic", Correct: 0, Total: 1},
+		{Category: "geography", Correct: 2, Total: 2},
+	}
+	if !slices.Equal(results.Categories, wantResults) {
+		t.Fatalf("Expected categories %+v, got %+v", wantResults, results.Categories)
+	}
+
+	statistics, err := db.GetStatistics(context.Background(), "user", "")
+	if err != nil {
+		t.Fatalf("Error getting statistics: %v", err)
+	}
+
+	wantStatistics := []quiz.CategoryStatistics{
+		{Category: "arithmetic", Correct: 0, Total: 1, AvgCorrect: 2, AvgTotal: 2},
+		{Category: "geography", Correct: 2, Total: 2, AvgCorrect: 0, AvgTotal: 0},
+	}
+	if !slices.Equal(statistics.Categories, wantStatistics) {
+		t.Fatalf("Expected categories %+v, got %+v", wantStatistics, statistics.Categories)
+	}
+}
+
+func TestGetUsersResultsAndStatistics(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+
+	statistics, err := db.GetUsersStatistics(context.Background(), []string{"user"}, "")
+	if err != nil || len(statistics) != 0 {
+		t.Fatalf("Expected no statistics of a single user, got %+v, %v", statistics, err)
+	}
+
+	db.InsertUser(context.Background(), "other")
+	db.InsertQuizAnswer(context.Background(), "other", quiz.QuizAnswer{0: "Paris", 2: "1"})
+
+	results, err := db.GetUsersResults(context.Background(), []string{"user", "other", "unknown"})
+	if err != nil {
+		t.Fatalf("Error getting results: %v", err)
+	}
+
+	if len(results) != 2 || results["other"].Correct != 1 || results["other"].Total != 2 || len(results["other"].Categories) != 2 {
+		t.Fatalf("Expected the results of the known users, got %+v", results)
+	}
+
+	statistics, err = db.GetUsersStatistics(context.Background(), []string{"user", "other", "unknown"}, "")
+	if err != nil {
+		t.Fatalf("Error getting statistics: %v", err)
+	}
+
+	if len(statistics) != 2 || statistics["user"].AvgCorrect != 1 || statistics["other"].Correct != 1 {
+		t.Fatalf("Expected the statistics of the known users, got %+v", statistics)
+	}
+
+	if _, err := db.GetUsersStatistics(context.Background(), []string{"user"}, "unknown"); err != ErrGroupNotFound {
+		t.Fatalf("Expected ErrGroupNotFound, got %v", err)
+	}
+}
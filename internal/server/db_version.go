@@ -0,0 +1,43 @@
+package server
+
+import "context"
+
+// Version changes with every write, reads that depend on everything, like the statistics, are
+// tagged with it
+func (db *InMemoryDB) Version(ctx context.Context) uint64 {
+	defer db.observe(ctx, "Version")()
+
+	db.lockVersions.Lock()
+	defer db.lockVersions.Unlock()
+
+	return db.version
+}
+
+// UserVersion changes with every write to the results of the user
+func (db *InMemoryDB) UserVersion(ctx context.Context, user string) (uint64, error) {
+	defer db.observe(ctx, "UserVersion")()
+
+	db.lockUsers.RLock()
+	userID, err := db.getUserID(user)
+	db.lockUsers.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+
+	db.lockVersions.Lock()
+	defer db.lockVersions.Unlock()
+
+	return db.userVersions[userID], nil
+}
+
+// bumpVersion after a write, to the results of the user IDs if any. It only takes lockVersions,
+// so it can be called holding the other locks.
+func (db *InMemoryDB) bumpVersion(userIDs ...uint64) {
+	db.lockVersions.Lock()
+	defer db.lockVersions.Unlock()
+
+	db.version++
+	for _, userID := range userIDs {
+		db.userVersions[userID]++
+	}
+}
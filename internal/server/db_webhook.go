@@ -0,0 +1,219 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	// webhookMaxAttempts before a delivery goes to the dead letters
+	webhookMaxAttempts = 5
+	// webhookDeadLetters kept, the oldest ones are dropped first
+	webhookDeadLetters = 1000
+)
+
+type delivery struct {
+	quiz.WebhookDelivery
+	nextAttempt time.Time
+}
+
+// PutWebhook creates or replaces the subscription
+func (db *InMemoryDB) PutWebhook(ctx context.Context, w quiz.Webhook) error {
+	defer db.observe(ctx, "PutWebhook")()
+
+	db.lockWebhooks.Lock()
+	defer db.lockWebhooks.Unlock()
+
+	db.webhooks[w.Slug] = w
+	return nil
+}
+
+// GetWebhooks answers the subscriptions without their secrets
+func (db *InMemoryDB) GetWebhooks(ctx context.Context) ([]quiz.Webhook, error) {
+	defer db.observe(ctx, "GetWebhooks")()
+
+	db.lockWebhooks.Lock()
+	defer db.lockWebhooks.Unlock()
+
+	webhooks := make([]quiz.Webhook, 0, len(db.webhooks))
+	for _, w := range db.webhooks {
+		w.Secret = ""
+		webhooks = append(webhooks, w)
+	}
+	slices.SortFunc(webhooks, func(a, b quiz.Webhook) int {
+		return strings.Compare(a.Slug, b.Slug)
+	})
+	return webhooks, nil
+}
+
+// DeleteWebhook also drops its pending deliveries, its dead letters stay until redelivered
+func (db *InMemoryDB) DeleteWebhook(ctx context.Context, slug string) error {
+	defer db.observe(ctx, "DeleteWebhook")()
+
+	db.lockWebhooks.Lock()
+	defer db.lockWebhooks.Unlock()
+
+	if _, ok := db.webhooks[slug]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(db.webhooks, slug)
+
+	for id, d := range db.deliveries {
+		if d.Webhook == slug && d.Status == quiz.DeliveryPending {
+			delete(db.deliveries, id)
+		}
+	}
+	return nil
+}
+
+// GetDeadLetters answers the deliveries that failed every attempt, oldest first
+func (db *InMemoryDB) GetDeadLetters(ctx context.Context) ([]quiz.WebhookDelivery, error) {
+	defer db.observe(ctx, "GetDeadLetters")()
+
+	db.lockWebhooks.Lock()
+	defer db.lockWebhooks.Unlock()
+
+	dead := []quiz.WebhookDelivery{}
+	for _, d := range db.deliveries {
+		if d.Status == quiz.DeliveryDead {
+			dead = append(dead, d.WebhookDelivery)
+		}
+	}
+	slices.SortFunc(dead, func(a, b quiz.WebhookDelivery) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return dead, nil
+}
+
+// Redeliver queues a dead letter again with all its attempts
+func (db *InMemoryDB) Redeliver(ctx context.Context, id uint64) (quiz.WebhookDelivery, error) {
+	defer db.observe(ctx, "Redeliver")()
+
+	db.lockWebhooks.Lock()
+	defer db.lockWebhooks.Unlock()
+
+	d, ok := db.deliveries[id]
+	if !ok || d.Status != quiz.DeliveryDead {
+		return quiz.WebhookDelivery{}, ErrDeliveryNotFound
+	}
+	if _, ok := db.webhooks[d.Webhook]; !ok {
+		return quiz.WebhookDelivery{}, ErrWebhookNotFound
+	}
+
+	d.Status = quiz.DeliveryPending
+	d.Attempts = 0
+	d.nextAttempt = db.now()
+	db.signalWebhooks()
+	return d.WebhookDelivery, nil
+}
+
+// publish queues a delivery of the event to every subscribed webhook. It only takes lockWebhooks,
+// so it can be called holding the other locks.
+func (db *InMemoryDB) publish(eventType string, data quiz.WebhookEventData) {
+	db.lockWebhooks.Lock()
+	defer db.lockWebhooks.Unlock()
+
+	now := db.now()
+	event := quiz.WebhookEvent{ID: db.nextEvent, Type: eventType, CreatedAt: now, Data: data}
+	db.nextEvent++
+
+	queued := false
+	for _, w := range db.webhooks {
+		if !w.Subscribes(eventType) {
+			continue
+		}
+		db.deliveries[db.nextDelivery] = &delivery{
+			WebhookDelivery: quiz.WebhookDelivery{ID: db.nextDelivery, Webhook: w.Slug, Event: event, Status: quiz.DeliveryPending},
+			nextAttempt:     now,
+		}
+		db.nextDelivery++
+		queued = true
+	}
+	if queued {
+		db.signalWebhooks()
+	}
+}
+
+// signalWebhooks must be called holding lockWebhooks
+func (db *InMemoryDB) signalWebhooks() {
+	select {
+	case db.webhookSignal <- struct{}{}:
+	default:
+	}
+}
+
+// dueDeliveries answers the pending deliveries due at now with the webhooks to deliver them to,
+// and when the next one is due, zero if there is none
+func (db *InMemoryDB) dueDeliveries(now time.Time) ([]quiz.WebhookDelivery, map[string]quiz.Webhook, time.Time) {
+	db.lockWebhooks.Lock()
+	defer db.lockWebhooks.Unlock()
+
+	due := []quiz.WebhookDelivery{}
+	webhooks := map[string]quiz.Webhook{}
+	next := time.Time{}
+	for _, d := range db.deliveries {
+		if d.Status != quiz.DeliveryPending {
+			continue
+		}
+		if d.nextAttempt.After(now) {
+			if next.IsZero() || d.nextAttempt.Before(next) {
+				next = d.nextAttempt
+			}
+			continue
+		}
+		due = append(due, d.WebhookDelivery)
+		webhooks[d.Webhook] = db.webhooks[d.Webhook]
+	}
+	slices.SortFunc(due, func(a, b quiz.WebhookDelivery) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return due, webhooks, next
+}
+
+// recordDelivery forgets delivered deliveries, failed ones are retried after a backoff that
+// doubles with every attempt, until they go to the dead letters
+func (db *InMemoryDB) recordDelivery(id uint64, err error, backoff time.Duration) {
+	db.lockWebhooks.Lock()
+	defer db.lockWebhooks.Unlock()
+
+	d, ok := db.deliveries[id]
+	if !ok {
+		return
+	}
+	if err == nil {
+		delete(db.deliveries, id)
+		return
+	}
+
+	d.Attempts++
+	d.LastError = err.Error()
+	if d.Attempts >= webhookMaxAttempts {
+		d.Status = quiz.DeliveryDead
+		db.dropDeadLetters()
+		return
+	}
+	d.nextAttempt = db.now().Add(backoff << (d.Attempts - 1))
+}
+
+// dropDeadLetters over webhookDeadLetters, the oldest first, must be called holding lockWebhooks
+func (db *InMemoryDB) dropDeadLetters() {
+	dead := []uint64{}
+	for id, d := range db.deliveries {
+		if d.Status == quiz.DeliveryDead {
+			dead = append(dead, id)
+		}
+	}
+	if len(dead) <= webhookDeadLetters {
+		return
+	}
+
+	slices.Sort(dead)
+	for _, id := range dead[:len(dead)-webhookDeadLetters] {
+		delete(db.deliveries, id)
+	}
+}
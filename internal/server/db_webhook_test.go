@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestPublishWebhooks(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+	ctx := context.Background()
+
+	err = db.PutWebhook(ctx, quiz.Webhook{Slug: "hr", URL: "http://localhost/hr", Events: []string{quiz.EventQuizPassed}, Secret: "0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("Error putting webhook: %v", err)
+	}
+	err = db.PutWebhook(ctx, quiz.Webhook{Slug: "chat", URL: "http://localhost/chat", Events: quiz.WebhookEvents, Secret: "0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("Error putting webhook: %v", err)
+	}
+
+	webhooks, err := db.GetWebhooks(ctx)
+	if err != nil {
+		t.Fatalf("Error getting webhooks: %v", err)
+	}
+	if len(webhooks) != 2 || webhooks[0].Slug != "chat" || webhooks[1].Slug != "hr" || webhooks[0].Secret != "" {
+		t.Fatalf("Expected webhooks chat and hr without secrets, got %+v", webhooks)
+	}
+
+	err = db.PutQuiz(ctx, quiz.Quiz{Slug: "capitals", QuestionIDs: []uint64{0, 1}, PassMark: 0.5})
+	if err != nil {
+		t.Fatalf("Error putting quiz: %v", err)
+	}
+	_, err = db.InsertUser(ctx, "other")
+	if err != nil {
+		t.Fatalf("Error inserting user: %v", err)
+	}
+	session, err := db.StartQuiz(ctx, "other", "capitals")
+	if err != nil {
+		t.Fatalf("Error starting quiz: %v", err)
+	}
+	answer := quiz.QuizAnswer{}
+	for _, q := range session.Questions {
+		for i, option := range q.Options {
+			if option == map[uint64]string{0: "Paris", 1: "Berlin"}[q.ID] {
+				answer[q.ID] = q.OptionIDs[i]
+			}
+		}
+	}
+	_, err = db.InsertSessionAnswer(ctx, "other", session.ID, answer)
+	if err != nil {
+		t.Fatalf("Error inserting answers: %v", err)
+	}
+
+	due, _, _ := db.dueDeliveries(db.now())
+	got := map[string][]string{}
+	for _, d := range due {
+		got[d.Webhook] = append(got[d.Webhook], d.Event.Type)
+	}
+	if len(got["chat"]) != 3 || len(got["hr"]) != 1 || got["hr"][0] != quiz.EventQuizPassed {
+		t.Fatalf("Expected 3 deliveries to chat and quiz.passed to hr, got %v", got)
+	}
+	for _, d := range due {
+		if d.Event.Type == quiz.EventQuizPassed && (d.Event.Data.Attempt == nil || !d.Event.Data.Attempt.Passed || d.Event.Data.Quiz != "capitals") {
+			t.Fatalf("Expected a passed attempt of capitals, got %+v", d.Event.Data)
+		}
+	}
+
+	err = db.DeleteWebhook(ctx, "chat")
+	if err != nil {
+		t.Fatalf("Error deleting webhook: %v", err)
+	}
+	err = db.DeleteWebhook(ctx, "chat")
+	if err != ErrWebhookNotFound {
+		t.Fatalf("Expected ErrWebhookNotFound, got %v", err)
+	}
+	if due, _, _ := db.dueDeliveries(db.now()); len(due) != 1 {
+		t.Fatalf("Expected the deliveries of chat dropped, got %+v", due)
+	}
+}
+
+func TestRecordDelivery(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.now = func() time.Time { return now }
+
+	err = db.PutWebhook(ctx, quiz.Webhook{Slug: "chat", URL: "http://localhost/chat", Events: quiz.WebhookEvents, Secret: "0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("Error putting webhook: %v", err)
+	}
+	_, err = db.InsertUser(ctx, "other")
+	if err != nil {
+		t.Fatalf("Error inserting user: %v", err)
+	}
+
+	failure := errors.New("connection refused")
+	for attempt := range webhookMaxAttempts {
+		due, _, _ := db.dueDeliveries(now)
+		if len(due) != 1 || due[0].Attempts != uint64(attempt) {
+			t.Fatalf("Expected attempt %d due, got %+v", attempt, due)
+		}
+		db.recordDelivery(due[0].ID, failure, time.Second)
+
+		// the backoff doubles with every attempt
+		backoff := time.Second << attempt
+		if due, _, next := db.dueDeliveries(now); attempt < webhookMaxAttempts-1 && (len(due) != 0 || !next.Equal(now.Add(backoff))) {
+			t.Fatalf("Expected the next attempt in %s, got %+v due at %s", backoff, due, next)
+		}
+		now = now.Add(backoff)
+	}
+
+	dead, err := db.GetDeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("Error getting dead letters: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Status != quiz.DeliveryDead || dead[0].LastError != failure.Error() || dead[0].Event.Type != quiz.EventUserCreated {
+		t.Fatalf("Expected a dead user.created delivery, got %+v", dead)
+	}
+
+	_, err = db.Redeliver(ctx, 99)
+	if err != ErrDeliveryNotFound {
+		t.Fatalf("Expected ErrDeliveryNotFound, got %v", err)
+	}
+	d, err := db.Redeliver(ctx, dead[0].ID)
+	if err != nil {
+		t.Fatalf("Error redelivering: %v", err)
+	}
+	if d.Status != quiz.DeliveryPending || d.Attempts != 0 {
+		t.Fatalf("Expected a pending delivery, got %+v", d)
+	}
+
+	due, _, _ := db.dueDeliveries(now)
+	if len(due) != 1 {
+		t.Fatalf("Expected the redelivery due, got %+v", due)
+	}
+	db.recordDelivery(due[0].ID, nil, time.Second)
+	if dead, _ := db.GetDeadLetters(ctx); len(dead) != 0 {
+		t.Fatalf("Expected no dead letters, got %+v", dead)
+	}
+	if due, _, next := db.dueDeliveries(now); len(due) != 0 || !next.IsZero() {
+		t.Fatalf("Expected nothing pending, got %+v due at %s", due, next)
+	}
+}
+
+func TestDeadLettersCapped(t *testing.T) {
+	t.Parallel()
+	db, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatalf("Error creating in-memory database: %v", err)
+	}
+	ctx := context.Background()
+
+	err = db.PutWebhook(ctx, quiz.Webhook{Slug: "chat", URL: "http://localhost/chat", Events: quiz.WebhookEvents, Secret: "0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("Error putting webhook: %v", err)
+	}
+	for range webhookDeadLetters + 10 {
+		db.publish(quiz.EventUserCreated, quiz.WebhookEventData{User: "other"})
+	}
+
+	failure := errors.New("connection refused")
+	due, _, _ := db.dueDeliveries(db.now())
+	for _, d := range due {
+		for range webhookMaxAttempts {
+			db.recordDelivery(d.ID, failure, 0)
+		}
+	}
+
+	dead, err := db.GetDeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("Error getting dead letters: %v", err)
+	}
+	if len(dead) != webhookDeadLetters || dead[0].ID != due[10].ID {
+		t.Fatalf("Expected the newest %d dead letters, got %d from %d", webhookDeadLetters, len(dead), dead[0].ID)
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	headerETag           = "ETag"
+	headerIfNoneMatch    = "If-None-Match"
+	headerCacheControl   = "Cache-Control"
+	valueCacheRevalidate = "no-cache"
+)
+
+// newETagEpoch tells apart the versions of two runs of the server, the in memory versions
+// start over at every run
+func newETagEpoch() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// setETag tags the response with a strong ETag of the version, writeData answers 304 Not Modified
+// to the requests that already have it. Caches may keep the response but must revalidate it.
+func (h *Handler) setETag(w http.ResponseWriter, r *http.Request, version uint64) {
+	etag := fmt.Sprintf(`"%s-%d"`, h.etagEpoch, version)
+	// every media type is a representation of its own
+	switch mediaTypeOf(r) {
+	case valueContentTypeCSV:
+		etag = withETagSuffix(etag, "csv")
+	case valueContentTypeMsgPack:
+		etag = withETagSuffix(etag, "msgpack")
+	}
+	w.Header().Set(headerETag, etag)
+	w.Header().Set(headerCacheControl, valueCacheRevalidate)
+}
+
+// notModified tells whether `If-None-Match` lists the ETag, with the weak comparison of RFC 9110.
+// The ETags of compressed responses match the ETag of the response before withCompression.
+func notModified(r *http.Request, etag string) bool {
+	ifNoneMatch := r.Header.Get(headerIfNoneMatch)
+	if etag == "" || ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if withoutEncodingSuffix(strings.TrimPrefix(strings.TrimSpace(candidate), "W/")) == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerETag(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	ctx := context.Background()
+
+	if _, err := handler.db.InsertUser(ctx, "other"); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+
+	get := func(url string, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if ifNoneMatch != "" {
+			r.Header.Set(headerIfNoneMatch, ifNoneMatch)
+		}
+
+		w := httptest.NewRecorder()
+		handler.Mux.ServeHTTP(w, r)
+		return w
+	}
+
+	results := get("/quiz/user", "")
+	statistics := get("/statistics/user", "")
+	for _, w := range []*httptest.ResponseRecorder{results, statistics} {
+		if w.Code != http.StatusOK || w.Header().Get(headerETag) == "" || w.Header().Get(headerCacheControl) != valueCacheRevalidate {
+			t.Fatalf("expected a tagged 200, got %d with headers %v", w.Code, w.Header())
+		}
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		etag       string
+		statusCode int
+	}{
+		{name: "results", url: "/quiz/user", etag: results.Header().Get(headerETag), statusCode: http.StatusNotModified},
+		{name: "results weak", url: "/quiz/user", etag: "W/" + results.Header().Get(headerETag), statusCode: http.StatusNotModified},
+		{name: "results list", url: "/v1/quiz/user", etag: `"other", ` + results.Header().Get(headerETag), statusCode: http.StatusNotModified},
+		{name: "results other", url: "/quiz/user", etag: `"other"`, statusCode: http.StatusOK},
+		{name: "statistics", url: "/statistics/user", etag: statistics.Header().Get(headerETag), statusCode: http.StatusNotModified},
+		{name: "unknown user", url: "/quiz/unknown", etag: "*", statusCode: http.StatusBadRequest},
+		{name: "unknown quiz", url: "/quiz/user?quiz=unknown", etag: "*", statusCode: http.StatusNotFound},
+		{name: "statistics unknown user", url: "/statistics/unknown", etag: "*", statusCode: http.StatusBadRequest},
+		{name: "statistics unknown group", url: "/statistics/user?group=unknown", etag: "*", statusCode: http.StatusNotFound},
+		{name: "statistics unknown quiz", url: "/statistics/user?quiz=unknown", etag: "*", statusCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(tt.url, tt.etag)
+			if w.Code != tt.statusCode {
+				t.Fatalf("expected status code %d, got %d", tt.statusCode, w.Code)
+			}
+			if tt.statusCode == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Fatalf("expected no body, got %s", w.Body.String())
+			}
+			if tt.statusCode >= http.StatusBadRequest && w.Header().Get(headerETag) != "" {
+				t.Fatalf("expected an untagged problem, got ETag %s", w.Header().Get(headerETag))
+			}
+		})
+	}
+
+	// results only change with the answers of the user, statistics with the answers of anyone
+	if err := handler.db.InsertQuizAnswer(ctx, "other", quiz.QuizAnswer{0: "Paris"}); err != nil {
+		t.Fatalf("failed to insert answers: %v", err)
+	}
+	if w := get("/quiz/user", results.Header().Get(headerETag)); w.Code != http.StatusNotModified {
+		t.Fatalf("expected the results of user not modified, got %d", w.Code)
+	}
+	if w := get("/statistics/user", statistics.Header().Get(headerETag)); w.Code != http.StatusOK {
+		t.Fatalf("expected the statistics of user modified, got %d", w.Code)
+	}
+
+	if err := handler.db.InsertQuizAnswer(ctx, "user", quiz.QuizAnswer{0: "Paris"}); err != nil {
+		t.Fatalf("failed to insert answers: %v", err)
+	}
+	if w := get("/quiz/user", results.Header().Get(headerETag)); w.Code != http.StatusOK || w.Header().Get(headerETag) == results.Header().Get(headerETag) {
+		t.Fatalf("expected the results of user modified with a new ETag, got %d", w.Code)
+	}
+}
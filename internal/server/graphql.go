@@ -0,0 +1,587 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	// graphQLMaxCost bounds the fields a query may resolve, see graphQLCost
+	graphQLMaxCost = 1000
+	// graphQLListSize is the assumed length of the lists whose length is only known once resolved
+	graphQLListSize = 10
+	fieldQuery      = "query"
+)
+
+var ErrQueryTooExpensive = errors.New("query too expensive")
+
+var (
+	errGraphQLUnauthenticated = errors.New("unauthenticated")
+	errGraphQLAdmin           = errors.New("the admin token is not a user")
+)
+
+// graphQLCodes of the errors that are not one of problemCodes, the REST routes answer them by status
+var graphQLCodes = map[error]string{
+	errGraphQLUnauthenticated: quiz.CodeUnauthorized,
+}
+
+// graphQLLists are the fields that resolve to lists of objects, their selections cost once per item
+var graphQLLists = map[string]bool{
+	"users":       true,
+	"questions":   true,
+	"leaderboard": true,
+	"attempts":    true,
+	"categories":  true,
+}
+
+// graphQLUser is a user with their overall results, the other fields of User are resolved on demand
+type graphQLUser struct {
+	Name       string
+	Correct    uint64
+	Total      uint64
+	Accuracy   float64
+	Categories []quiz.CategoryResults
+}
+
+func newGraphQLUser(name string, results quiz.QuizResults) graphQLUser {
+	return graphQLUser{
+		Name:       name,
+		Correct:    results.Correct,
+		Total:      results.Total,
+		Accuracy:   quiz.Accuracy(results.Correct, results.Total),
+		Categories: results.Categories,
+	}
+}
+
+type graphQLContextKey struct{}
+
+// graphQLContext is shared by the resolvers of one request, the loaders batch the lookups of the
+// users resolved at the same level of the query
+type graphQLContext struct {
+	db *InMemoryDB
+	// user authenticated like the group routes, empty with userErr when there is none
+	user    string
+	userErr error
+	results *loader[string, quiz.QuizResults]
+	// loaders per quiz, per quiz and group, and per group, their keys are users
+	quizResults map[string]*loader[string, quiz.QuizResults]
+	statistics  map[[2]string]*loader[string, quiz.StatisticsResults]
+	ranks       map[string]*loader[string, uint64]
+}
+
+func newGraphQLContext(db *InMemoryDB, user string, userErr error) *graphQLContext {
+	return &graphQLContext{
+		db:          db,
+		user:        user,
+		userErr:     userErr,
+		results:     newLoader(ErrUserNotFound, db.GetUsersResults),
+		quizResults: map[string]*loader[string, quiz.QuizResults]{},
+		statistics:  map[[2]string]*loader[string, quiz.StatisticsResults]{},
+		ranks:       map[string]*loader[string, uint64]{},
+	}
+}
+
+func graphQLContextOf(ctx context.Context) *graphQLContext {
+	return ctx.Value(graphQLContextKey{}).(*graphQLContext)
+}
+
+func (c *graphQLContext) quizResultsOf(slug string) *loader[string, quiz.QuizResults] {
+	if _, ok := c.quizResults[slug]; !ok {
+		c.quizResults[slug] = newLoader(ErrUserNotFound, func(ctx context.Context, users []string) (map[string]quiz.QuizResults, error) {
+			return c.db.GetUsersQuizResults(ctx, users, slug)
+		})
+	}
+	return c.quizResults[slug]
+}
+
+func (c *graphQLContext) statisticsOf(slug string, group string) *loader[string, quiz.StatisticsResults] {
+	key := [2]string{slug, group}
+	if _, ok := c.statistics[key]; !ok {
+		c.statistics[key] = newLoader(ErrNotEnoughUsersForStatistics, func(ctx context.Context, users []string) (map[string]quiz.StatisticsResults, error) {
+			if slug != "" {
+				return c.db.GetUsersQuizStatistics(ctx, users, slug, group)
+			}
+			return c.db.GetUsersStatistics(ctx, users, group)
+		})
+	}
+	return c.statistics[key]
+}
+
+// ranksOf resolves users outside of the leaderboard to a zero rank. One leaderboard has every rank,
+// it is loaded once for the request and every batch answers from it.
+func (c *graphQLContext) ranksOf(group string) *loader[string, uint64] {
+	if _, ok := c.ranks[group]; !ok {
+		var ranks map[string]uint64
+		c.ranks[group] = newLoader(nil, func(ctx context.Context, _ []string) (map[string]uint64, error) {
+			if ranks != nil {
+				return ranks, nil
+			}
+			leaderboard, err := c.db.GetLeaderboard(ctx, group)
+			if err != nil {
+				return nil, err
+			}
+			ranks = make(map[string]uint64, len(leaderboard))
+			for _, entry := range leaderboard {
+				ranks[entry.User] = entry.Rank
+			}
+			return ranks, nil
+		})
+	}
+	return c.ranks[group]
+}
+
+// userThunk resolves a user through the batched results
+func (c *graphQLContext) userThunk(ctx context.Context, name string) func() (any, error) {
+	thunk := c.results.load(ctx, name)
+	return func() (any, error) {
+		results, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+		return newGraphQLUser(name, results), nil
+	}
+}
+
+func stringArg(p graphql.ResolveParams, name string) string {
+	value, _ := p.Args[name].(string)
+	return value
+}
+
+func stringsArg(p graphql.ResolveParams, name string) []string {
+	args, _ := p.Args[name].([]any)
+	values := make([]string, 0, len(args))
+	for _, arg := range args {
+		if value, ok := arg.(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// newGraphQLSchema only has queries, changes stay on the REST routes
+func newGraphQLSchema() (graphql.Schema, error) {
+	nonNullString := graphql.NewNonNull(graphql.String)
+	nonNullInt := graphql.NewNonNull(graphql.Int)
+	nonNullFloat := graphql.NewNonNull(graphql.Float)
+
+	categoryResultsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CategoryResults",
+		Fields: graphql.Fields{
+			"category": &graphql.Field{Type: nonNullString},
+			"correct":  &graphql.Field{Type: nonNullInt},
+			"total":    &graphql.Field{Type: nonNullInt},
+		},
+	})
+
+	categoryStatisticsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CategoryStatistics",
+		Fields: graphql.Fields{
+			"category":   &graphql.Field{Type: nonNullString},
+			"correct":    &graphql.Field{Type: nonNullInt},
+			"total":      &graphql.Field{Type: nonNullInt},
+			"avgCorrect": &graphql.Field{Type: nonNullFloat},
+			"avgTotal":   &graphql.Field{Type: nonNullFloat},
+		},
+	})
+
+	statisticsType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Statistics",
+		Description: "The user compared with the other users, or with the other members of a group",
+		Fields: graphql.Fields{
+			"correct":    &graphql.Field{Type: nonNullInt},
+			"total":      &graphql.Field{Type: nonNullInt},
+			"avgCorrect": &graphql.Field{Type: nonNullFloat},
+			"avgTotal":   &graphql.Field{Type: nonNullFloat},
+			"categories": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryStatisticsType)))},
+		},
+	})
+
+	attemptType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Attempt",
+		Description: "One session of a named quiz",
+		Fields: graphql.Fields{
+			"session":   &graphql.Field{Type: nonNullInt},
+			"startedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"correct":   &graphql.Field{Type: nonNullInt},
+			"total":     &graphql.Field{Type: nonNullInt},
+			"passed":    &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+
+	questionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Question",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: nonNullInt},
+			"text":     &graphql.Field{Type: nonNullString},
+			"options":  &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(nonNullString))},
+			"category": &graphql.Field{Type: graphql.String},
+			"tags":     &graphql.Field{Type: graphql.NewList(nonNullString)},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"name":       &graphql.Field{Type: nonNullString},
+			"correct":    &graphql.Field{Type: nonNullInt},
+			"total":      &graphql.Field{Type: nonNullInt},
+			"accuracy":   &graphql.Field{Type: nonNullFloat},
+			"categories": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryResultsType)))},
+			// the fields resolved by a loader are nullable, the executor does not catch the errors of
+			// a non-null field resolved late and the whole data would be null
+			"attempts": &graphql.Field{
+				Type:        graphql.NewList(graphql.NewNonNull(attemptType)),
+				Description: "Sessions of the named quiz",
+				Args:        graphql.FieldConfigArgument{"quiz": &graphql.ArgumentConfig{Type: nonNullString}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					user := p.Source.(graphQLUser)
+					thunk := graphQLContextOf(p.Context).quizResultsOf(stringArg(p, "quiz")).load(p.Context, user.Name)
+					return func() (any, error) {
+						results, err := thunk()
+						if err != nil {
+							return nil, err
+						}
+						return results.Attempts, nil
+					}, nil
+				},
+			},
+			"statistics": &graphql.Field{
+				Type:        statisticsType,
+				Description: "Null with an error when there is no one to compare with",
+				Args: graphql.FieldConfigArgument{
+					"quiz":  &graphql.ArgumentConfig{Type: graphql.String, Description: "Only the answers of this named quiz"},
+					"group": &graphql.ArgumentConfig{Type: graphql.String, Description: "Only compared with the members of this group"},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					user := p.Source.(graphQLUser)
+					thunk := graphQLContextOf(p.Context).statisticsOf(stringArg(p, "quiz"), stringArg(p, "group")).load(p.Context, user.Name)
+					return func() (any, error) {
+						return thunk()
+					}, nil
+				},
+			},
+			"leaderboardRank": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Null when the user is not ranked, e.g. not a member of the group",
+				Args:        graphql.FieldConfigArgument{"group": &graphql.ArgumentConfig{Type: graphql.String}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					user := p.Source.(graphQLUser)
+					thunk := graphQLContextOf(p.Context).ranksOf(stringArg(p, "group")).load(p.Context, user.Name)
+					return func() (any, error) {
+						rank, err := thunk()
+						if err != nil || rank == 0 {
+							return nil, err
+						}
+						return rank, nil
+					}, nil
+				},
+			},
+		},
+	})
+
+	leaderboardEntryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LeaderboardEntry",
+		Fields: graphql.Fields{
+			"rank": &graphql.Field{Type: nonNullInt},
+			"user": &graphql.Field{
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					entry := p.Source.(quiz.LeaderboardEntry)
+					return graphQLContextOf(p.Context).userThunk(p.Context, entry.User), nil
+				},
+			},
+			"correct":  &graphql.Field{Type: nonNullInt},
+			"total":    &graphql.Field{Type: nonNullInt},
+			"accuracy": &graphql.Field{Type: nonNullFloat},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type:        userType,
+				Description: "The user of `Authorization: Bearer <token>`",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					c := graphQLContextOf(p.Context)
+					if c.userErr != nil {
+						return nil, fmt.Errorf("%w: %w", errGraphQLUnauthenticated, c.userErr)
+					}
+					return c.userThunk(p.Context, c.user), nil
+				},
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{"name": &graphql.ArgumentConfig{Type: nonNullString}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return graphQLContextOf(p.Context).userThunk(p.Context, stringArg(p, "name")), nil
+				},
+			},
+			"users": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(userType)),
+				Description: "Unknown users are null",
+				Args:        graphql.FieldConfigArgument{"names": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(nonNullString))}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					c := graphQLContextOf(p.Context)
+					names := stringsArg(p, "names")
+					users := make([]any, 0, len(names))
+					for _, name := range names {
+						users = append(users, c.userThunk(p.Context, name))
+					}
+					return users, nil
+				},
+			},
+			"questions": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(questionType))),
+				Description: "A sample of the questions, like GET /quiz",
+				Args: graphql.FieldConfigArgument{
+					"category": &graphql.ArgumentConfig{Type: graphql.String},
+					"tags":     &graphql.ArgumentConfig{Type: graphql.NewList(nonNullString)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					filter := QuestionFilter{Category: stringArg(p, "category"), Tags: stringsArg(p, "tags")}
+					return graphQLContextOf(p.Context).db.GetQuestions(p.Context, filter)
+				},
+			},
+			"leaderboard": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(leaderboardEntryType))),
+				Args: graphql.FieldConfigArgument{
+					"group": &graphql.ArgumentConfig{Type: graphql.String, Description: "Only the members of this group"},
+					"first": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Only the first ranks, every user by default"},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					first, limited := p.Args["first"].(int)
+					if limited && first < 0 {
+						return nil, &quiz.FieldError{Field: "first", Reason: "must not be negative"}
+					}
+					leaderboard, err := graphQLContextOf(p.Context).db.GetLeaderboard(p.Context, stringArg(p, "group"))
+					if err != nil {
+						return nil, err
+					}
+					if limited && first < len(leaderboard) {
+						leaderboard = leaderboard[:first]
+					}
+					return leaderboard, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// postGraphQL answers queries over the same store as the REST routes. Reads are public like theirs,
+// `me` needs the `Authorization: Bearer <token>` of the group routes.
+func (h *Handler) postGraphQL(w http.ResponseWriter, r *http.Request) {
+	request := quiz.GraphQLRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	if err != nil {
+		// the message goes on with an excerpt of the query
+		reason, _, _ := strings.Cut(err.Error(), "\n")
+		h.writeError(w, r, http.StatusBadRequest, &quiz.FieldError{Field: fieldQuery, Reason: reason})
+		return
+	}
+
+	cost, err := graphQLCost(doc, request.OperationName, request.Variables, int(h.db.countUsers()))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if cost > graphQLMaxCost {
+		err := fmt.Errorf("%w: %w", ErrQueryTooExpensive, &quiz.FieldError{Field: fieldQuery, Reason: fmt.Sprintf("costs more than %d, the limit", graphQLMaxCost)})
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var result *graphql.Result
+	if validation := graphql.ValidateDocument(&h.graphql, doc, nil); !validation.IsValid {
+		result = &graphql.Result{Errors: validation.Errors}
+	} else {
+		user, userErr := h.fromAuthUser(r)
+		if userErr == nil && user == "" {
+			userErr = errGraphQLAdmin
+		}
+		ctx := context.WithValue(r.Context(), graphQLContextKey{}, newGraphQLContext(h.db, user, userErr))
+		result = graphql.Execute(graphql.ExecuteParams{
+			Schema:        h.graphql,
+			AST:           doc,
+			OperationName: request.OperationName,
+			Args:          request.Variables,
+			Context:       ctx,
+		})
+	}
+
+	for i, err := range result.Errors {
+		if result.Errors[i].Extensions == nil {
+			result.Errors[i].Extensions = map[string]any{}
+		}
+		result.Errors[i].Extensions["code"] = graphQLErrorCode(err)
+	}
+	h.writeData(w, r, result)
+}
+
+// graphQLErrorCode finds the problem code of the error a resolver returned, the executor wraps it
+func graphQLErrorCode(err error) string {
+	for err != nil {
+		switch e := err.(type) {
+		case gqlerrors.FormattedError:
+			err = e.OriginalError()
+		case *gqlerrors.Error:
+			err = e.OriginalError
+		default:
+			for sentinel, code := range graphQLCodes {
+				if errors.Is(err, sentinel) {
+					return code
+				}
+			}
+			if code := problemCode(err); code != "" {
+				return code
+			}
+			return quiz.CodeInvalidRequest
+		}
+	}
+	return quiz.CodeInvalidRequest
+}
+
+// graphQLCost estimates the fields the operation resolves before running it, every field costs one
+// and the fields under a list cost once per item. Lists of users cost the names asked for, the
+// leaderboard its `first` ranks or every one of the users, the other lists are assumed graphQLListSize
+// long. Fragments that spread themselves are refused here, the validation of the executor recurses on
+// them until the stack overflows.
+func graphQLCost(doc *ast.Document, operationName string, variables map[string]any, users int) (int, error) {
+	c := costWalker{fragments: map[string]*ast.FragmentDefinition{}, variables: variables, users: users, visiting: map[string]bool{}}
+	operations := []*ast.OperationDefinition{}
+	for _, definition := range doc.Definitions {
+		switch d := definition.(type) {
+		case *ast.FragmentDefinition:
+			c.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				operations = append(operations, d)
+			}
+		}
+	}
+
+	cost := 0
+	for _, operation := range operations {
+		cost = max(cost, c.selections(operation.SelectionSet))
+	}
+	for _, fragment := range c.fragments {
+		c.selections(fragment.SelectionSet)
+	}
+	if c.cycle != "" {
+		return 0, &quiz.FieldError{Field: fieldQuery, Reason: fmt.Sprintf("fragment `%s` spreads itself", c.cycle)}
+	}
+	return cost, nil
+}
+
+type costWalker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	// users is the length of a leaderboard without `first`
+	users int
+	// fragments being walked, to find the cycles
+	visiting map[string]bool
+	cycle    string
+}
+
+// selections stops once over graphQLMaxCost, so fragments spread many times do not have to be walked
+func (c *costWalker) selections(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+
+	cost := 0
+	for _, selection := range set.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			cost += 1 + c.listSize(s)*c.selections(s.SelectionSet)
+		case *ast.InlineFragment:
+			cost += c.selections(s.SelectionSet)
+		case *ast.FragmentSpread:
+			fragment, ok := c.fragments[s.Name.Value]
+			if !ok {
+				continue
+			}
+			if c.visiting[s.Name.Value] {
+				c.cycle = s.Name.Value
+				return cost
+			}
+			c.visiting[s.Name.Value] = true
+			cost += c.selections(fragment.SelectionSet)
+			delete(c.visiting, s.Name.Value)
+		}
+		if cost > graphQLMaxCost {
+			return cost
+		}
+	}
+	return cost
+}
+
+func (c *costWalker) listSize(field *ast.Field) int {
+	if !graphQLLists[field.Name.Value] {
+		return 1
+	}
+	switch field.Name.Value {
+	case "users":
+	case "leaderboard":
+		return c.leaderboardSize(field)
+	default:
+		return graphQLListSize
+	}
+
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "names" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.ListValue:
+			return len(value.Values)
+		case *ast.Variable:
+			if names, ok := c.variables[value.Name.Value].([]any); ok {
+				return len(names)
+			}
+		}
+	}
+	return graphQLListSize
+}
+
+func (c *costWalker) leaderboardSize(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		var first int
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			first, _ = strconv.Atoi(value.Value)
+		case *ast.Variable:
+			// numbers of the JSON variables are decoded as float64
+			if number, ok := c.variables[value.Name.Value].(float64); ok {
+				first = int(number)
+			} else {
+				continue
+			}
+		default:
+			continue
+		}
+		return max(0, min(first, c.users))
+	}
+	return c.users
+}
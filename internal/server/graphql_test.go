@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// postGraphQLQuery answers the response of a 200, or the problem of other statuses
+func postGraphQLQuery(t *testing.T, handler *Handler, token string, query string) (quiz.GraphQLResponse, *quiz.Problem) {
+	t.Helper()
+	body, err := json.Marshal(quiz.GraphQLRequest{Query: query})
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/v1/graphql", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		problem := &quiz.Problem{}
+		if err := json.Unmarshal(w.Body.Bytes(), problem); err != nil {
+			t.Fatalf("failed to unmarshal problem: %v", err)
+		}
+		return quiz.GraphQLResponse{}, problem
+	}
+
+	var resp quiz.GraphQLResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	return resp, nil
+}
+
+func TestHandlerGraphQL(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	ctx := context.Background()
+
+	if _, err := handler.db.InsertUser(ctx, "other"); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+	if err := handler.db.InsertQuizAnswer(ctx, "other", quiz.QuizAnswer{0: "Paris", 2: "1"}); err != nil {
+		t.Fatalf("failed to insert answers: %v", err)
+	}
+	token := testUserToken(t, handler.db, "user")
+
+	tests := []struct {
+		name  string
+		token string
+		query string
+		want  string
+		code  string
+	}{
+		{
+			name:  "user",
+			query: `{ user(name: "other") { name correct total categories { category correct } leaderboardRank } }`,
+			want:  `{"user":{"categories":[{"category":"arithmetic","correct":0},{"category":"geography","correct":1}],"correct":1,"leaderboardRank":1,"name":"other","total":2}}`,
+		},
+		{
+			name:  "me",
+			token: token,
+			query: `{ me { name statistics { correct avgCorrect } } }`,
+			want:  `{"me":{"name":"user","statistics":{"avgCorrect":1,"correct":0}}}`,
+		},
+		{
+			name:  "me with a user name",
+			token: "user",
+			query: `{ me { name } }`,
+			want:  `{"me":null}`,
+			code:  quiz.CodeUnauthorized,
+		},
+		{
+			name:  "me unauthenticated",
+			query: `{ me { name } }`,
+			want:  `{"me":null}`,
+			code:  quiz.CodeUnauthorized,
+		},
+		{
+			name:  "me as admin",
+			token: testAdminToken,
+			query: `{ me { name } }`,
+			want:  `{"me":null}`,
+			code:  quiz.CodeUnauthorized,
+		},
+		{
+			name:  "unknown user",
+			query: `{ users(names: ["other", "unknown"]) { name } }`,
+			want:  `{"users":[{"name":"other"},null]}`,
+			code:  quiz.CodeUserNotFound,
+		},
+		{
+			name:  "unknown quiz",
+			query: `{ user(name: "other") { attempts(quiz: "unknown") { session } } }`,
+			want:  `{"user":{"attempts":null}}`,
+			code:  quiz.CodeQuizNotFound,
+		},
+		{
+			name:  "leaderboard",
+			query: `{ leaderboard { rank user { name } } }`,
+			want:  `{"leaderboard":[{"rank":1,"user":{"name":"other"}},{"rank":2,"user":{"name":"user"}}]}`,
+		},
+		{
+			name:  "leaderboard first",
+			query: `{ leaderboard(first: 1) { rank user { name } } }`,
+			want:  `{"leaderboard":[{"rank":1,"user":{"name":"other"}}]}`,
+		},
+		{
+			name:  "invalid field",
+			query: `{ user(name: "other") { answer } }`,
+			code:  quiz.CodeInvalidRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, problem := postGraphQLQuery(t, handler, tt.token, tt.query)
+			if problem != nil {
+				t.Fatalf("expected a response, got %v", problem)
+			}
+
+			if tt.want != "" && string(resp.Data) != tt.want {
+				t.Fatalf("expected data %s, got %s", tt.want, resp.Data)
+			}
+			if tt.code == "" && len(resp.Errors) > 0 {
+				t.Fatalf("expected no errors, got %v", resp.Errors)
+			}
+			if tt.code != "" && (len(resp.Errors) != 1 || resp.Errors[0].Code() != tt.code) {
+				t.Fatalf("expected an error with code %s, got %+v", tt.code, resp.Errors)
+			}
+		})
+	}
+}
+
+func TestHandlerGraphQLProblems(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	names := make([]string, 250)
+	for i := range names {
+		names[i] = fmt.Sprintf("%q", fmt.Sprint("user", i))
+	}
+	// the leaderboard costs every user
+	for i := range 100 {
+		if _, err := handler.db.InsertUser(context.Background(), fmt.Sprint("ranked", i)); err != nil {
+			t.Fatalf("failed to insert user: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{name: "syntax", query: `{ user(name: "user") { name }`, code: quiz.CodeInvalidRequest},
+		{name: "nested lists", query: `{ leaderboard(first: 10) { user { categories { category } attempts(quiz: "a") { session } } } }`, code: ""},
+		{name: "every user", query: `{ leaderboard { user { categories { category } attempts(quiz: "a") { session } } } }`, code: quiz.CodeQueryTooExpensive},
+		{name: "too many users", query: `{ users(names: [` + strings.Join(names, ",") + `]) { name correct total accuracy leaderboardRank } }`, code: quiz.CodeQueryTooExpensive},
+		{name: "fragments", query: `{ ...a } fragment a on Query { leaderboard { user { ...b } } } fragment b on User { attempts(quiz: "a") { session correct total passed startedAt } categories { category correct total } statistics { categories { category correct total avgCorrect avgTotal } } }`, code: quiz.CodeQueryTooExpensive},
+		{name: "fragment cycle", query: `{ ...a } fragment a on Query { leaderboard { rank } ...b } fragment b on Query { ...a }`, code: quiz.CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, problem := postGraphQLQuery(t, handler, "", tt.query)
+			if tt.code == "" {
+				if problem != nil {
+					t.Fatalf("expected the query to be run, got %v", problem)
+				}
+				return
+			}
+
+			if problem == nil || problem.Code != tt.code || len(problem.Errors) != 1 || problem.Errors[0].Field != "query" {
+				t.Fatalf("expected a problem with code %s on the query, got %+v", tt.code, problem)
+			}
+		})
+	}
+}
+
+func TestGraphQLBatching(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	ctx := context.Background()
+
+	users := []string{"a", "b", "c", "d"}
+	for _, user := range users {
+		if _, err := handler.db.InsertUser(ctx, user); err != nil {
+			t.Fatalf("failed to insert user: %v", err)
+		}
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: `{
+		leaderboard { user { name statistics { correct } leaderboardRank } }
+		users(names: ["a", "b"]) { name leaderboardRank }
+	}`})
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	c := newGraphQLContext(handler.db, "", errGraphQLUnauthenticated)
+	result := graphql.Execute(graphql.ExecuteParams{Schema: handler.graphql, AST: doc, Context: context.WithValue(ctx, graphQLContextKey{}, c)})
+	if len(result.Errors) > 0 {
+		t.Fatalf("expected no errors, got %v", result.Errors)
+	}
+
+	if leaderboard := result.Data.(map[string]any)["leaderboard"].([]any); len(leaderboard) != len(users)+1 {
+		t.Fatalf("expected every user in the leaderboard, got %v", leaderboard)
+	}
+
+	// users of the same level of the query are loaded at once, whatever field asked for them
+	if c.results.batches != 1 {
+		t.Fatalf("expected the users loaded in 1 batch, got %d", c.results.batches)
+	}
+	if c.statisticsOf("", "").batches != 1 || c.ranksOf("").batches != 1 {
+		t.Fatalf("expected statistics and ranks loaded in 1 batch each, got %d and %d", c.statisticsOf("", "").batches, c.ranksOf("").batches)
+	}
+}
@@ -0,0 +1,366 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+	"github.com/vrnvu/temp/pkg/quizpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// gRPC metadata keys are lowercase
+	metadataXRequestID = "x-request-id"
+	errorInfoDomain    = "quiz"
+	contentTypeGRPC    = "application/grpc"
+)
+
+// grpcCodes of every sentinel error of problemCodes, the HTTP routes answer some of them with a
+// plain 400 or 409 where gRPC has a more precise code
+var grpcCodes = map[error]codes.Code{
+	ErrUserAlreadyExists:           codes.AlreadyExists,
+	ErrUserNotFound:                codes.NotFound,
+	ErrNotEnoughUsersForStatistics: codes.FailedPrecondition,
+	ErrSessionNotFound:             codes.NotFound,
+	ErrQuestionNotInSession:        codes.InvalidArgument,
+	ErrQuestionAlreadyAnswered:     codes.FailedPrecondition,
+	ErrInvalidOption:               codes.InvalidArgument,
+	ErrBatchedAnswers:              codes.InvalidArgument,
+	ErrQuestionNotFound:            codes.NotFound,
+	ErrQuizNotFound:                codes.NotFound,
+	ErrQuizClosed:                  codes.FailedPrecondition,
+	ErrMaxAttemptsReached:          codes.ResourceExhausted,
+	ErrGroupNotFound:               codes.NotFound,
+	ErrGroupAlreadyExists:          codes.AlreadyExists,
+	ErrNotGroupOwner:               codes.PermissionDenied,
+	ErrLastGroupOwner:              codes.FailedPrecondition,
+	ErrRoomNotFound:                codes.NotFound,
+	ErrUnsupportedVersion:          codes.InvalidArgument,
+	ErrQueryTooExpensive:           codes.ResourceExhausted,
+	ErrWebhookNotFound:             codes.NotFound,
+	ErrDeliveryNotFound:            codes.NotFound,
+	ErrIdempotencyKeyReused:        codes.InvalidArgument,
+	ErrIdempotencyKeyInUse:         codes.Aborted,
+	ErrNotAcceptable:               codes.InvalidArgument,
+	ErrInvalidArchive:              codes.InvalidArgument,
+	ErrImportConflict:              codes.AlreadyExists,
+	ErrBlobNotFound:                codes.NotFound,
+	ErrBlobTooLarge:                codes.InvalidArgument,
+	ErrUnsupportedMediaType:        codes.InvalidArgument,
+}
+
+// NewGRPCServer serves QuizService from the same store as the Handler, so both APIs see the same
+// users and answers
+func NewGRPCServer(h *Handler, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(
+		withGRPCRequestID(h.requestIDGenerator),
+		withGRPCTracing(h.tracer),
+		withGRPCLogging(h.Slog),
+		withGRPCAudit(h.audit),
+		withGRPCStatus(h.Slog),
+		withGRPCRecovery(),
+	))
+
+	s := grpc.NewServer(opts...)
+	quizpb.RegisterQuizServiceServer(s, &quizService{db: h.db})
+	return s
+}
+
+// WithGRPC multiplexes one TLS listener, HTTP/2 requests of gRPC go to grpcServer and the rest to next
+func WithGRPC(grpcServer http.Handler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get(headerContentType), contentTypeGRPC) {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withGRPCRequestID mirrors withRequestID, the ID travels in the `x-request-id` metadata
+func withGRPCRequestID(requestIDGenerator func() string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		id := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(metadataXRequestID)) > 0 {
+			id = md.Get(metadataXRequestID)[0]
+		}
+		if !requestIDRegexp.MatchString(id) {
+			id = requestIDGenerator()
+		}
+
+		ctx = context.WithValue(ctx, xRequestIDHeaderKey, id)
+		// there is no header to set outside of a real stream, e.g. when the interceptor is tested alone
+		_ = grpc.SetHeader(ctx, metadata.Pairs(metadataXRequestID, id))
+		return handler(ctx, req)
+	}
+}
+
+// withGRPCLogging mirrors withLoggingMethod
+func withGRPCLogging(slog *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		slog.InfoContext(ctx, "", "method", info.FullMethod, headerXRequestID, grpcRequestID(ctx))
+		return handler(ctx, req)
+	}
+}
+
+// grpcAuditActions of the methods that change something, as the operation of the api that does the
+// same, so the audit log is filtered by the same action whichever api was called
+var grpcAuditActions = map[string]string{
+	quizpb.QuizService_SubmitAnswers_FullMethodName: "putQuizAnswers",
+	quizpb.QuizService_CreateUser_FullMethodName:    "putUser",
+}
+
+// withGRPCAudit mirrors withAudit for the methods of grpcAuditActions, gRPC takes no token so the
+// actor is never known. It runs outside of withGRPCStatus to record the status the client gets.
+func withGRPCAudit(audit *auditLog) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		action, ok := grpcAuditActions[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		event := quiz.AuditEvent{Action: action, RequestID: grpcRequestID(ctx), SourceIP: grpcSourceIP(ctx)}
+		if r, ok := req.(interface{ GetUser() string }); ok {
+			event.Target = queryUser + "=" + r.GetUser()
+		}
+
+		resp, err := handler(ctx, req)
+		event.Status = grpcHTTPStatus(status.Code(err))
+		event.Outcome = quiz.AuditOutcome(event.Status)
+		audit.record(event)
+		return resp, err
+	}
+}
+
+// grpcSourceIP mirrors sourceIP, the peer of the connection
+func grpcSourceIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// grpcHTTPStatus of a code, the audit events record an HTTP status whichever api was called
+func grpcHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// withGRPCStatus logs the errors of the methods and answers them as a status, with the stable code
+// of the HTTP problems as the reason of an ErrorInfo detail. Internal errors are not detailed.
+func withGRPCStatus(slog *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		code, reason := grpcStatusOf(err)
+		slog.ErrorContext(ctx, code.String(), "error", err, "method", info.FullMethod, headerXRequestID, grpcRequestID(ctx))
+
+		message := err.Error()
+		if code == codes.Internal {
+			message = http.StatusText(http.StatusInternalServerError)
+		}
+		st, detailErr := status.New(code, message).WithDetails(&errdetails.ErrorInfo{
+			Reason:   reason,
+			Domain:   errorInfoDomain,
+			Metadata: map[string]string{"request_id": grpcRequestID(ctx)},
+		})
+		if detailErr != nil {
+			return nil, status.Error(code, message)
+		}
+		return nil, st.Err()
+	}
+}
+
+// withGRPCRecovery answers a panic of a method as an internal error, net/http recovers the panics
+// of its handlers but a gRPC server does not and the whole process would exit
+func withGRPCRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				resp, err = nil, fmt.Errorf("panic: %v\n%s", p, debug.Stack())
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func grpcStatusOf(err error) (codes.Code, string) {
+	var fieldErr *quiz.FieldError
+	if errors.As(err, &fieldErr) {
+		return codes.InvalidArgument, quiz.CodeInvalidRequest
+	}
+
+	for sentinel, code := range grpcCodes {
+		if errors.Is(err, sentinel) {
+			return code, problemCodes[sentinel]
+		}
+	}
+	return codes.Internal, quiz.CodeInternal
+}
+
+func grpcRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(xRequestIDHeaderKey).(string)
+	return id
+}
+
+type quizService struct {
+	quizpb.UnimplementedQuizServiceServer
+	db *InMemoryDB
+}
+
+func (s *quizService) ListQuestions(ctx context.Context, req *quizpb.ListQuestionsRequest) (*quizpb.ListQuestionsResponse, error) {
+	filter := QuestionFilter{Category: req.GetCategory(), Tags: req.GetTags()}
+
+	var questions []quiz.Question
+	var err error
+	if user := req.GetReviewUser(); user != "" {
+		questions, err = s.db.GetDueQuestions(ctx, user, filter)
+	} else {
+		questions, err = s.db.GetQuestions(ctx, filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &quizpb.ListQuestionsResponse{Questions: make([]*quizpb.Question, 0, len(questions))}
+	for _, q := range questions {
+		resp.Questions = append(resp.Questions, &quizpb.Question{Id: q.ID, Text: q.Text, Options: q.Options, Category: q.Category, Tags: q.Tags})
+	}
+	return resp, nil
+}
+
+func (s *quizService) SubmitAnswers(ctx context.Context, req *quizpb.SubmitAnswersRequest) (*quizpb.SubmitAnswersResponse, error) {
+	if err := assertUser(req.GetUser()); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.InsertQuizAnswer(ctx, req.GetUser(), req.GetAnswers()); err != nil {
+		return nil, err
+	}
+	return &quizpb.SubmitAnswersResponse{}, nil
+}
+
+func (s *quizService) GetResults(ctx context.Context, req *quizpb.GetResultsRequest) (*quizpb.Results, error) {
+	if err := assertUser(req.GetUser()); err != nil {
+		return nil, err
+	}
+
+	var results quiz.QuizResults
+	var err error
+	if slug := req.GetQuiz(); slug != "" {
+		results, err = s.db.GetQuizResults(ctx, req.GetUser(), slug)
+	} else {
+		results, err = s.db.GetResults(ctx, req.GetUser())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &quizpb.Results{Correct: results.Correct, Total: results.Total}
+	for _, c := range results.Categories {
+		resp.Categories = append(resp.Categories, &quizpb.CategoryResults{Category: c.Category, Correct: c.Correct, Total: c.Total})
+	}
+	for _, a := range results.Attempts {
+		resp.Attempts = append(resp.Attempts, &quizpb.AttemptResults{
+			Session:   a.Session,
+			StartedAt: timestamppb.New(a.StartedAt),
+			Correct:   a.Correct,
+			Total:     a.Total,
+			Passed:    a.Passed,
+		})
+	}
+	return resp, nil
+}
+
+func (s *quizService) CreateUser(ctx context.Context, req *quizpb.CreateUserRequest) (*quizpb.CreateUserResponse, error) {
+	if err := assertUser(req.GetUser()); err != nil {
+		return nil, err
+	}
+
+	token, err := s.db.InsertUser(ctx, req.GetUser())
+	if err != nil {
+		return nil, err
+	}
+	return &quizpb.CreateUserResponse{Token: token}, nil
+}
+
+func (s *quizService) GetStatistics(ctx context.Context, req *quizpb.GetStatisticsRequest) (*quizpb.Statistics, error) {
+	if err := assertUser(req.GetUser()); err != nil {
+		return nil, err
+	}
+
+	var statistics quiz.StatisticsResults
+	var err error
+	if slug := req.GetQuiz(); slug != "" {
+		statistics, err = s.db.GetQuizStatistics(ctx, req.GetUser(), slug, req.GetGroup())
+	} else {
+		statistics, err = s.db.GetStatistics(ctx, req.GetUser(), req.GetGroup())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &quizpb.Statistics{
+		Correct:    statistics.Correct,
+		Total:      statistics.Total,
+		AvgCorrect: statistics.AvgCorrect,
+		AvgTotal:   statistics.AvgTotal,
+	}
+	for _, c := range statistics.Categories {
+		resp.Categories = append(resp.Categories, &quizpb.CategoryStatistics{
+			Category:   c.Category,
+			Correct:    c.Correct,
+			Total:      c.Total,
+			AvgCorrect: c.AvgCorrect,
+			AvgTotal:   c.AvgTotal,
+		})
+	}
+	return resp, nil
+}
+
+func assertUser(user string) error {
+	if user == "" {
+		return &quiz.FieldError{Field: queryUser, Reason: "``"}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+	"github.com/vrnvu/temp/pkg/quizpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testGRPC serves the handler and its gRPC server on the same TLS listener, like the server does
+func testGRPC(t *testing.T) (quizpb.QuizServiceClient, *httptest.Server) {
+	handler := testHandler(t)
+
+	s := httptest.NewUnstartedServer(WithGRPC(NewGRPCServer(handler), handler))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	t.Cleanup(s.Close)
+
+	tlsConfig := s.Client().Transport.(*http.Transport).TLSClientConfig
+	conn, err := grpc.NewClient(s.Listener.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return quizpb.NewQuizServiceClient(conn), s
+}
+
+// assertStatus checks the code of the status and the reason of its ErrorInfo
+func assertStatus(t *testing.T, err error, code codes.Code, reason string) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != code {
+		t.Fatalf("expected status %s, got %v", code, err)
+	}
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetReason() == reason {
+			return
+		}
+	}
+	t.Fatalf("expected reason %s, got details %v", reason, st.Details())
+}
+
+func TestGRPC(t *testing.T) {
+	t.Parallel()
+	c, s := testGRPC(t)
+	ctx := context.Background()
+
+	questions, err := c.ListQuestions(ctx, &quizpb.ListQuestionsRequest{Category: "geography", Tags: []string{"capitals"}})
+	if err != nil || len(questions.GetQuestions()) != 2 {
+		t.Fatalf("ListQuestions() got: %v, %v", questions, err)
+	}
+
+	_, err = c.GetStatistics(ctx, &quizpb.GetStatisticsRequest{User: "user"})
+	assertStatus(t, err, codes.FailedPrecondition, quiz.CodeNotEnoughUsers)
+
+	if _, err := c.CreateUser(ctx, &quizpb.CreateUserRequest{User: "other"}); err != nil {
+		t.Fatalf("CreateUser() got: %v", err)
+	}
+	_, err = c.CreateUser(ctx, &quizpb.CreateUserRequest{User: "other"})
+	assertStatus(t, err, codes.AlreadyExists, quiz.CodeUserAlreadyExists)
+
+	_, err = c.SubmitAnswers(ctx, &quizpb.SubmitAnswersRequest{Answers: map[uint64]string{0: "Paris"}})
+	assertStatus(t, err, codes.InvalidArgument, quiz.CodeInvalidRequest)
+
+	// unknown questions are refused before any answer is scored
+	_, err = c.SubmitAnswers(ctx, &quizpb.SubmitAnswersRequest{User: "other", Answers: map[uint64]string{0: "Paris", 999: "x"}})
+	assertStatus(t, err, codes.NotFound, quiz.CodeQuestionNotFound)
+
+	if _, err := c.SubmitAnswers(ctx, &quizpb.SubmitAnswersRequest{User: "other", Answers: map[uint64]string{0: "Paris", 1: "Paris"}}); err != nil {
+		t.Fatalf("SubmitAnswers() got: %v", err)
+	}
+
+	results, err := c.GetResults(ctx, &quizpb.GetResultsRequest{User: "other"})
+	if err != nil || results.GetCorrect() != 1 || results.GetTotal() != 2 || len(results.GetCategories()) != 1 {
+		t.Fatalf("GetResults() got: %v, %v", results, err)
+	}
+	_, err = c.GetResults(ctx, &quizpb.GetResultsRequest{User: "other", Quiz: "unknown"})
+	assertStatus(t, err, codes.NotFound, quiz.CodeQuizNotFound)
+
+	statistics, err := c.GetStatistics(ctx, &quizpb.GetStatisticsRequest{User: "other"})
+	if err != nil || statistics.GetCorrect() != 1 || len(statistics.GetCategories()) == 0 {
+		t.Fatalf("GetStatistics() got: %v, %v", statistics, err)
+	}
+
+	// the HTTP routes share the listener and the store
+	resp, err := s.Client().Get(s.URL + "/v1/quiz/other")
+	if err != nil {
+		t.Fatalf("failed to get results: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the user created over gRPC, got status %d", resp.StatusCode)
+	}
+
+	// and the audit log, which records the mutations of gRPC like the ones of HTTP
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL+"/v1/admin/audit?action=putUser", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err = s.Client().Do(r)
+	if err != nil {
+		t.Fatalf("failed to get audit log: %v", err)
+	}
+	defer resp.Body.Close()
+	var events []quiz.AuditEvent
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		t.Fatalf("failed to decode audit log: %v", err)
+	}
+	// the latest first, the user already existing the second time
+	if len(events) != 2 || events[0].Outcome != quiz.AuditFailure || events[1].Outcome != quiz.AuditSuccess || events[1].Target != "user=other" || events[1].SourceIP != "127.0.0.1" {
+		t.Fatalf("expected the creations of other, got %+v", events)
+	}
+}
+
+func TestGRPCCodes(t *testing.T) {
+	t.Parallel()
+	for sentinel, code := range problemCodes {
+		if _, ok := grpcCodes[sentinel]; !ok {
+			t.Errorf("expected a gRPC code for %s", code)
+		}
+	}
+}
+
+func TestGRPCRequestID(t *testing.T) {
+	t.Parallel()
+	c, _ := testGRPC(t)
+
+	tests := []struct {
+		name string
+		id   string
+		want string
+	}{
+		{name: "propagated", id: "caller-id", want: "caller-id"},
+		{name: "generated", id: "", want: "123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.id != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", tt.id)
+			}
+
+			var header metadata.MD
+			_, err := c.GetResults(ctx, &quizpb.GetResultsRequest{User: "unknown"}, grpc.Header(&header))
+			assertStatus(t, err, codes.NotFound, quiz.CodeUserNotFound)
+
+			if got := header.Get("x-request-id"); len(got) != 1 || got[0] != tt.want {
+				t.Fatalf("expected request ID %s, got %v", tt.want, got)
+			}
+
+			st, _ := status.FromError(err)
+			if info := st.Details()[0].(*errdetails.ErrorInfo); info.GetMetadata()["request_id"] != tt.want {
+				t.Fatalf("expected request ID %s in the error, got %v", tt.want, info.GetMetadata())
+			}
+		})
+	}
+}
+
+func TestGRPCRecovery(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	info := &grpc.UnaryServerInfo{FullMethod: "/quiz.QuizService/SubmitAnswers"}
+
+	// the method panics under the interceptors of NewGRPCServer, the status is answered all the same
+	_, err := withGRPCStatus(handler.Slog)(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return withGRPCRecovery()(ctx, req, info, func(context.Context, any) (any, error) {
+			panic("index out of range")
+		})
+	})
+	assertStatus(t, err, codes.Internal, quiz.CodeInternal)
+}
@@ -1,19 +1,40 @@
 package server
 
 import (
+	"bufio"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/graphql-go/graphql"
 	"github.com/vrnvu/temp/pkg/quiz"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	headerContentType    = "Content-Type"
 	valueContentTypeJSON = "application/json"
 	headerXRequestID     = "X-Request-ID"
+	headerAuthorization  = "Authorization"
+)
+
+const (
+	queryMode       = "mode"
+	queryUser       = "user"
+	queryCategory   = "category"
+	queryTag        = "tag"
+	valueModeReview = "review"
 )
 
 type xRequestIDHeader string
@@ -21,9 +42,22 @@ type xRequestIDHeader string
 const xRequestIDHeaderKey xRequestIDHeader = headerXRequestID
 
 type Handler struct {
-	Slog *slog.Logger
-	Mux  *http.ServeMux
-	db   *InMemoryDB
+	Slog       *slog.Logger
+	Mux        *http.ServeMux
+	db         *InMemoryDB
+	adminToken string
+	rooms      *rooms
+	openapi    *openapi3.T
+	graphql    graphql.Schema
+	webhooks   webhookConfig
+	// responses of the requests sent with an Idempotency-Key
+	idempotency *idempotency
+	audit       *auditLog
+	metrics     *metrics
+	tracer      trace.Tracer
+	etagEpoch   string
+	// the gRPC server tags its requests like the HTTP routes
+	requestIDGenerator func() string
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -33,6 +67,18 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 type Config struct {
 	Slog               *slog.Logger
 	RequestIDGenerator func() string
+	// bearer token of the /admin routes, admin routes are disabled when empty
+	AdminToken string
+	// WebhookClient delivers the webhooks, defaults to a client with a 10s timeout
+	WebhookClient *http.Client
+	// WebhookBackoff is the wait before the first retry of a delivery, defaults to 1s
+	WebhookBackoff time.Duration
+	// IdempotencyTTL is how long the responses of an Idempotency-Key are replayed, defaults to 24h
+	IdempotencyTTL time.Duration
+	// AuditLog also gets every audit event as a line of JSON, like a RotatingFile
+	AuditLog io.Writer
+	// TracerProvider of the spans of the requests and of the store, defaults to the global one of otel
+	TracerProvider trace.TracerProvider
 }
 
 func FromConfig(c *Config) (*Handler, error) {
@@ -41,54 +87,132 @@ func FromConfig(c *Config) (*Handler, error) {
 		return nil, err
 	}
 
-	h := &Handler{Slog: c.Slog, Mux: http.NewServeMux(), db: db}
+	openapi, err := loadOpenAPI()
+	if err != nil {
+		return nil, err
+	}
 
-	h.Mux.HandleFunc("GET /health", withBaseMiddleware(h.Slog, c.RequestIDGenerator, health))
-	h.Mux.HandleFunc("GET /quiz", withBaseMiddleware(h.Slog, c.RequestIDGenerator, h.getQuiz))
-	h.Mux.HandleFunc("GET /quiz/{user}", withBaseMiddleware(h.Slog, c.RequestIDGenerator, h.getQuizResults))
-	h.Mux.HandleFunc("PUT /quiz/{user}", withBaseMiddleware(h.Slog, c.RequestIDGenerator, h.putQuizAnswers))
-	h.Mux.HandleFunc("PUT /users/{user}", withBaseMiddleware(h.Slog, c.RequestIDGenerator, h.putNewUser))
-	h.Mux.HandleFunc("GET /statistics/{user}", withBaseMiddleware(h.Slog, c.RequestIDGenerator, h.getStatistics))
+	schema, err := newGraphQLSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	tracerProvider := c.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+	db.tracer = tracerProvider.Tracer(tracerName)
+
+	h := &Handler{Slog: c.Slog, Mux: http.NewServeMux(), db: db, adminToken: c.AdminToken, rooms: newRooms(), openapi: openapi, graphql: schema, webhooks: newWebhookConfig(c), idempotency: newIdempotency(c.IdempotencyTTL), audit: newAuditLog(c.Slog, c.AuditLog), metrics: newMetrics(db), tracer: db.tracer, etagEpoch: newETagEpoch(), requestIDGenerator: c.RequestIDGenerator}
+
+	// probes and version discovery must not depend on a version
+	h.Mux.HandleFunc("GET /health", withBaseMiddleware(h.Slog, h.metrics, h.tracer, c.RequestIDGenerator, health))
+	h.Mux.HandleFunc("GET /versions", withBaseMiddleware(h.Slog, h.metrics, h.tracer, c.RequestIDGenerator, h.getVersions))
+	h.Mux.HandleFunc("GET /openapi.json", withBaseMiddleware(h.Slog, h.metrics, h.tracer, c.RequestIDGenerator, getOpenAPI))
+
+	h.handle(http.MethodGet, "/quiz", c.RequestIDGenerator, h.getQuiz)
+	h.handle(http.MethodGet, "/quiz/{user}", c.RequestIDGenerator, h.getQuizResults)
+	h.handle(http.MethodPut, "/quiz/{user}", c.RequestIDGenerator, h.putQuizAnswers)
+	h.handle(http.MethodPut, "/users/{user}", c.RequestIDGenerator, h.putNewUser)
+	h.handle(http.MethodGet, "/statistics/{user}", c.RequestIDGenerator, h.getStatistics)
+	h.handle(http.MethodPost, "/sessions/{user}", c.RequestIDGenerator, h.postSession)
+	h.handle(http.MethodGet, "/sessions/{user}/{session}", c.RequestIDGenerator, h.getSession)
+	h.handle(http.MethodPut, "/sessions/{user}/{session}", c.RequestIDGenerator, h.putSessionAnswers)
+	h.handle(http.MethodGet, "/quizzes", c.RequestIDGenerator, h.getQuizzes)
+	h.handle(http.MethodGet, "/quizzes/{slug}", c.RequestIDGenerator, h.getNamedQuiz)
+	h.handle(http.MethodPut, "/admin/quizzes/{slug}", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.putNamedQuiz))
+	h.handle(http.MethodDelete, "/admin/quizzes/{slug}", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.deleteNamedQuiz))
+	h.handle(http.MethodGet, "/groups", c.RequestIDGenerator, h.getGroups)
+	h.handle(http.MethodGet, "/groups/{group}", c.RequestIDGenerator, h.getGroup)
+	h.handle(http.MethodPut, "/groups/{group}", c.RequestIDGenerator, h.putGroup)
+	h.handle(http.MethodPut, "/groups/{group}/members/{user}", c.RequestIDGenerator, h.putGroupMember)
+	h.handle(http.MethodDelete, "/groups/{group}/members/{user}", c.RequestIDGenerator, h.deleteGroupMember)
+	h.handle(http.MethodGet, "/leaderboard", c.RequestIDGenerator, h.getLeaderboard)
+	h.handle(http.MethodGet, "/leaderboard/groups", c.RequestIDGenerator, h.getGroupLeaderboard)
+	h.handle(http.MethodGet, "/rooms", c.RequestIDGenerator, h.hostRoom)
+	h.handle(http.MethodGet, "/rooms/{code}", c.RequestIDGenerator, h.joinRoom)
+	h.handle(http.MethodPost, "/graphql", c.RequestIDGenerator, h.postGraphQL)
+	h.handle(http.MethodGet, "/admin/webhooks", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.getWebhooks))
+	h.handle(http.MethodPut, "/admin/webhooks/{slug}", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.putWebhook))
+	h.handle(http.MethodDelete, "/admin/webhooks/{slug}", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.deleteWebhook))
+	h.handle(http.MethodGet, "/admin/dead-letters", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.getDeadLetters))
+	h.handle(http.MethodPost, "/admin/dead-letters/{delivery}/redeliver", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.redeliver))
+	h.handle(http.MethodGet, "/admin/export", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.getExport))
+	h.handle(http.MethodPost, "/admin/import", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.postImport))
+	h.handle(http.MethodPut, "/admin/questions/{question}", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.putQuestion))
+	h.handle(http.MethodGet, "/admin/questions/{question}/versions", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.getQuestionVersions))
+	h.handle(http.MethodPost, "/admin/questions/{question}/regrade", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.postRegrade))
+	h.handle(http.MethodPost, "/admin/blobs", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.postBlob))
+	h.handle(http.MethodGet, "/blobs/{blob}", c.RequestIDGenerator, h.getBlob)
+	h.handle(http.MethodGet, "/admin/audit", c.RequestIDGenerator, withAdmin(h.Slog, c.AdminToken, h.getAuditLog))
 	return h, nil
 }
 
 func health(_ http.ResponseWriter, _ *http.Request) {}
 
 func (h *Handler) getQuiz(w http.ResponseWriter, r *http.Request) {
-	questions, err := h.db.GetQuestions(r.Context())
+	switch mode := r.URL.Query().Get(queryMode); mode {
+	case "":
+	case valueModeReview:
+		h.getQuizReview(w, r)
+		return
+	default:
+		err := &quiz.FieldError{Field: queryMode, Reason: fmt.Sprintf("`%s`, try: [%s]", mode, valueModeReview)}
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	questions, err := h.db.GetQuestions(r.Context(), fromQueryFilter(r))
 	if err != nil {
-		h.logError(r, http.StatusText(http.StatusInternalServerError), err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.writeError(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	h.writeJSON(w, r, questions)
+	h.writeData(w, r, localizeQuestions(w, r, questions))
+}
+
+func (h *Handler) getQuizReview(w http.ResponseWriter, r *http.Request) {
+	user, err := fromQueryUser(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	questions, err := h.db.GetDueQuestions(r.Context(), user, fromQueryFilter(r))
+	if err != nil {
+		switch err {
+		case ErrUserNotFound:
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, localizeQuestions(w, r, questions))
 }
 
 func (h *Handler) putQuizAnswers(w http.ResponseWriter, r *http.Request) {
 	user, err := fromPathUser(r)
 	if err != nil {
-		h.logError(r, http.StatusText(http.StatusBadRequest), err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
 	quizAnswer := quiz.QuizAnswer{}
 	if err := json.NewDecoder(r.Body).Decode(&quizAnswer); err != nil {
-		h.logError(r, http.StatusText(http.StatusBadRequest), err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.db.InsertQuizAnswer(r.Context(), user, quizAnswer); err != nil {
 		switch err {
-		case ErrUserNotFound:
-			h.logError(r, http.StatusText(http.StatusBadRequest), err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		case ErrUserNotFound, ErrQuestionNotFound:
+			h.writeError(w, r, http.StatusBadRequest, err)
 			return
 		default:
-			h.logError(r, http.StatusText(http.StatusInternalServerError), err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			h.writeError(w, r, http.StatusInternalServerError, err)
 			return
 		}
 	}
@@ -97,115 +221,198 @@ func (h *Handler) putQuizAnswers(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) getQuizResults(w http.ResponseWriter, r *http.Request) {
 	user, err := fromPathUser(r)
 	if err != nil {
-		h.logError(r, http.StatusText(http.StatusBadRequest), err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
+	if slug := r.URL.Query().Get(queryQuiz); slug != "" {
+		h.getNamedQuizResults(w, r, user, slug)
+		return
+	}
+
+	// unknown users are answered below
+	if version, err := h.db.UserVersion(r.Context(), user); err == nil {
+		h.setETag(w, r, version)
+	}
+
 	results, err := h.db.GetResults(r.Context(), user)
 	if err != nil {
 		switch err {
 		case ErrUserNotFound:
-			h.logError(r, http.StatusText(http.StatusBadRequest), err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.writeError(w, r, http.StatusBadRequest, err)
 			return
 		default:
-			h.logError(r, http.StatusText(http.StatusInternalServerError), err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			h.writeError(w, r, http.StatusInternalServerError, err)
 			return
 		}
 	}
 
-	h.writeJSON(w, r, results)
+	h.writeData(w, r, results)
 }
 
 func (h *Handler) putNewUser(w http.ResponseWriter, r *http.Request) {
 	user, err := fromPathUser(r)
 	if err != nil {
-		h.logError(r, http.StatusText(http.StatusBadRequest), err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	if err := h.db.InsertUser(r.Context(), user); err != nil {
+	token, err := h.db.InsertUser(r.Context(), user)
+	if err != nil {
 		switch err {
 		case ErrUserAlreadyExists:
-			h.logError(r, http.StatusText(http.StatusBadRequest), err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.writeError(w, r, http.StatusBadRequest, err)
 			return
 		default:
-			h.logError(r, http.StatusText(http.StatusInternalServerError), err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			h.writeError(w, r, http.StatusInternalServerError, err)
 			return
 		}
 	}
+
+	h.writeData(w, r, quiz.UserToken{User: user, Token: token})
 }
 
 func (h *Handler) getStatistics(w http.ResponseWriter, r *http.Request) {
 	user, err := fromPathUser(r)
 	if err != nil {
-		h.logError(r, http.StatusText(http.StatusBadRequest), err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	statistics, err := h.db.GetStatistics(r.Context(), user)
+	// statistics compare the user with everyone else. The version is read before the statistics so a
+	// change in between is a new ETag, the response is only tagged once they are found.
+	version := h.db.Version(r.Context())
+
+	if slug := r.URL.Query().Get(queryQuiz); slug != "" {
+		h.getNamedQuizStatistics(w, r, user, slug, version)
+		return
+	}
+
+	statistics, err := h.db.GetStatistics(r.Context(), user, r.URL.Query().Get(queryGroup))
 	if err != nil {
 		switch err {
 		case ErrUserNotFound:
-			h.logError(r, http.StatusText(http.StatusBadRequest), err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.writeError(w, r, http.StatusBadRequest, err)
 			return
 		case ErrNotEnoughUsersForStatistics:
-			h.logError(r, http.StatusText(http.StatusBadRequest), err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		case ErrGroupNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
 			return
 		default:
-			h.logError(r, http.StatusText(http.StatusInternalServerError), err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			h.writeError(w, r, http.StatusInternalServerError, err)
 			return
 		}
 	}
 
-	h.writeJSON(w, r, statistics)
+	h.setETag(w, r, version)
+	h.writeData(w, r, statistics)
 }
 
-func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, data any) {
-	w.Header().Set(headerContentType, valueContentTypeJSON)
+// writeData answers data in the media type withNegotiation picked, JSON by default
+func (h *Handler) writeData(w http.ResponseWriter, r *http.Request, data any) {
+	mediaType := mediaTypeOf(r)
+	w.Header().Set(headerContentType, contentType(mediaType))
 	w.Header().Set(headerXRequestID, fromContext(r, xRequestIDHeaderKey))
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		h.logError(r, http.StatusText(http.StatusInternalServerError), err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	// the handler tagged the response with setETag
+	if notModified(r, w.Header().Get(headerETag)) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	b, err := encode(mediaType, data)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, err)
 		return
 	}
+	w.Write(b)
 }
 
 func (h *Handler) logError(r *http.Request, message string, err error) {
-	h.Slog.Error(message, "error", err, "method", r.Method, "path", r.URL.Path, headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+	h.Slog.ErrorContext(r.Context(), message, "error", err, "method", r.Method, "path", r.URL.Path, headerXRequestID, fromContext(r, xRequestIDHeaderKey))
 }
 
 func fromContext(r *http.Request, key any) string {
 	return r.Context().Value(key).(string)
 }
 
+// requestIDRegexp limits the request IDs callers propagate, they end up in the logs as they are
+var requestIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// withRequestID keeps the `X-Request-ID` of the caller so a request can be followed across services,
+// requests without one, or with an invalid one, get a generated ID
 func withRequestID(requestIDGenerator func() string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		id := requestIDGenerator()
+		id := r.Header.Get(headerXRequestID)
+		if !requestIDRegexp.MatchString(id) {
+			id = requestIDGenerator()
+		}
 		ctx := context.WithValue(r.Context(), xRequestIDHeaderKey, id)
+		// the client gets the ID of every response, not only of the problems
+		w.Header().Set(headerXRequestID, id)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
 func withLoggingMethod(slog *slog.Logger, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		slog.Info("", "method", r.Method, "path", r.URL.Path, headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+		slog.InfoContext(r.Context(), "", "method", r.Method, "path", r.URL.Path, headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+		next.ServeHTTP(w, r)
+	}
+}
+
+// statusWriter keeps the status of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+// Hijack lets the live rooms take the connection over, once it switched protocols
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(sw.ResponseWriter).Hijack()
+	if err == nil && sw.status == 0 {
+		sw.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+var errAdminToken = errors.New("missing or invalid admin token")
+
+// withAdmin only lets through requests with `Authorization: Bearer <token>`
+func withAdmin(slog *slog.Logger, token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get(headerAuthorization), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			slog.WarnContext(r.Context(), "unauthorized admin request", "method", r.Method, "path", r.URL.Path, headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+			writeProblem(w, r, newProblem(r, http.StatusUnauthorized, errAdminToken))
+			return
+		}
 		next.ServeHTTP(w, r)
 	}
 }
 
-func withBaseMiddleware(slog *slog.Logger, requestIDGenerator func() string, next http.HandlerFunc) http.HandlerFunc {
-	return withRequestID(requestIDGenerator, withLoggingMethod(slog, next))
+func withBaseMiddleware(slog *slog.Logger, m *metrics, tracer trace.Tracer, requestIDGenerator func() string, next http.HandlerFunc) http.HandlerFunc {
+	return withRequestID(requestIDGenerator, withTracing(tracer, withMetrics(m, withLoggingMethod(slog, withCompression(next)))))
 }
 
 func assertHeaderValueIs(r *http.Request, header string, value string) error {
@@ -218,7 +425,34 @@ func assertHeaderValueIs(r *http.Request, header string, value string) error {
 func fromPathUser(r *http.Request) (string, error) {
 	rawUser := r.PathValue("user")
 	if rawUser == "" {
-		return "", fmt.Errorf("invalid user: `%s`", rawUser)
+		return "", &quiz.FieldError{Field: queryUser, Reason: fmt.Sprintf("`%s`", rawUser)}
+	}
+	return rawUser, nil
+}
+
+// fromAuthUser reads the user from `Authorization: Bearer <token>`, the token given to the user
+// when it was created. The admin token acts as any user and is returned as an empty user.
+func (h *Handler) fromAuthUser(r *http.Request) (string, error) {
+	token, ok := strings.CutPrefix(r.Header.Get(headerAuthorization), "Bearer ")
+	if !ok || token == "" {
+		return "", fmt.Errorf("missing `%s: Bearer <token>` header", headerAuthorization)
+	}
+	if h.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1 {
+		return "", nil
+	}
+	return h.db.AuthenticateUser(r.Context(), token)
+}
+
+func fromQueryUser(r *http.Request) (string, error) {
+	rawUser := r.URL.Query().Get(queryUser)
+	if rawUser == "" {
+		return "", &quiz.FieldError{Field: queryUser, Reason: fmt.Sprintf("`%s`", rawUser)}
 	}
 	return rawUser, nil
 }
+
+// fromQueryFilter reads `?category=geography&tag=capitals&tag=europe`
+func fromQueryFilter(r *http.Request) QuestionFilter {
+	query := r.URL.Query()
+	return QuestionFilter{Category: query.Get(queryCategory), Tags: query[queryTag]}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	headerContentDisposition = "Content-Disposition"
+	queryDryRun              = "dry_run"
+	// archiveLineMaxSize bounds a record, the import only holds one at a time
+	archiveLineMaxSize = 1 << 20
+)
+
+// getExport streams the archive, one record per line
+func (h *Handler) getExport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(headerContentType, quiz.ContentTypeNDJSON)
+	w.Header().Set(headerContentDisposition, `attachment; filename="quiz.ndjson"`)
+	w.Header().Set(headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+
+	enc := json.NewEncoder(w)
+	err := h.db.ExportArchive(r.Context(), func(record quiz.ArchiveRecord) error {
+		return enc.Encode(record)
+	})
+	if err != nil {
+		// the status is sent already, the caller gets a truncated archive
+		h.logError(r, "export interrupted", err)
+	}
+}
+
+// postImport reads the archive one line at a time and answers what it changed, the first invalid
+// or conflicting record stops the import
+func (h *Handler) postImport(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get(queryMode)
+	if mode == "" {
+		mode = quiz.ImportFail
+	}
+	archive := h.db.newArchiveImport(mode, r.URL.Query().Get(queryDryRun) == "true")
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, archiveLineMaxSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var record quiz.ArchiveRecord
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		} else {
+			err = archive.importRecord(record)
+		}
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrImportConflict) {
+				status = http.StatusConflict
+			}
+			h.writeError(w, r, status, fmt.Errorf("line %d: %w", line, err))
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidArchive, err))
+		return
+	}
+	if archive.summary.Version == 0 {
+		h.writeError(w, r, http.StatusBadRequest, fmt.Errorf("%w: no records", ErrInvalidArchive))
+		return
+	}
+
+	h.writeData(w, r, archive.summary)
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// testArchive adds a question, a user with results and an attempt to the ones of testHandler
+const testArchive = `{"kind": "archive", "archive": {"version": 1, "exported_at": "2024-01-01T00:00:00Z"}}
+
+{"kind": "question", "question": {"id": 5, "text": "What is 3 + 3?", "options": ["5", "6"], "answer": "6", "category": "arithmetic"}}
+{"kind": "user", "user": {"name": "imported"}}
+{"kind": "result", "result": {"user": "imported", "correct": 1, "total": 1, "categories": [{"category": "arithmetic", "correct": 1, "total": 1}], "reviews": []}}
+{"kind": "attempt", "attempt": {"user": "imported", "started_at": "2024-01-01T00:00:00Z", "question_ids": [5], "answers": [{"question_id": 5, "submitted_at": "2024-01-01T00:00:05Z", "time_taken_ms": 5000, "late": false, "correct": true}]}}
+`
+
+func TestHandlerArchive(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	adminRequest := func(method string, url string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		r.Header.Set("Authorization", "Bearer "+testAdminToken)
+		r.Header.Set(headerContentType, quiz.ContentTypeNDJSON)
+
+		w := httptest.NewRecorder()
+		handler.Mux.ServeHTTP(w, r)
+		return w
+	}
+
+	importArchive := func(url string, body string) quiz.ImportSummary {
+		t.Helper()
+		w := adminRequest(http.MethodPost, url, body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var summary quiz.ImportSummary
+		if err := json.NewDecoder(w.Body).Decode(&summary); err != nil {
+			t.Fatalf("failed to decode summary: %v", err)
+		}
+		return summary
+	}
+
+	summary := importArchive("/v1/admin/import?dry_run=true", testArchive)
+	if summary.Records != 5 || summary.Questions.Created != 1 || summary.Attempts.Created != 1 || !summary.DryRun {
+		t.Fatalf("expected every record created in the dry run, got %+v", summary)
+	}
+	if w := adminRequest(http.MethodGet, "/v1/quiz/imported", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected the dry run to import nothing, got %d", w.Code)
+	}
+
+	summary = importArchive("/v1/admin/import", testArchive)
+	if summary.Mode != quiz.ImportFail || summary.Users.Created != 1 || summary.Results.Created != 1 {
+		t.Fatalf("expected every record created, got %+v", summary)
+	}
+	summary = importArchive("/v1/admin/import?mode=skip", testArchive)
+	if summary.Questions.Skipped != 1 || summary.Users.Skipped != 1 || summary.Results.Skipped != 1 || summary.Attempts.Skipped != 1 {
+		t.Fatalf("expected every record skipped, got %+v", summary)
+	}
+
+	w := adminRequest(http.MethodGet, "/v1/admin/export", "")
+	if w.Code != http.StatusOK || w.Header().Get(headerContentType) != quiz.ContentTypeNDJSON {
+		t.Fatalf("expected an archive, got %d with headers %v", w.Code, w.Header())
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	// header, 6 questions, 2 users, the results of imported and its attempt
+	if len(lines) != 11 || !strings.Contains(lines[6], `"answer":"6"`) {
+		t.Fatalf("expected the archive with the imported records, got:\n%s", w.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		body       string
+		statusCode int
+		code       string
+		detail     string
+	}{
+		{name: "conflict", url: "/v1/admin/import", body: testArchive, statusCode: http.StatusConflict, code: quiz.CodeImportConflict, detail: "line 3"},
+		{name: "malformed", url: "/v1/admin/import", body: `{"kind": "archive", "archive": {"version": 1}}` + "\n{", statusCode: http.StatusBadRequest, code: quiz.CodeInvalidArchive, detail: "line 2"},
+		{name: "newer version", url: "/v1/admin/import", body: `{"kind": "archive", "archive": {"version": 5}}`, statusCode: http.StatusBadRequest, code: quiz.CodeInvalidArchive, detail: "line 1"},
+		{name: "empty", url: "/v1/admin/import", body: "\n", statusCode: http.StatusBadRequest, code: quiz.CodeInvalidArchive},
+		{name: "unknown mode", url: "/v1/admin/import?mode=merge", body: testArchive, statusCode: http.StatusBadRequest, code: quiz.CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := adminRequest(http.MethodPost, tt.url, tt.body)
+			if w.Code != tt.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.statusCode, w.Code, w.Body.String())
+			}
+
+			var p quiz.Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatalf("failed to decode problem: %v", err)
+			}
+			if p.Code != tt.code || !strings.HasPrefix(p.Detail, tt.detail) {
+				t.Fatalf("expected code %s and detail %q, got %+v", tt.code, tt.detail, p)
+			}
+		})
+	}
+}
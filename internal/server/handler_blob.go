@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	headerXContentTypeOptions = "X-Content-Type-Options"
+	// blobs never change, caches keep them without asking again
+	valueCacheImmutable = "public, max-age=31536000, immutable"
+)
+
+var ErrBlobTooLarge = errors.New("blob too large")
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// postBlob stores the image of the body, its media type is sniffed from the content rather than
+// taken from the `Content-Type`, so a blob is never served as something else
+func (h *Handler) postBlob(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, quiz.MaxBlobSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeError(w, r, http.StatusRequestEntityTooLarge, fmt.Errorf("%w: the limit is %d bytes", ErrBlobTooLarge, quiz.MaxBlobSize))
+			return
+		}
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	mediaType := http.DetectContentType(data)
+	if !quiz.IsBlobMediaType(mediaType) {
+		h.writeError(w, r, http.StatusUnsupportedMediaType, fmt.Errorf("%w: `%s`, try: %v", ErrUnsupportedMediaType, mediaType, quiz.BlobMediaTypes))
+		return
+	}
+
+	blob, err := h.db.PutBlob(r.Context(), mediaType, data)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeData(w, r, blob)
+}
+
+// getBlob serves the blob as it was stored, its ID is its ETag since the content never changes
+func (h *Handler) getBlob(w http.ResponseWriter, r *http.Request) {
+	blob, data, err := h.db.GetBlob(r.Context(), r.PathValue("blob"))
+	if err != nil {
+		switch err {
+		case ErrBlobNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	etag := fmt.Sprintf(`"%s"`, blob.ID)
+	w.Header().Set(headerContentType, blob.MediaType)
+	w.Header().Set(headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+	w.Header().Set(headerXContentTypeOptions, "nosniff")
+	w.Header().Set(headerETag, etag)
+	w.Header().Set(headerCacheControl, valueCacheImmutable)
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(data)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// testPNG is sniffed as a PNG, only its signature is checked
+const testPNG = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestHandlerBlobs(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	request := func(method string, url string, header http.Header, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		r.Header = header
+
+		w := httptest.NewRecorder()
+		handler.Mux.ServeHTTP(w, r)
+		return w
+	}
+	admin := http.Header{"Authorization": {"Bearer " + testAdminToken}, headerContentType: {"text/plain"}}
+
+	var blob quiz.Blob
+	for range 2 {
+		w := request(http.MethodPost, "/v1/admin/blobs", admin, testPNG)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if err := json.NewDecoder(w.Body).Decode(&blob); err != nil {
+			t.Fatalf("failed to decode blob: %v", err)
+		}
+		want := quiz.Blob{ID: quiz.BlobID([]byte(testPNG)), MediaType: "image/png", Size: int64(len(testPNG))}
+		if blob != want {
+			t.Fatalf("expected the same blob for the same content %+v, got %+v", want, blob)
+		}
+	}
+
+	if w := request(http.MethodPost, "/v1/admin/blobs", http.Header{}, testPNG); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected uploads to need the admin token, got %d", w.Code)
+	}
+
+	w := request(http.MethodGet, "/v1/blobs/"+blob.ID, http.Header{headerAcceptEncoding: {"gzip"}}, "")
+	if w.Code != http.StatusOK || w.Body.String() != testPNG {
+		t.Fatalf("expected the image back, got %d: %q", w.Code, w.Body.String())
+	}
+	for header, want := range map[string]string{
+		headerContentType:         "image/png",
+		headerETag:                `"` + blob.ID + `"`,
+		headerCacheControl:        valueCacheImmutable,
+		headerXContentTypeOptions: "nosniff",
+		headerContentEncoding:     "",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Fatalf("expected %s %q, got %q", header, want, got)
+		}
+	}
+
+	// questions attach blobs of the archive or of the server
+	archive := func(attachment string) string {
+		return fmt.Sprintf(`{"kind": "archive", "archive": {"version": 2}}
+{"kind": "blob", "blob": {"id": "%s", "media_type": "image/gif", "data": "R0lGODlh"}}
+{"kind": "question", "question": {"id": 5, "text": "Which one?", "options": ["a", "b"], "answer": "a", "attachments": ["%s", "%s"]}}
+`, quiz.BlobID([]byte("GIF89a")), quiz.BlobID([]byte("GIF89a")), attachment)
+	}
+	admin.Set(headerContentType, quiz.ContentTypeNDJSON)
+	if w := request(http.MethodPost, "/v1/admin/import?dry_run=true", admin, archive(quiz.BlobID(nil))); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "line 3") {
+		t.Fatalf("expected a question attaching a missing blob to fail, got %d: %s", w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/v1/admin/import", admin, archive(blob.ID))
+	var summary quiz.ImportSummary
+	if err := json.NewDecoder(w.Body).Decode(&summary); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected the archive imported, got %d: %v", w.Code, err)
+	}
+	if summary.Blobs.Created != 1 || summary.Questions.Created != 1 {
+		t.Fatalf("expected the blob and question created, got %+v", summary)
+	}
+
+	w = request(http.MethodGet, "/v1/admin/export", admin, "")
+	if !strings.Contains(w.Body.String(), `"kind":"blob"`) || !strings.Contains(w.Body.String(), blob.ID) {
+		t.Fatalf("expected the blobs in the export, got:\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	queryGroup     = "group"
+	queryRole      = "role"
+	valueRoleOwner = "owner"
+)
+
+func (h *Handler) getGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.db.GetGroups(r.Context())
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeData(w, r, groups)
+}
+
+func (h *Handler) getGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := h.db.GetGroup(r.Context(), r.PathValue("group"))
+	if err != nil {
+		switch err {
+		case ErrGroupNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, group)
+}
+
+// putGroup creates the group owned by the authenticated user
+func (h *Handler) putGroup(w http.ResponseWriter, r *http.Request) {
+	owner, err := h.fromAuthUser(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	group := quiz.Group{}
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil && err != io.EOF {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	group.Slug = r.PathValue("group")
+
+	if err := group.Validate(); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	group, err = h.db.InsertGroup(r.Context(), group, owner)
+	if err != nil {
+		switch err {
+		case ErrUserNotFound, ErrGroupAlreadyExists:
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, group)
+}
+
+// putGroupMember adds a member, `?role=owner` makes the member an owner too
+func (h *Handler) putGroupMember(w http.ResponseWriter, r *http.Request) {
+	actor, err := h.fromAuthUser(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	user, err := fromPathUser(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	owner := r.URL.Query().Get(queryRole) == valueRoleOwner
+	group, err := h.db.PutGroupMember(r.Context(), r.PathValue("group"), actor, user, owner)
+	if err != nil {
+		h.writeGroupMemberError(w, r, err)
+		return
+	}
+
+	h.writeData(w, r, group)
+}
+
+func (h *Handler) deleteGroupMember(w http.ResponseWriter, r *http.Request) {
+	actor, err := h.fromAuthUser(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	user, err := fromPathUser(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	group, err := h.db.DeleteGroupMember(r.Context(), r.PathValue("group"), actor, user)
+	if err != nil {
+		h.writeGroupMemberError(w, r, err)
+		return
+	}
+
+	h.writeData(w, r, group)
+}
+
+func (h *Handler) writeGroupMemberError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case ErrUserNotFound, ErrLastGroupOwner:
+		h.writeError(w, r, http.StatusBadRequest, err)
+	case ErrNotGroupOwner:
+		h.writeError(w, r, http.StatusForbidden, err)
+	case ErrGroupNotFound:
+		h.writeError(w, r, http.StatusNotFound, err)
+	default:
+		h.writeError(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// getLeaderboard ranks every user, or only the members with `?group=`
+func (h *Handler) getLeaderboard(w http.ResponseWriter, r *http.Request) {
+	leaderboard, err := h.db.GetLeaderboard(r.Context(), r.URL.Query().Get(queryGroup))
+	if err != nil {
+		switch err {
+		case ErrGroupNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, leaderboard)
+}
+
+func (h *Handler) getGroupLeaderboard(w http.ResponseWriter, r *http.Request) {
+	statistics, err := h.db.GetGroupStatistics(r.Context())
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeData(w, r, statistics)
+}
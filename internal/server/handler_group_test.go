@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerGroups(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	other, err := handler.db.InsertUser(context.Background(), "other")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	// the token of every user is its own, a user name is not a token
+	tokens := map[string]string{"user": testUserToken(t, handler.db, "user"), "other": other, testAdminToken: testAdminToken}
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		token      string
+		body       string
+		statusCode int
+	}{
+		{name: "create unauthenticated", method: http.MethodPut, url: "/groups/team-a", body: `{"kind": "team"}`, statusCode: http.StatusUnauthorized},
+		{name: "create with a user name", method: http.MethodPut, url: "/groups/team-a", token: "Bearer user", body: `{"kind": "team"}`, statusCode: http.StatusUnauthorized},
+		{name: "create invalid kind", method: http.MethodPut, url: "/groups/team-a", token: "user", body: `{"kind": "guild"}`, statusCode: http.StatusBadRequest},
+		{name: "create", method: http.MethodPut, url: "/groups/team-a", token: "user", body: `{"kind": "team"}`, statusCode: http.StatusOK},
+		{name: "create again", method: http.MethodPut, url: "/groups/team-a", token: "other", body: `{"kind": "team"}`, statusCode: http.StatusBadRequest},
+		{name: "add member not owner", method: http.MethodPut, url: "/groups/team-a/members/other", token: "other", statusCode: http.StatusForbidden},
+		{name: "add unknown member", method: http.MethodPut, url: "/groups/team-a/members/unknown", token: "user", statusCode: http.StatusBadRequest},
+		{name: "add member", method: http.MethodPut, url: "/groups/team-a/members/other", token: "user", statusCode: http.StatusOK},
+		{name: "add member as admin", method: http.MethodPut, url: "/groups/team-a/members/other?role=owner", token: testAdminToken, statusCode: http.StatusOK},
+		{name: "get", method: http.MethodGet, url: "/groups/team-a", statusCode: http.StatusOK},
+		{name: "get not found", method: http.MethodGet, url: "/groups/unknown", statusCode: http.StatusNotFound},
+		{name: "list", method: http.MethodGet, url: "/groups", statusCode: http.StatusOK},
+		{name: "statistics", method: http.MethodGet, url: "/statistics/user?group=team-a", statusCode: http.StatusOK},
+		{name: "statistics group not found", method: http.MethodGet, url: "/statistics/user?group=unknown", statusCode: http.StatusNotFound},
+		{name: "leaderboard", method: http.MethodGet, url: "/leaderboard?group=team-a", statusCode: http.StatusOK},
+		{name: "leaderboard group not found", method: http.MethodGet, url: "/leaderboard?group=unknown", statusCode: http.StatusNotFound},
+		{name: "groups leaderboard", method: http.MethodGet, url: "/leaderboard/groups", statusCode: http.StatusOK},
+		{name: "remove member", method: http.MethodDelete, url: "/groups/team-a/members/user", token: "other", statusCode: http.StatusOK},
+		{name: "remove last owner", method: http.MethodDelete, url: "/groups/team-a/members/other", token: "other", statusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if token, ok := tokens[tt.token]; ok {
+				r.Header.Set("Authorization", "Bearer "+token)
+			} else if tt.token != "" {
+				r.Header.Set("Authorization", tt.token)
+			}
+
+			w := httptest.NewRecorder()
+			handler.Mux.ServeHTTP(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.statusCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/groups/team-a", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	var group quiz.Group
+	err = json.Unmarshal(w.Body.Bytes(), &group)
+	if err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+
+	if group.IsMember("user") || !group.IsOwner("other") {
+		t.Fatalf("expected other to be the only owner, got %+v", group)
+	}
+}
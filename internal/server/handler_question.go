@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// getQuestionVersions is the trail of the edits of a question, with the answer of every version
+func (h *Handler) getQuestionVersions(w http.ResponseWriter, r *http.Request) {
+	questionID, ok := h.questionID(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.db.GetQuestionVersions(r.Context(), questionID)
+	if err != nil {
+		switch err {
+		case ErrQuestionNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, versions)
+}
+
+// putQuestion edits a question into its next version, like a fix of its answer that postRegrade
+// then scores the answers of the older versions against
+func (h *Handler) putQuestion(w http.ResponseWriter, r *http.Request) {
+	questionID, ok := h.questionID(w, r)
+	if !ok {
+		return
+	}
+
+	q := quiz.ArchiveQuestion{}
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if q.ID != questionID {
+		err := &quiz.FieldError{Field: "id", Reason: fmt.Sprintf("body `%d` does not match path `%d`", q.ID, questionID)}
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err := (quiz.ArchiveRecord{Kind: quiz.RecordQuestion, Question: &q}).Validate(); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := h.db.PutQuestion(r.Context(), q.Question())
+	if err != nil {
+		switch err {
+		case ErrQuestionNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		case ErrBlobNotFound:
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, version)
+}
+
+// postRegrade scores the answers of a question again after its answer was fixed, and answers how
+// the results changed
+func (h *Handler) postRegrade(w http.ResponseWriter, r *http.Request) {
+	questionID, ok := h.questionID(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.db.Regrade(r.Context(), questionID, r.URL.Query().Get(queryDryRun) == "true")
+	if err != nil {
+		switch err {
+		case ErrQuestionNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, report)
+}
+
+func (h *Handler) questionID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	rawQuestion := r.PathValue("question")
+	questionID, err := strconv.ParseUint(rawQuestion, 10, 64)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, &quiz.FieldError{Field: "question", Reason: fmt.Sprintf("`%s`", rawQuestion)})
+		return 0, false
+	}
+	return questionID, true
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerRegradeQuizAnswers(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	ctx := context.Background()
+
+	request := func(method string, url string, token string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		w := httptest.NewRecorder()
+		handler.Mux.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, url, http.StatusOK, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	// the key of "What is 2 + 2?" is 4, the user answers 3 outside of a session
+	id := uint64(slices.IndexFunc(handler.db.questions, func(q quiz.Question) bool { return q.Text == "What is 2 + 2?" }))
+	request(http.MethodPut, "/v1/quiz/user", "", fmt.Sprintf(`{"%d": "3"}`, id))
+
+	// the fix rewords the option too, the answer is regraded by the index it recorded
+	fixed := quiz.NewArchiveQuestion(handler.db.questions[id])
+	fixed.Options = []string{"1", "2", "three", "4"}
+	fixed.Answer = "three"
+	body, err := json.Marshal(fixed)
+	if err != nil {
+		t.Fatalf("failed to marshal question: %v", err)
+	}
+	var version quiz.QuestionVersion
+	if err := json.Unmarshal(request(http.MethodPut, fmt.Sprintf("/v1/admin/questions/%d", id), testAdminToken, string(body)).Body.Bytes(), &version); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	if version.Version != 2 || version.Question.Answer != "three" {
+		t.Fatalf("expected the fix as version 2, got %+v", version)
+	}
+
+	w := request(http.MethodPost, fmt.Sprintf("/v1/admin/questions/%d/regrade", id), testAdminToken, "")
+	var report quiz.RegradeReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	want := quiz.RegradeReport{QuestionID: id, Version: 2, Answers: 1, Users: []quiz.RegradeUser{
+		{User: "user", CorrectBefore: 0, CorrectAfter: 1, Total: 1, Sessions: []uint64{}},
+	}}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v", want, report)
+	}
+
+	var results quiz.QuizResults
+	if err := json.Unmarshal(request(http.MethodGet, "/v1/quiz/user", "", "").Body.Bytes(), &results); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	if results.Correct != 1 || results.Total != 1 {
+		t.Fatalf("expected the answer scored right, got %+v", results)
+	}
+}
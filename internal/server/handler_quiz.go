@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const queryQuiz = "quiz"
+
+func (h *Handler) getQuizzes(w http.ResponseWriter, r *http.Request) {
+	quizzes, err := h.db.GetQuizzes(r.Context())
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeData(w, r, quizzes)
+}
+
+func (h *Handler) getNamedQuiz(w http.ResponseWriter, r *http.Request) {
+	q, err := h.db.GetQuiz(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		switch err {
+		case ErrQuizNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, q)
+}
+
+func (h *Handler) putNamedQuiz(w http.ResponseWriter, r *http.Request) {
+	q := quiz.Quiz{}
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	slug := r.PathValue("slug")
+	if q.Slug == "" {
+		q.Slug = slug
+	}
+	if q.Slug != slug {
+		err := &quiz.FieldError{Field: "slug", Reason: fmt.Sprintf("body `%s` does not match path `%s`", q.Slug, slug)}
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := q.Validate(); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.db.PutQuiz(r.Context(), q); err != nil {
+		switch err {
+		case ErrQuestionNotFound:
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, q)
+}
+
+func (h *Handler) deleteNamedQuiz(w http.ResponseWriter, r *http.Request) {
+	if err := h.db.DeleteQuiz(r.Context(), r.PathValue("slug")); err != nil {
+		switch err {
+		case ErrQuizNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+}
+
+func (h *Handler) startNamedQuiz(w http.ResponseWriter, r *http.Request, user string, slug string) {
+	session, err := h.db.StartQuiz(r.Context(), user, slug)
+	if err != nil {
+		switch err {
+		case ErrUserNotFound:
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		case ErrQuizNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		case ErrQuizClosed, ErrMaxAttemptsReached:
+			h.writeError(w, r, http.StatusForbidden, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, localizeSession(w, r, session))
+}
+
+func (h *Handler) getNamedQuizResults(w http.ResponseWriter, r *http.Request, user string, slug string) {
+	// the attempts depend on the definition of the quiz too, see getStatistics
+	version := h.db.Version(r.Context())
+
+	results, err := h.db.GetQuizResults(r.Context(), user, slug)
+	if err != nil {
+		switch err {
+		case ErrUserNotFound:
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		case ErrQuizNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.setETag(w, r, version)
+	h.writeData(w, r, results)
+}
+
+func (h *Handler) getNamedQuizStatistics(w http.ResponseWriter, r *http.Request, user string, slug string, version uint64) {
+	statistics, err := h.db.GetQuizStatistics(r.Context(), user, slug, r.URL.Query().Get(queryGroup))
+	if err != nil {
+		switch err {
+		case ErrUserNotFound, ErrNotEnoughUsersForStatistics:
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		case ErrQuizNotFound, ErrGroupNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.setETag(w, r, version)
+	h.writeData(w, r, statistics)
+}
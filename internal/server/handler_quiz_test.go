@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerAdminQuizzes(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		token      string
+		body       string
+		statusCode int
+	}{
+		{name: "no token", method: http.MethodPut, url: "/admin/quizzes/capitals", body: `{}`, statusCode: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodPut, url: "/admin/quizzes/capitals", token: "user", body: `{}`, statusCode: http.StatusUnauthorized},
+		{name: "invalid slug", method: http.MethodPut, url: "/admin/quizzes/Capitals", token: testAdminToken, body: `{}`, statusCode: http.StatusBadRequest},
+		{name: "slug mismatch", method: http.MethodPut, url: "/admin/quizzes/capitals", token: testAdminToken, body: `{"slug": "other"}`, statusCode: http.StatusBadRequest},
+		{name: "unknown question", method: http.MethodPut, url: "/admin/quizzes/capitals", token: testAdminToken, body: `{"question_ids": [99]}`, statusCode: http.StatusBadRequest},
+		{name: "put", method: http.MethodPut, url: "/admin/quizzes/capitals", token: testAdminToken, body: `{"title": "Capitals", "question_ids": [0, 1], "pass_mark": 0.5, "max_attempts": 1}`, statusCode: http.StatusOK},
+		{name: "get", method: http.MethodGet, url: "/quizzes/capitals", statusCode: http.StatusOK},
+		{name: "get not found", method: http.MethodGet, url: "/quizzes/unknown", statusCode: http.StatusNotFound},
+		{name: "start", method: http.MethodPost, url: "/sessions/user?quiz=capitals", statusCode: http.StatusOK},
+		{name: "start max attempts", method: http.MethodPost, url: "/sessions/user?quiz=capitals", statusCode: http.StatusForbidden},
+		{name: "start not found", method: http.MethodPost, url: "/sessions/user?quiz=unknown", statusCode: http.StatusNotFound},
+		{name: "results", method: http.MethodGet, url: "/quiz/user?quiz=capitals", statusCode: http.StatusOK},
+		{name: "results not found", method: http.MethodGet, url: "/quiz/user?quiz=unknown", statusCode: http.StatusNotFound},
+		{name: "statistics not enough users", method: http.MethodGet, url: "/statistics/user?quiz=capitals", statusCode: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, url: "/admin/quizzes/capitals", token: testAdminToken, statusCode: http.StatusOK},
+		{name: "delete not found", method: http.MethodDelete, url: "/admin/quizzes/capitals", token: testAdminToken, statusCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			w := httptest.NewRecorder()
+			handler.Mux.ServeHTTP(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.statusCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerQuizzes(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodPut, "/admin/quizzes/arithmetic", strings.NewReader(`{"category": "arithmetic", "sample": 3}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+testAdminToken)
+
+	w := httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	r, err = http.NewRequestWithContext(context.Background(), http.MethodGet, "/quizzes", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	var quizzes []quiz.Quiz
+	err = json.Unmarshal(w.Body.Bytes(), &quizzes)
+	if err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+
+	if len(quizzes) != 1 || quizzes[0].Slug != "arithmetic" {
+		t.Fatalf("expected quiz arithmetic, got %+v", quizzes)
+	}
+
+	r, err = http.NewRequestWithContext(context.Background(), http.MethodPost, "/sessions/user?quiz=arithmetic", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	var session quiz.Session
+	err = json.Unmarshal(w.Body.Bytes(), &session)
+	if err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+
+	if len(session.Questions) != 3 {
+		t.Fatalf("expected 3 sampled questions, got %d", len(session.Questions))
+	}
+
+	for _, q := range session.Questions {
+		if q.Category != "arithmetic" {
+			t.Fatalf("expected arithmetic questions, got %s", q.Category)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+var roomUpgrader = websocket.Upgrader{
+	Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+		writeProblem(w, r, newProblem(r, status, reason))
+	},
+}
+
+// hostRoom opens a live room of a named quiz, the host then starts it over the websocket
+func (h *Handler) hostRoom(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get(queryQuiz)
+	if slug == "" {
+		err := &quiz.FieldError{Field: queryQuiz, Reason: fmt.Sprintf("`%s`", slug)}
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	limits, err := fromQueryLimits(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	q, questions, err := h.db.GetQuizQuestions(r.Context(), slug)
+	if err != nil {
+		switch err {
+		case ErrQuizNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		case ErrQuizClosed:
+			h.writeError(w, r, http.StatusForbidden, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	timeLimit := limits.QuestionTimeLimit
+	if timeLimit == 0 {
+		timeLimit = time.Duration(q.QuestionTimeLimitMs) * time.Millisecond
+	}
+	if timeLimit == 0 {
+		timeLimit = roomQuestionTimeLimit
+	}
+
+	// the upgrader already replied on errors
+	conn, err := roomUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logError(r, http.StatusText(http.StatusBadRequest), err)
+		return
+	}
+
+	room := h.rooms.open(h.Slog, conn, q, questions, timeLimit)
+	room.read("", conn)
+}
+
+// joinRoom adds the player to the room, the player receives the questions over the websocket
+func (h *Handler) joinRoom(w http.ResponseWriter, r *http.Request) {
+	player, err := fromQueryUser(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	room, err := h.rooms.get(r.PathValue("code"))
+	if err != nil {
+		h.writeError(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	conn, err := roomUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logError(r, http.StatusText(http.StatusBadRequest), err)
+		return
+	}
+
+	if !room.dispatch(roomEvent{player: player, conn: conn, join: true}) {
+		conn.Close()
+		return
+	}
+	room.read(player, conn)
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func dialRoom(t *testing.T, server *httptest.Server, path string) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + path
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", path, err)
+	}
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readRoom skips the messages of other types, like players joining
+func readRoom(t *testing.T, conn *websocket.Conn, messageType string) quiz.RoomMessage {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var m quiz.RoomMessage
+		if err := conn.ReadJSON(&m); err != nil {
+			t.Fatalf("failed to read %s message: %v", messageType, err)
+		}
+		if m.Type == messageType {
+			return m
+		}
+	}
+}
+
+func answerRoom(t *testing.T, conn *websocket.Conn, number int, option int) {
+	if err := conn.WriteJSON(quiz.RoomMessage{Type: quiz.RoomMessageAnswer, Number: number, Option: &option}); err != nil {
+		t.Fatalf("failed to answer: %v", err)
+	}
+}
+
+func TestHandlerRoom(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	handler.rooms.pause = 0
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodPut, "/admin/quizzes/meeting", strings.NewReader(`{"question_ids": [0, 2]}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+testAdminToken)
+	w := httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/rooms/UNKNOWN?user=alice")
+	if err != nil {
+		t.Fatalf("failed to get room: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status code %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	host := dialRoom(t, server, "/rooms?quiz=meeting&question_time_limit=2s")
+	room := readRoom(t, host, quiz.RoomMessageRoom)
+	if room.Code == "" || room.Count != 2 || room.TimeLimit() != 2*time.Second {
+		t.Fatalf("expected a room of 2 questions of 2s, got %+v", room)
+	}
+
+	alice := dialRoom(t, server, "/rooms/"+room.Code+"?user=alice")
+	readRoom(t, host, quiz.RoomMessagePlayers)
+	bob := dialRoom(t, server, "/rooms/"+room.Code+"?user=bob")
+	if players := readRoom(t, host, quiz.RoomMessagePlayers); len(players.Players) != 2 {
+		t.Fatalf("expected alice and bob, got %v", players.Players)
+	}
+
+	taken := dialRoom(t, server, "/rooms/"+room.Code+"?user=bob")
+	if m := readRoom(t, taken, quiz.RoomMessageError); m.Error == "" {
+		t.Fatalf("expected an error joining with a taken name, got %+v", m)
+	}
+
+	if err := host.WriteJSON(quiz.RoomMessage{Type: quiz.RoomMessageStart}); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	// both players get the question at once, alice is right and bob is wrong
+	for _, player := range []*websocket.Conn{alice, bob} {
+		question := readRoom(t, player, quiz.RoomMessageQuestion)
+		if question.Number != 1 || question.Question.ID != 0 {
+			t.Fatalf("expected the first question, got %+v", question)
+		}
+	}
+	answerRoom(t, alice, 1, 1)
+	answerRoom(t, bob, 1, 0)
+
+	ranking := readRoom(t, bob, quiz.RoomMessageRanking)
+	if *ranking.Option != 1 || ranking.Ranking[0].Player != "alice" || ranking.Ranking[0].Points <= quiz.RoomMaxPoints/2 || ranking.Ranking[1].Score != 0 {
+		t.Fatalf("expected alice first after the first question, got %+v", ranking)
+	}
+
+	// only bob answers, alice runs out of time
+	readRoom(t, bob, quiz.RoomMessageQuestion)
+	answerRoom(t, bob, 2, 3)
+
+	finished := readRoom(t, alice, quiz.RoomMessageFinished)
+	if len(finished.Ranking) != 2 {
+		t.Fatalf("expected 2 players in the final ranking, got %+v", finished.Ranking)
+	}
+	for _, score := range finished.Ranking {
+		if score.Correct != 1 || score.Score == 0 {
+			t.Fatalf("expected both players with 1 correct answer, got %+v", finished.Ranking)
+		}
+	}
+
+	readRoom(t, host, quiz.RoomMessageFinished)
+}
+
+func TestHandlerRoomPing(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	handler.rooms.pongWait = 200 * time.Millisecond
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodPut, "/admin/quizzes/meeting", strings.NewReader(`{"question_ids": [0]}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+testAdminToken)
+	w := httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	host := dialRoom(t, server, "/rooms?quiz=meeting")
+	room := readRoom(t, host, quiz.RoomMessageRoom)
+
+	// alice never reads, so she answers no ping and leaves, the host reads and stays
+	dialRoom(t, server, "/rooms/"+room.Code+"?user=alice")
+	if players := readRoom(t, host, quiz.RoomMessagePlayers); len(players.Players) != 1 {
+		t.Fatalf("expected alice, got %v", players.Players)
+	}
+	if players := readRoom(t, host, quiz.RoomMessagePlayers); len(players.Players) != 0 {
+		t.Fatalf("expected alice gone without answering the pings, got %v", players.Players)
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	queryTimeLimit         = "time_limit"
+	queryQuestionTimeLimit = "question_time_limit"
+)
+
+func (h *Handler) postSession(w http.ResponseWriter, r *http.Request) {
+	user, err := fromPathUser(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	// limits of named quizzes come from the quiz itself
+	if slug := r.URL.Query().Get(queryQuiz); slug != "" {
+		h.startNamedQuiz(w, r, user, slug)
+		return
+	}
+
+	limits, err := fromQueryLimits(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	session, err := h.db.CreateSession(r.Context(), user, fromQueryFilter(r), limits)
+	if err != nil {
+		switch err {
+		case ErrUserNotFound:
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, localizeSession(w, r, session))
+}
+
+func (h *Handler) getSession(w http.ResponseWriter, r *http.Request) {
+	user, sessionID, err := fromPathSession(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	session, err := h.db.GetSession(r.Context(), user, sessionID)
+	if err != nil {
+		switch err {
+		case ErrUserNotFound:
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		case ErrSessionNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, localizeSession(w, r, session))
+}
+
+func (h *Handler) putSessionAnswers(w http.ResponseWriter, r *http.Request) {
+	user, sessionID, err := fromPathSession(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	quizAnswer := quiz.QuizAnswer{}
+	if err := json.NewDecoder(r.Body).Decode(&quizAnswer); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	records, err := h.db.InsertSessionAnswer(r.Context(), user, sessionID, quizAnswer)
+	if err != nil {
+		switch err {
+		case ErrUserNotFound, ErrQuestionNotInSession, ErrQuestionAlreadyAnswered, ErrInvalidOption, ErrBatchedAnswers:
+			h.writeError(w, r, http.StatusBadRequest, err)
+			return
+		case ErrSessionNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	// the explanations come in the language the questions were asked in
+	session, err := h.db.GetSession(r.Context(), user, sessionID)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeData(w, r, explainAnswers(localizeQuestions(w, r, session.Questions), records))
+}
+
+func fromPathSession(r *http.Request) (string, uint64, error) {
+	user, err := fromPathUser(r)
+	if err != nil {
+		return "", 0, err
+	}
+
+	rawSession := r.PathValue("session")
+	sessionID, err := strconv.ParseUint(rawSession, 10, 64)
+	if err != nil {
+		return "", 0, &quiz.FieldError{Field: "session", Reason: fmt.Sprintf("`%s`", rawSession)}
+	}
+	return user, sessionID, nil
+}
+
+// fromQueryLimits reads `?time_limit=5m&question_time_limit=30s`
+func fromQueryLimits(r *http.Request) (SessionLimits, error) {
+	limits := SessionLimits{}
+	for key, limit := range map[string]*time.Duration{
+		queryTimeLimit:         &limits.TimeLimit,
+		queryQuestionTimeLimit: &limits.QuestionTimeLimit,
+	} {
+		raw := r.URL.Query().Get(key)
+		if raw == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			return SessionLimits{}, &quiz.FieldError{Field: key, Reason: fmt.Sprintf("`%s`, try: 30s, 5m", raw)}
+		}
+		*limit = d
+	}
+	return limits, nil
+}
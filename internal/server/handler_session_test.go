@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerSession(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/sessions/user?time_limit=1m&question_time_limit=20s", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var session quiz.Session
+	err = json.Unmarshal(w.Body.Bytes(), &session)
+	if err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+
+	if session.TimeLimitMs != 60000 || session.QuestionTimeLimitMs != 20000 {
+		t.Fatalf("expected limits 60000ms and 20000ms, got %dms and %dms", session.TimeLimitMs, session.QuestionTimeLimitMs)
+	}
+
+	url := fmt.Sprintf("/sessions/user/%d", session.ID)
+	body := fmt.Sprintf(`{"%d": "%s"}`, session.Questions[0].ID, session.Questions[0].OptionIDs[0])
+	r, err = http.NewRequestWithContext(context.Background(), http.MethodPut, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var records []quiz.AnswerRecord
+	err = json.Unmarshal(w.Body.Bytes(), &records)
+	if err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+
+	if len(records) != 1 || records[0].Late {
+		t.Fatalf("expected one answer on time, got %+v", records)
+	}
+
+	r, err = http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	err = json.Unmarshal(w.Body.Bytes(), &session)
+	if err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+
+	if len(session.Answers) != 1 {
+		t.Fatalf("expected one recorded answer, got %d", len(session.Answers))
+	}
+}
+
+func TestHandlerSessionExplanations(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	serve := func(method string, url string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		w := httptest.NewRecorder()
+		handler.Mux.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	var session quiz.Session
+	if err := json.Unmarshal(serve(http.MethodPost, "/v1/sessions/user?category=geography", "").Body.Bytes(), &session); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	if strings.Contains(serve(http.MethodGet, fmt.Sprintf("/v1/sessions/user/%d", session.ID), "").Body.String(), "explanation") {
+		t.Fatal("expected no explanation before answering")
+	}
+
+	q := session.Questions[0]
+	url := fmt.Sprintf("/v1/sessions/user/%d?lang=es", session.ID)
+	var records []quiz.AnswerRecord
+	if err := json.Unmarshal(serve(http.MethodPut, url, fmt.Sprintf(`{"%d": "%s"}`, q.ID, q.OptionIDs[0])).Body.Bytes(), &records); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	if len(records) != 1 || !strings.Contains(records[0].Explanation, "capital de") {
+		t.Fatalf("expected the explanation in Spanish with the answer, got %+v", records)
+	}
+
+	if err := json.Unmarshal(serve(http.MethodGet, url, "").Body.Bytes(), &session); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	if len(session.Answers) != 1 || session.Answers[0].Explanation != records[0].Explanation {
+		t.Fatalf("expected the explanation with the recorded answer, got %+v", session.Answers)
+	}
+}
+
+func TestHandlerSessionErrors(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		statusCode int
+	}{
+		{name: "unknown user", method: http.MethodPost, url: "/sessions/unknown", statusCode: http.StatusBadRequest},
+		{name: "invalid time limit", method: http.MethodPost, url: "/sessions/user?time_limit=soon", statusCode: http.StatusBadRequest},
+		{name: "negative time limit", method: http.MethodPost, url: "/sessions/user?question_time_limit=-1s", statusCode: http.StatusBadRequest},
+		{name: "invalid session", method: http.MethodGet, url: "/sessions/user/abc", statusCode: http.StatusBadRequest},
+		{name: "session not found", method: http.MethodGet, url: "/sessions/user/99", statusCode: http.StatusNotFound},
+		{name: "answer session not found", method: http.MethodPut, url: "/sessions/user/99", body: `{"1": "0"}`, statusCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			handler.Mux.ServeHTTP(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("expected status code %d, got %d", tt.statusCode, w.Code)
+			}
+		})
+	}
+}
@@ -8,18 +8,22 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/vrnvu/temp/pkg/quiz"
 )
 
+const testAdminToken = "admin"
+
 func testHandler(t *testing.T) *Handler {
 	handler, err := FromConfig(&Config{
 		Slog: slog.New(slog.NewJSONHandler(os.Stdout, nil)),
 		RequestIDGenerator: func() string {
 			return "123"
 		},
+		AdminToken: testAdminToken,
 	})
 	if err != nil {
 		t.Fatalf("failed to create handler: %v", err)
@@ -27,6 +31,21 @@ func testHandler(t *testing.T) *Handler {
 	return handler
 }
 
+// testUserToken gives the user a new token, the users of NewInMemoryDB have none
+func testUserToken(t *testing.T, db *InMemoryDB, user string) string {
+	t.Helper()
+	db.lockUsers.Lock()
+	defer db.lockUsers.Unlock()
+
+	userID, err := db.getUserID(user)
+	if err != nil {
+		t.Fatalf("failed to find user %s: %v", user, err)
+	}
+	token := newUserToken()
+	db.users[userID].TokenHash = hashToken(token)
+	return token
+}
+
 func TestHandlerHealth(t *testing.T) {
 	t.Parallel()
 	handler := testHandler(t)
@@ -42,6 +61,37 @@ func TestHandlerHealth(t *testing.T) {
 	}
 }
 
+func TestHandlerRequestID(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	tests := []struct {
+		name string
+		id   string
+		want string
+	}{
+		{name: "propagated", id: "caller-id_1", want: "caller-id_1"},
+		{name: "missing", id: "", want: "123"},
+		{name: "invalid", id: "not valid\n", want: "123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/v1/quiz", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			r.Header.Set("X-Request-ID", tt.id)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got := w.Header().Get("X-Request-ID"); got != tt.want {
+				t.Fatalf("expected request ID %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
 func TestHandlerQuiz(t *testing.T) {
 	t.Parallel()
 	handler := testHandler(t)
@@ -140,9 +190,26 @@ func TestHandlerPutQuizAnswers(t *testing.T) {
 	if body.Total != 1 {
 		t.Fatalf("expected total to be one, got %d", body.Total)
 	}
+
+	// unknown questions are refused before any answer is scored
+	r, err = http.NewRequestWithContext(context.Background(), http.MethodPut, "/quiz/user", strings.NewReader(`{"1": "a", "999": "x"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), quiz.CodeQuestionNotFound) {
+		t.Fatalf("expected status code %d with %s, got %d: %s", http.StatusBadRequest, quiz.CodeQuestionNotFound, w.Code, w.Body.String())
+	}
+	if results, err := handler.db.GetResults(context.Background(), "user"); err != nil || results.Total != 1 {
+		t.Fatalf("expected no answer scored, got %v, %v", results, err)
+	}
 }
 
 func TestHandlerPutNewUser(t *testing.T) {
+	t.Parallel()
 	handler := testHandler(t)
 	tests := []struct {
 		name       string
@@ -227,6 +294,10 @@ func TestHandlerGetStatistics(t *testing.T) {
 		Total:      0,
 		AvgCorrect: 0,
 		AvgTotal:   0,
+		Categories: []quiz.CategoryStatistics{
+			{Category: "arithmetic"},
+			{Category: "geography"},
+		},
 	}
 
 	var body quiz.StatisticsResults
@@ -235,7 +306,105 @@ func TestHandlerGetStatistics(t *testing.T) {
 		t.Fatalf("failed to unmarshal body: %v", err)
 	}
 
-	if body != expected {
+	if !reflect.DeepEqual(body, expected) {
 		t.Fatalf("expected body %v, got %v", expected, body)
 	}
 }
+
+func TestHandlerQuizReview(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	tests := []struct {
+		name       string
+		url        string
+		statusCode int
+	}{
+		{name: "review", url: "/quiz?mode=review&user=user", statusCode: http.StatusOK},
+		{name: "missing user", url: "/quiz?mode=review", statusCode: http.StatusBadRequest},
+		{name: "unknown user", url: "/quiz?mode=review&user=unknown", statusCode: http.StatusBadRequest},
+		{name: "invalid mode", url: "/quiz?mode=invalid", statusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			handler.Mux.ServeHTTP(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("expected status code %d, got %d", tt.statusCode, w.Code)
+			}
+		})
+	}
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/quiz?mode=review&user=user", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	var body []quiz.Question
+	err = json.Unmarshal(w.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+
+	if len(body) != len(handler.db.questions) {
+		t.Fatalf("expected %d questions to review, got %d", len(handler.db.questions), len(body))
+	}
+}
+
+func TestHandlerQuizFilter(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	tests := []struct {
+		name     string
+		url      string
+		category string
+		empty    bool
+	}{
+		{name: "category", url: "/quiz?category=geography", category: "geography"},
+		{name: "category and tag", url: "/quiz?category=arithmetic&tag=addition", category: "arithmetic"},
+		{name: "tags", url: "/quiz?tag=capitals&tag=europe", category: "geography"},
+		{name: "no match", url: "/quiz?category=geography&tag=addition", empty: true},
+		{name: "review", url: "/quiz?mode=review&user=user&category=arithmetic", category: "arithmetic"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			handler.Mux.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+
+			var body []quiz.Question
+			err = json.Unmarshal(w.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatalf("failed to unmarshal body: %v", err)
+			}
+
+			if tt.empty != (len(body) == 0) {
+				t.Fatalf("expected empty body to be %t, got %d questions", tt.empty, len(body))
+			}
+
+			for _, q := range body {
+				if q.Category != tt.category {
+					t.Fatalf("expected category %s, got %s", tt.category, q.Category)
+				}
+			}
+		})
+	}
+}
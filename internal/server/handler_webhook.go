@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func (h *Handler) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.db.GetWebhooks(r.Context())
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeData(w, r, webhooks)
+}
+
+func (h *Handler) putWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook := quiz.Webhook{}
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	slug := r.PathValue("slug")
+	if webhook.Slug == "" {
+		webhook.Slug = slug
+	}
+	if webhook.Slug != slug {
+		err := &quiz.FieldError{Field: "slug", Reason: fmt.Sprintf("body `%s` does not match path `%s`", webhook.Slug, slug)}
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := webhook.Validate(); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.db.PutWebhook(r.Context(), webhook); err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	webhook.Secret = ""
+	h.writeData(w, r, webhook)
+}
+
+func (h *Handler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := h.db.DeleteWebhook(r.Context(), r.PathValue("slug")); err != nil {
+		switch err {
+		case ErrWebhookNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+}
+
+func (h *Handler) getDeadLetters(w http.ResponseWriter, r *http.Request) {
+	dead, err := h.db.GetDeadLetters(r.Context())
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeData(w, r, dead)
+}
+
+func (h *Handler) redeliver(w http.ResponseWriter, r *http.Request) {
+	rawDelivery := r.PathValue("delivery")
+	id, err := strconv.ParseUint(rawDelivery, 10, 64)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, &quiz.FieldError{Field: "delivery", Reason: fmt.Sprintf("`%s`", rawDelivery)})
+		return
+	}
+
+	d, err := h.db.Redeliver(r.Context(), id)
+	if err != nil {
+		switch err {
+		case ErrDeliveryNotFound, ErrWebhookNotFound:
+			h.writeError(w, r, http.StatusNotFound, err)
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.writeData(w, r, d)
+}
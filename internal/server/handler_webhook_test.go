@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+func TestHandlerWebhooks(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	handler.webhooks.backoff = time.Millisecond
+
+	// the receiver fails until told otherwise, every delivery it gets is verified
+	var healthy atomic.Bool
+	received := make(chan quiz.WebhookEvent, 16)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read delivery: %v", err)
+			return
+		}
+		if err := quiz.VerifyWebhook(testWebhookSecret, r.Header.Get(quiz.HeaderWebhookSignature), body, time.Now(), time.Minute); err != nil {
+			t.Errorf("failed to verify delivery: %v", err)
+		}
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var event quiz.WebhookEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("failed to unmarshal delivery: %v", err)
+		}
+		if r.Header.Get(quiz.HeaderWebhookEvent) != event.Type {
+			t.Errorf("expected header %s, got %s", event.Type, r.Header.Get(quiz.HeaderWebhookEvent))
+		}
+		received <- event
+	}))
+	defer receiver.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handler.RunWebhooks(ctx)
+
+	adminRequest := func(method string, url string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		r.Header.Set("Authorization", "Bearer "+testAdminToken)
+
+		w := httptest.NewRecorder()
+		handler.Mux.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, url, http.StatusOK, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	w := adminRequest(http.MethodPut, "/admin/webhooks/chat", `{"url": "`+receiver.URL+`", "events": ["user.created"], "secret": "`+testWebhookSecret+`"}`)
+	if strings.Contains(w.Body.String(), testWebhookSecret) {
+		t.Fatalf("expected the secret not answered back, got %s", w.Body.String())
+	}
+
+	if _, err := handler.db.InsertUser(ctx, "other"); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+
+	// every attempt fails, so the delivery goes to the dead letters
+	var dead []quiz.WebhookDelivery
+	for deadline := time.Now().Add(5 * time.Second); len(dead) == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a dead letter")
+		}
+		w := adminRequest(http.MethodGet, "/admin/dead-letters", "")
+		if err := json.Unmarshal(w.Body.Bytes(), &dead); err != nil {
+			t.Fatalf("failed to unmarshal body: %v", err)
+		}
+	}
+	if dead[0].Attempts != webhookMaxAttempts || dead[0].Event.Data.User != "other" {
+		t.Fatalf("expected a delivery of other dead after %d attempts, got %+v", webhookMaxAttempts, dead[0])
+	}
+
+	healthy.Store(true)
+	adminRequest(http.MethodPost, "/admin/dead-letters/"+strconv.FormatUint(dead[0].ID, 10)+"/redeliver", "")
+
+	select {
+	case event := <-received:
+		if event.Type != quiz.EventUserCreated || event.Data.User != "other" {
+			t.Fatalf("expected user.created of other, got %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the redelivery to be received")
+	}
+
+	w = adminRequest(http.MethodGet, "/admin/dead-letters", "")
+	if strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Fatalf("expected no dead letters, got %s", w.Body.String())
+	}
+}
+
+func TestHandlerWebhooksSlowReceiver(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	// the slow receiver holds its deliveries until the end of the test
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	received := make(chan string, 16)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(quiz.HeaderWebhookEvent)
+	}))
+	defer fast.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handler.RunWebhooks(ctx)
+
+	for slug, url := range map[string]string{"slow": slow.URL, "fast": fast.URL} {
+		if err := handler.db.PutWebhook(ctx, quiz.Webhook{Slug: slug, URL: url, Events: quiz.WebhookEvents, Secret: testWebhookSecret}); err != nil {
+			t.Fatalf("failed to put webhook: %v", err)
+		}
+	}
+	for _, user := range []string{"a", "b"} {
+		if _, err := handler.db.InsertUser(ctx, user); err != nil {
+			t.Fatalf("failed to insert user: %v", err)
+		}
+	}
+
+	for range 2 {
+		select {
+		case event := <-received:
+			if event != quiz.EventUserCreated {
+				t.Fatalf("expected user.created, got %s", event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the deliveries of fast while slow is stuck")
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	headerIdempotencyKey     = "Idempotency-Key"
+	headerIdempotentReplayed = "Idempotent-Replayed"
+	idempotencyKeyMaxLength  = 255
+	defaultIdempotencyTTL    = 24 * time.Hour
+	// idempotencyMaxResponses kept, the answered responses closest to expire are dropped first
+	idempotencyMaxResponses = 10000
+)
+
+var (
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyKeyInUse  = errors.New("a request with the same idempotency key is in progress")
+)
+
+// idempotentResponse is the first response of a key, pending until its request is answered
+type idempotentResponse struct {
+	key         string
+	fingerprint [sha256.Size]byte
+	pending     bool
+	expiresAt   time.Time
+	status      int
+	header      http.Header
+	body        []byte
+}
+
+// idempotency remembers the responses of the mutating requests sent with an `Idempotency-Key`,
+// keys are scoped to the `Authorization` of the caller, and to the user of the path for the routes
+// without one, so they cannot replay someone else's response
+type idempotency struct {
+	lock      sync.Mutex
+	responses map[string]*idempotentResponse
+	// answered responses in the order they expire, every one of them lives for ttl since it was answered
+	answered *list.List
+	ttl      time.Duration
+	max      int
+	now      func() time.Time
+}
+
+func newIdempotency(ttl time.Duration) *idempotency {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	return &idempotency{responses: map[string]*idempotentResponse{}, answered: list.New(), ttl: ttl, max: idempotencyMaxResponses, now: time.Now}
+}
+
+// begin answers the response to replay, or nil when the request is the first one of the key and
+// must be recorded with end
+func (i *idempotency) begin(key string, fingerprint [sha256.Size]byte) (*idempotentResponse, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	// expired responses are dropped from the front until one is still alive
+	now := i.now()
+	for e := i.answered.Front(); e != nil && now.After(e.Value.(*idempotentResponse).expiresAt); e = i.answered.Front() {
+		i.drop(e)
+	}
+
+	resp, ok := i.responses[key]
+	switch {
+	case !ok:
+		// pending responses are never dropped, they are bounded by the requests being answered
+		for len(i.responses)+1 > i.max && i.answered.Len() > 0 {
+			i.drop(i.answered.Front())
+		}
+		i.responses[key] = &idempotentResponse{key: key, fingerprint: fingerprint, pending: true}
+		return nil, nil
+	case resp.fingerprint != fingerprint:
+		return nil, ErrIdempotencyKeyReused
+	case resp.pending:
+		return nil, ErrIdempotencyKeyInUse
+	default:
+		return resp, nil
+	}
+}
+
+// end records the response of the key, server errors are forgotten so the request can be retried
+func (i *idempotency) end(key string, status int, header http.Header, body []byte) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if status >= http.StatusInternalServerError {
+		delete(i.responses, key)
+		return
+	}
+
+	resp := i.responses[key]
+	resp.pending = false
+	resp.expiresAt = i.now().Add(i.ttl)
+	resp.status = status
+	resp.header = header
+	resp.body = body
+	i.answered.PushBack(resp)
+}
+
+// drop forgets the answered response of the element, must be called holding lock
+func (i *idempotency) drop(e *list.Element) {
+	resp := i.answered.Remove(e).(*idempotentResponse)
+	delete(i.responses, resp.key)
+}
+
+// recorder keeps a copy of the response it writes
+type recorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+		rec.header = rec.ResponseWriter.Header().Clone()
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// withIdempotency replays the first response of requests retried with the same `Idempotency-Key`,
+// the key reused for another request is a 422 and a retry while the first one runs is a 409
+func withIdempotency(slog *slog.Logger, i *idempotency, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(headerIdempotencyKey)
+		if key == "" || r.Method == http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fail := func(status int, err error) {
+			slog.WarnContext(r.Context(), http.StatusText(status), "error", err, "method", r.Method, "path", r.URL.Path, headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+			writeProblem(w, r, newProblem(r, status, err))
+		}
+
+		if len(key) > idempotencyKeyMaxLength {
+			fail(http.StatusBadRequest, &quiz.FieldError{Field: headerIdempotencyKey, Reason: fmt.Sprintf("use at most %d characters", idempotencyKeyMaxLength)})
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			fail(http.StatusBadRequest, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// the credential is hashed, the keys kept in memory do not hold tokens
+		scoped := hashToken(r.Header.Get(headerAuthorization)) + "\x00" + r.PathValue("user") + "\x00" + key
+		resp, err := i.begin(scoped, requestFingerprint(r, body))
+		switch {
+		case errors.Is(err, ErrIdempotencyKeyReused):
+			fail(http.StatusUnprocessableEntity, err)
+			return
+		case errors.Is(err, ErrIdempotencyKeyInUse):
+			fail(http.StatusConflict, err)
+			return
+		case resp != nil:
+			for name, values := range resp.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+			w.Header().Set(headerIdempotentReplayed, "true")
+			w.WriteHeader(resp.status)
+			w.Write(resp.body)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w}
+		answered := false
+		defer func() {
+			// a panic is answered as an internal error by net/http
+			if !answered {
+				i.end(scoped, http.StatusInternalServerError, nil, nil)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+		answered = true
+
+		if rec.status == 0 {
+			rec.WriteHeader(http.StatusOK)
+		}
+		i.end(scoped, rec.status, rec.header, rec.body.Bytes())
+	}
+}
+
+func requestFingerprint(r *http.Request, body []byte) [sha256.Size]byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+	h.Write(body)
+	var fingerprint [sha256.Size]byte
+	h.Sum(fingerprint[:0])
+	return fingerprint
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerIdempotencyKey(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		token      string
+		key        string
+		body       string
+		statusCode int
+		replayed   bool
+	}{
+		{name: "first", method: http.MethodPut, url: "/quiz/user", key: "a", body: `{"0": "Paris"}`, statusCode: http.StatusOK},
+		{name: "retry", method: http.MethodPut, url: "/quiz/user", key: "a", body: `{"0": "Paris"}`, statusCode: http.StatusOK, replayed: true},
+		{name: "retry other version", method: http.MethodPut, url: "/v1/quiz/user", key: "a", body: `{"0": "Paris"}`, statusCode: http.StatusUnprocessableEntity},
+		{name: "other body", method: http.MethodPut, url: "/quiz/user", key: "a", body: `{"1": "Berlin"}`, statusCode: http.StatusUnprocessableEntity},
+		{name: "other caller", method: http.MethodPut, url: "/quiz/user", token: "other", key: "a", body: `{"1": "Berlin"}`, statusCode: http.StatusOK},
+		{name: "other user", method: http.MethodPut, url: "/users/other", key: "a", statusCode: http.StatusOK},
+		{name: "error first", method: http.MethodPut, url: "/users/user", key: "b", statusCode: http.StatusBadRequest},
+		{name: "error retry", method: http.MethodPut, url: "/users/user", key: "b", statusCode: http.StatusBadRequest, replayed: true},
+		{name: "without key", method: http.MethodPut, url: "/quiz/user", body: `{"2": "1"}`, statusCode: http.StatusOK},
+		{name: "key too long", method: http.MethodPut, url: "/quiz/user", key: strings.Repeat("a", 256), body: `{"2": "1"}`, statusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.key != "" {
+				r.Header.Set(headerIdempotencyKey, tt.key)
+			}
+
+			w := httptest.NewRecorder()
+			handler.Mux.ServeHTTP(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.statusCode, w.Code, w.Body.String())
+			}
+			if replayed := w.Header().Get(headerIdempotentReplayed) == "true"; replayed != tt.replayed {
+				t.Fatalf("expected replayed %t, got %t", tt.replayed, replayed)
+			}
+			if tt.replayed && w.Header().Get(headerXRequestID) != "123" {
+				t.Fatalf("expected the request ID of the retry, got %s", w.Header().Get(headerXRequestID))
+			}
+		})
+	}
+
+	// the keys are scoped to a hash of the credential, not to the credential itself
+	if _, ok := handler.idempotency.responses[hashToken("Bearer other")+"\x00user\x00a"]; !ok {
+		t.Fatalf("expected the key of the other caller scoped to the hash of its credential")
+	}
+	for key := range handler.idempotency.responses {
+		if strings.Contains(key, "Bearer") {
+			t.Fatalf("expected the credential hashed in the key, got %q", key)
+		}
+	}
+
+	results, err := handler.db.GetResults(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("failed to get results: %v", err)
+	}
+	// first, other caller and without key
+	if results.Total != 3 {
+		t.Fatalf("expected the retried answers counted once, got %+v", results)
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	i := newIdempotency(time.Hour)
+	i.now = func() time.Time { return now }
+
+	fingerprint := sha256.Sum256([]byte("PUT /quiz/user"))
+	if resp, err := i.begin("key", fingerprint); resp != nil || err != nil {
+		t.Fatalf("expected the first request to run, got %v, %v", resp, err)
+	}
+	if _, err := i.begin("key", fingerprint); err != ErrIdempotencyKeyInUse {
+		t.Fatalf("expected ErrIdempotencyKeyInUse, got %v", err)
+	}
+
+	// server errors are not replayed, the retry runs again
+	i.end("key", http.StatusInternalServerError, nil, nil)
+	if resp, err := i.begin("key", fingerprint); resp != nil || err != nil {
+		t.Fatalf("expected the retry to run, got %v, %v", resp, err)
+	}
+
+	body, err := json.Marshal(quiz.QuizResults{Correct: 1, Total: 1})
+	if err != nil {
+		t.Fatalf("failed to marshal body: %v", err)
+	}
+	i.end("key", http.StatusOK, http.Header{}, body)
+	if resp, err := i.begin("key", fingerprint); err != nil || resp == nil || string(resp.body) != string(body) {
+		t.Fatalf("expected the response replayed, got %v, %v", resp, err)
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	if resp, err := i.begin("key", fingerprint); resp != nil || err != nil {
+		t.Fatalf("expected the expired key to run again, got %v, %v", resp, err)
+	}
+}
+
+func TestIdempotencyCapped(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	i := newIdempotency(time.Hour)
+	i.now = func() time.Time { return now }
+	i.max = 2
+
+	fingerprint := sha256.Sum256([]byte("PUT /quiz/user"))
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := i.begin(key, fingerprint); err != nil {
+			t.Fatalf("expected %s to run, got %v", key, err)
+		}
+		now = now.Add(time.Second)
+	}
+	// every response is pending, none of them is dropped
+	if len(i.responses) != 3 {
+		t.Fatalf("expected the pending responses kept, got %d", len(i.responses))
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		i.end(key, http.StatusOK, http.Header{}, nil)
+		now = now.Add(time.Second)
+	}
+	if _, err := i.begin("d", fingerprint); err != nil {
+		t.Fatalf("expected d to run, got %v", err)
+	}
+	if _, ok := i.responses["c"]; !ok || len(i.responses) != 2 {
+		t.Fatalf("expected only the newest response kept with d, got %d responses", len(i.responses))
+	}
+	if resp, err := i.begin("c", fingerprint); err != nil || resp == nil {
+		t.Fatalf("expected the newest response replayed, got %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"slices"
+)
+
+// loader batches the store lookups of one GraphQL request. Resolvers ask for keys and get thunks,
+// the executor resolves the thunks of a level of the query once all of them were asked for, so the
+// first thunk loads every pending key at once instead of one lookup per key.
+// A loader serves one request, it is not safe for concurrent use.
+type loader[K comparable, V any] struct {
+	batch func(ctx context.Context, keys []K) (map[K]V, error)
+	// missing is the error of the keys the batch has no value for, nil resolves them to the zero value
+	missing error
+	pending []K
+	values  map[K]V
+	errs    map[K]error
+	// batches counts the calls to batch
+	batches int
+}
+
+func newLoader[K comparable, V any](missing error, batch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{batch: batch, missing: missing, values: map[K]V{}, errs: map[K]error{}}
+}
+
+// load queues the key and returns the thunk of its value
+func (l *loader[K, V]) load(ctx context.Context, key K) func() (V, error) {
+	if !l.loaded(key) && !slices.Contains(l.pending, key) {
+		l.pending = append(l.pending, key)
+	}
+	return func() (V, error) {
+		return l.get(ctx, key)
+	}
+}
+
+func (l *loader[K, V]) get(ctx context.Context, key K) (V, error) {
+	if !l.loaded(key) {
+		l.flush(ctx)
+	}
+	if err := l.errs[key]; err != nil {
+		var zero V
+		return zero, err
+	}
+	return l.values[key], nil
+}
+
+func (l *loader[K, V]) loaded(key K) bool {
+	_, ok := l.values[key]
+	_, failed := l.errs[key]
+	return ok || failed
+}
+
+func (l *loader[K, V]) flush(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+	if len(keys) == 0 {
+		return
+	}
+
+	l.batches++
+	values, err := l.batch(ctx, keys)
+	for _, key := range keys {
+		value, ok := values[key]
+		switch {
+		case err != nil:
+			l.errs[key] = err
+		case ok:
+			l.values[key] = value
+		case l.missing != nil:
+			l.errs[key] = l.missing
+		default:
+			l.values[key] = value
+		}
+	}
+	// a batch may answer keys nobody asked for yet, like every rank of a leaderboard, they are kept
+	// so that asking for them later does not need another batch
+	for key, value := range values {
+		if err == nil && !l.loaded(key) {
+			l.values[key] = value
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	headerAcceptLanguage  = "Accept-Language"
+	headerContentLanguage = "Content-Language"
+	queryLang             = "lang"
+)
+
+// requestLocales by preference, the `lang` query parameter goes before `Accept-Language`
+func requestLocales(r *http.Request) []language.Tag {
+	return quiz.ParseLocales(r.URL.Query().Get(queryLang), r.Header.Get(headerAcceptLanguage))
+}
+
+// requestPrinter of the messages in the locale of the request
+func requestPrinter(r *http.Request) *message.Printer {
+	return quiz.NewPrinter(requestLocales(r)...)
+}
+
+// localizeQuestions into the locale of the request, every question falls back on its own
+func localizeQuestions(w http.ResponseWriter, r *http.Request, questions []quiz.Question) []quiz.Question {
+	w.Header().Add(headerVary, headerAcceptLanguage)
+	locales := requestLocales(r)
+	localized := make([]quiz.Question, len(questions))
+	for i, q := range questions {
+		localized[i] = q.Localize(locales)
+	}
+	return localized
+}
+
+// localizeSession into the locale of the request, the option IDs stay the same in every locale
+func localizeSession(w http.ResponseWriter, r *http.Request, s quiz.Session) quiz.Session {
+	s.Questions = localizeQuestions(w, r, s.Questions)
+	s.Answers = explainAnswers(s.Questions, s.Answers)
+	return s
+}
+
+// explainAnswers with the explanation of their question, of the questions already localized
+func explainAnswers(questions []quiz.Question, records []quiz.AnswerRecord) []quiz.AnswerRecord {
+	// the records are shared with the database
+	explained := slices.Clone(records)
+	for i, record := range explained {
+		if j := slices.IndexFunc(questions, func(q quiz.Question) bool { return q.ID == record.QuestionID }); j >= 0 {
+			explained[i].Explanation = questions[j].Explanation
+		}
+	}
+	return explained
+}
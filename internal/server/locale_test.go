@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerQuizLocale(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	tests := []struct {
+		name   string
+		url    string
+		header string
+		locale string
+	}{
+		{name: "default", url: "/v1/quiz?category=geography", locale: "en"},
+		{name: "accept language", url: "/v1/quiz?category=geography", header: "es-MX,es;q=0.9,en;q=0.8", locale: "es"},
+		{name: "query", url: "/v1/quiz?category=geography&lang=es", header: "en", locale: "es"},
+		{name: "untranslated", url: "/v1/quiz?category=geography&lang=fr", locale: "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if tt.header != "" {
+				r.Header.Set("Accept-Language", tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			handler.Mux.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if got := w.Header().Values("Vary"); !strings.Contains(strings.Join(got, ","), "Accept-Language") {
+				t.Fatalf("expected Vary with Accept-Language, got %v", got)
+			}
+
+			var questions []quiz.Question
+			if err := json.Unmarshal(w.Body.Bytes(), &questions); err != nil {
+				t.Fatalf("failed to unmarshal body: %v", err)
+			}
+			if len(questions) == 0 {
+				t.Fatal("expected questions")
+			}
+			for _, q := range questions {
+				spanish := strings.HasPrefix(q.Text, "¿")
+				if q.Locale != tt.locale || spanish != (tt.locale == "es") {
+					t.Fatalf("expected the question in %s, got %s: %q", tt.locale, q.Locale, q.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerPutQuizAnswersTranslated(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodPut, "/v1/quiz/user", strings.NewReader(`{"0": "París", "1": "Berlín"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	w := httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	r, err = http.NewRequestWithContext(context.Background(), http.MethodGet, "/v1/quiz/user", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	w = httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	var results quiz.QuizResults
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	if results.Correct != 2 || results.Total != 2 {
+		t.Fatalf("expected the answers in Spanish to score, got %+v", results)
+	}
+}
+
+func TestHandlerProblemLocale(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/v1/quizzes/unknown", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	r.Header.Set("Accept-Language", "es-ES")
+
+	w := httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Content-Language"); got != "es" {
+		t.Fatalf("expected Content-Language es, got %s", got)
+	}
+
+	var problem quiz.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	if problem.Title != "No encontrado" || problem.Detail != "cuestionario no encontrado" || problem.Code != quiz.CodeQuizNotFound {
+		t.Fatalf("expected the problem in Spanish with its code, got %+v", problem)
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "quiz"
+
+// metrics of a handler, in a registry of its own so that every handler, and every test, counts apart
+type metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+func newMetrics(db *InMemoryDB) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Name: "http_requests_total", Help: "Requests answered, by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace, Name: "http_request_duration_seconds", Help: "Time to answer a request, by route, method and status. Live rooms last as long as their connection.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Name: "http_requests_in_flight", Help: "Requests being answered.",
+		}),
+	}
+
+	db.operations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace, Name: "store_operation_duration_seconds", Help: "Time of an operation of the store, by operation, including the wait for its locks.",
+		Buckets: []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1},
+	}, []string{"operation"})
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.inFlight,
+		db.operations,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: metricsNamespace, Name: "users", Help: "Users."}, db.countUsers),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: metricsNamespace, Name: "questions", Help: "Questions."}, db.countQuestions),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: metricsNamespace, Name: "attempts_per_minute", Help: "Sessions completed in the last minute."}, db.attemptsPerMinute),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: metricsNamespace, Name: "accuracy_ratio", Help: "Correct answers of every user over their answers, 0 without answers."}, db.accuracy),
+	)
+	return m
+}
+
+// MetricsHandler answers the metrics in the Prometheus format, it is meant for a listener of its own
+// that is not exposed like the api
+func (h *Handler) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(h.metrics.registry, promhttp.HandlerOpts{Registry: h.metrics.registry})
+}
+
+// withMetrics counts the requests of the route by status, and how long they took. The route is the
+// pattern of the mux, so the requests of every user count together.
+func withMetrics(m *metrics, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		status := strconv.Itoa(sw.status)
+		m.requests.WithLabelValues(route(r), r.Method, status).Inc()
+		m.duration.WithLabelValues(route(r), r.Method, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// observe times an operation of the store, in a span of the trace of ctx,
+// `defer db.observe(ctx, "GetQuestions")()`
+func (db *InMemoryDB) observe(ctx context.Context, operation string) func() {
+	_, span := db.tracer.Start(ctx, "InMemoryDB."+operation)
+	start := time.Now()
+	return func() {
+		if db.operations != nil {
+			db.operations.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		}
+		span.End()
+	}
+}
+
+func (db *InMemoryDB) countUsers() float64 {
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+	return float64(len(db.users))
+}
+
+func (db *InMemoryDB) countQuestions() float64 {
+	db.lockQuestions.RLock()
+	defer db.lockQuestions.RUnlock()
+	return float64(len(db.questions))
+}
+
+// attemptsPerMinute are the sessions whose last answer arrived in the last minute
+func (db *InMemoryDB) attemptsPerMinute() float64 {
+	db.lockSessions.RLock()
+	defer db.lockSessions.RUnlock()
+
+	since := db.now().Add(-time.Minute)
+	attempts := 0
+	for _, s := range db.sessions {
+		if len(s.Questions) == 0 || len(s.Answers) != len(s.Questions) {
+			continue
+		}
+		if s.LastActivity().After(since) {
+			attempts++
+		}
+	}
+	return float64(attempts)
+}
+
+func (db *InMemoryDB) accuracy() float64 {
+	db.lockUsers.RLock()
+	defer db.lockUsers.RUnlock()
+
+	var correct, total uint64
+	for _, u := range db.users {
+		correct += u.Correct
+		total += u.Total
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(correct) / float64(total)
+}
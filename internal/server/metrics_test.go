@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerMetrics(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	request := func(method string, url string, body string) {
+		t.Helper()
+		r, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		r.Header.Set(headerContentType, valueContentTypeJSON)
+		handler.Mux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	request(http.MethodGet, "/v1/quiz", "")
+	request(http.MethodGet, "/v1/quiz", "")
+	request(http.MethodGet, "/v1/quiz/unknown", "")
+	request(http.MethodPut, "/v1/users/other", "")
+	request(http.MethodPut, "/v1/quiz/other", `{"0": "Paris", "1": "Paris"}`)
+
+	for _, test := range []struct {
+		route  string
+		method string
+		status string
+		want   float64
+	}{
+		{route: "/v1/quiz", method: http.MethodGet, status: "200", want: 2},
+		{route: "/v1/quiz/{user}", method: http.MethodGet, status: "400", want: 1},
+		{route: "/v1/users/{user}", method: http.MethodPut, status: "200", want: 1},
+	} {
+		if got := testutil.ToFloat64(handler.metrics.requests.WithLabelValues(test.route, test.method, test.status)); got != test.want {
+			t.Fatalf("expected %v requests to %s %s answered %s, got %v", test.want, test.method, test.route, test.status, got)
+		}
+	}
+	if got := testutil.CollectAndCount(handler.metrics.duration); got != 4 {
+		t.Fatalf("expected the latency of 4 routes and statuses, got %d", got)
+	}
+	if got := testutil.ToFloat64(handler.metrics.inFlight); got != 0 {
+		t.Fatalf("expected no requests in flight, got %v", got)
+	}
+	if got := testutil.CollectAndCount(handler.db.operations, "quiz_store_operation_duration_seconds"); got == 0 {
+		t.Fatalf("expected the latency of the store operations")
+	}
+
+	want := `
+# HELP quiz_accuracy_ratio Correct answers of every user over their answers, 0 without answers.
+# TYPE quiz_accuracy_ratio gauge
+quiz_accuracy_ratio 0.5
+# HELP quiz_attempts_per_minute Sessions completed in the last minute.
+# TYPE quiz_attempts_per_minute gauge
+quiz_attempts_per_minute 0
+# HELP quiz_questions Questions.
+# TYPE quiz_questions gauge
+quiz_questions 5
+# HELP quiz_users Users.
+# TYPE quiz_users gauge
+quiz_users 2
+`
+	if err := testutil.GatherAndCompare(handler.metrics.registry, strings.NewReader(want), "quiz_accuracy_ratio", "quiz_attempts_per_minute", "quiz_questions", "quiz_users"); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := handler.db.CreateSession(context.Background(), "user", QuestionFilter{}, SessionLimits{})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	answer := quiz.QuizAnswer{}
+	for _, q := range session.Questions {
+		answer[q.ID] = q.OptionIDs[0]
+	}
+	if _, err := handler.db.InsertSessionAnswer(context.Background(), "user", session.ID, answer); err != nil {
+		t.Fatalf("failed to answer: %v", err)
+	}
+	if got := handler.db.attemptsPerMinute(); got != 1 {
+		t.Fatalf("expected the completed session in the last minute, got %v", got)
+	}
+
+	w := httptest.NewRecorder()
+	handler.MetricsHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{`quiz_http_requests_total{method="GET",route="/v1/quiz",status="200"} 2`, "quiz_attempts_per_minute 1", "go_goroutines"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Fatalf("expected %q in the metrics, got:\n%s", want, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	headerAccept             = "Accept"
+	headerVary               = "Vary"
+	valueContentTypeCSV      = "text/csv"
+	valueContentTypeMsgPack  = "application/msgpack"
+	acceptQualityUnspecified = 1.0
+)
+
+var ErrNotAcceptable = errors.New("not acceptable")
+
+// acceptRange is one of the comma separated ranges of an `Accept` or `Accept-Encoding` header
+type acceptRange struct {
+	value   string
+	quality float64
+}
+
+// parseAccept reads the ranges and their `q` weights, e.g. `text/csv, application/json;q=0.5`
+func parseAccept(header string) ([]acceptRange, error) {
+	ranges := []acceptRange{}
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		value, params, _ := strings.Cut(part, ";")
+		r := acceptRange{value: strings.ToLower(strings.TrimSpace(value)), quality: acceptQualityUnspecified}
+		if r.value == "" || strings.ContainsAny(r.value, " \t") {
+			return nil, fmt.Errorf("malformed range `%s`", part)
+		}
+		for _, param := range strings.Split(params, ";") {
+			key, raw, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+				continue
+			}
+			quality, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+			if err != nil || quality < 0 || quality > 1 {
+				return nil, fmt.Errorf("malformed weight of `%s`", part)
+			}
+			r.quality = quality
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// negotiate picks the offer with the highest weight, the first offers win ties. Ranges match the
+// offers exactly, or with a `*` wildcard like `*/*`, `text/*` or the `*` of encodings. The most
+// specific range of an offer gives its weight.
+func negotiate(ranges []acceptRange, offers []string) (string, bool) {
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		quality, specificity := 0.0, -1
+		for _, r := range ranges {
+			s := matchRange(r.value, offer)
+			if s > specificity {
+				quality, specificity = r.quality, s
+			}
+		}
+		if specificity >= 0 && quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+	return best, bestQuality > 0
+}
+
+// matchRange answers how specifically the range matches the offer, -1 when it does not match
+func matchRange(r string, offer string) int {
+	switch {
+	case r == offer:
+		return 2
+	case r == "*" || r == "*/*":
+		return 0
+	case strings.HasSuffix(r, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(r, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
+
+type mediaTypeKey struct{}
+
+// operationMediaTypes are the types documented for the 200 of the operation, JSON first
+func operationMediaTypes(route *routers.Route) []string {
+	ok := route.Operation.Responses.Status(http.StatusOK)
+	if ok == nil || ok.Value == nil {
+		return nil
+	}
+
+	mediaTypes := []string{}
+	for mediaType := range ok.Value.Content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	slices.SortFunc(mediaTypes, func(a, b string) int {
+		return mediaTypeRank(a) - mediaTypeRank(b)
+	})
+	return mediaTypes
+}
+
+func mediaTypeRank(mediaType string) int {
+	switch mediaType {
+	case valueContentTypeJSON:
+		return 0
+	case valueContentTypeCSV:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// withNegotiation picks the representation of the response from the `Accept` of the request,
+// among the media types the operation offers. Requests that accept none of them, or with a
+// malformed `Accept`, get a 406 before the handler runs.
+func withNegotiation(slog *slog.Logger, offers []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fail := func(err error) {
+			slog.WarnContext(r.Context(), http.StatusText(http.StatusNotAcceptable), "error", err, "method", r.Method, "path", r.URL.Path, headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+			writeProblem(w, r, newProblem(r, http.StatusNotAcceptable, err))
+		}
+
+		ranges, err := parseAccept(r.Header.Get(headerAccept))
+		if err != nil {
+			fail(fmt.Errorf("%w: %w", ErrNotAcceptable, &quiz.FieldError{Field: headerAccept, Reason: err.Error()}))
+			return
+		}
+		if len(offers) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(offers) > 1 {
+			w.Header().Add(headerVary, headerAccept)
+		}
+
+		mediaType := offers[0]
+		if len(ranges) > 0 {
+			var ok bool
+			if mediaType, ok = negotiate(ranges, offers); !ok {
+				fail(fmt.Errorf("%w: %w", ErrNotAcceptable, &quiz.FieldError{Field: headerAccept, Reason: fmt.Sprintf("`%s`, try: %v", r.Header.Get(headerAccept), offers)}))
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), mediaTypeKey{}, mediaType)))
+	}
+}
+
+// mediaTypeOf the response negotiated by withNegotiation, JSON for the routes that do not negotiate
+func mediaTypeOf(r *http.Request) string {
+	if mediaType, ok := r.Context().Value(mediaTypeKey{}).(string); ok {
+		return mediaType
+	}
+	return valueContentTypeJSON
+}
+
+// encode data in the media type, JSON is the default
+func encode(mediaType string, data any) ([]byte, error) {
+	switch mediaType {
+	case valueContentTypeCSV:
+		return encodeCSV(data)
+	case valueContentTypeMsgPack:
+		return encodeMsgPack(data)
+	default:
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	}
+}
+
+// encodeMsgPack names and omits the fields like JSON does
+func encodeMsgPack(data any) ([]byte, error) {
+	var b strings.Builder
+	enc := msgpack.NewEncoder(&b)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(data); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+func encodeCSV(data any) ([]byte, error) {
+	records, err := csvRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// csvRecords are a header and a row per item of the tabular responses: the categories of the
+// results, the attempts of the results of a named quiz and the leaderboards
+func csvRecords(data any) ([][]string, error) {
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	switch data := data.(type) {
+	case quiz.QuizResults:
+		if data.Attempts != nil {
+			records := [][]string{{"session", "started_at", "correct", "total", "passed"}}
+			for _, a := range data.Attempts {
+				records = append(records, []string{u(a.Session), a.StartedAt.Format(time.RFC3339), u(a.Correct), u(a.Total), strconv.FormatBool(a.Passed)})
+			}
+			return records, nil
+		}
+		records := [][]string{{"category", "correct", "total"}}
+		for _, c := range data.Categories {
+			records = append(records, []string{c.Category, u(c.Correct), u(c.Total)})
+		}
+		return records, nil
+	case []quiz.LeaderboardEntry:
+		records := [][]string{{"rank", "user", "correct", "total", "accuracy"}}
+		for _, e := range data {
+			records = append(records, []string{u(e.Rank), e.User, u(e.Correct), u(e.Total), f(e.Accuracy)})
+		}
+		return records, nil
+	case []quiz.GroupStatistics:
+		records := [][]string{{"group", "kind", "members", "correct", "total", "avg_correct", "avg_total", "accuracy"}}
+		for _, g := range data {
+			records = append(records, []string{g.Group, g.Kind, u(g.Members), u(g.Correct), u(g.Total), f(g.AvgCorrect), f(g.AvgTotal), f(g.Accuracy)})
+		}
+		return records, nil
+	default:
+		return nil, fmt.Errorf("no csv representation of %T", data)
+	}
+}
+
+// contentType of the media type, with the charset of the text ones
+func contentType(mediaType string) string {
+	if strings.HasPrefix(mediaType, "text/") {
+		return mime.FormatMediaType(mediaType, map[string]string{"charset": "utf-8"})
+	}
+	return mediaType
+}
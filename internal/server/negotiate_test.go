@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestNegotiate(t *testing.T) {
+	t.Parallel()
+	offers := []string{valueContentTypeJSON, valueContentTypeCSV, valueContentTypeMsgPack}
+
+	tests := []struct {
+		name    string
+		accept  string
+		want    string
+		ok      bool
+		invalid bool
+	}{
+		{name: "exact", accept: "text/csv", want: valueContentTypeCSV, ok: true},
+		{name: "any", accept: "*/*", want: valueContentTypeJSON, ok: true},
+		{name: "wildcard subtype", accept: "text/*", want: valueContentTypeCSV, ok: true},
+		{name: "weights", accept: "application/json;q=0.5, application/msgpack", want: valueContentTypeMsgPack, ok: true},
+		{name: "ties keep the order of the offers", accept: "application/msgpack, text/csv", want: valueContentTypeCSV, ok: true},
+		{name: "specific range wins", accept: "*/*;q=0.1, application/json;q=0", want: valueContentTypeCSV, ok: true},
+		{name: "case and spaces", accept: " TEXT/CSV ; Q=0.8 ", want: valueContentTypeCSV, ok: true},
+		{name: "excluded", accept: "text/csv;q=0", ok: false},
+		{name: "unsupported", accept: "image/png", ok: false},
+		{name: "malformed weight", accept: "text/csv;q=high", invalid: true},
+		{name: "weight out of range", accept: "text/csv;q=2", invalid: true},
+		{name: "malformed range", accept: "text csv", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges, err := parseAccept(tt.accept)
+			if (err != nil) != tt.invalid {
+				t.Fatalf("expected invalid %t, got %v", tt.invalid, err)
+			}
+			if tt.invalid {
+				return
+			}
+
+			got, ok := negotiate(ranges, offers)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("expected %q, %t, got %q, %t", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestHandlerNegotiation(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	ctx := context.Background()
+
+	if err := handler.db.InsertQuizAnswer(ctx, "user", quiz.QuizAnswer{0: "Paris", 1: "Rome"}); err != nil {
+		t.Fatalf("failed to insert answers: %v", err)
+	}
+
+	get := func(url string, accept string, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if accept != "" {
+			r.Header.Set(headerAccept, accept)
+		}
+		if ifNoneMatch != "" {
+			r.Header.Set(headerIfNoneMatch, ifNoneMatch)
+		}
+
+		w := httptest.NewRecorder()
+		handler.Mux.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/leaderboard", "text/csv", "")
+	if w.Code != http.StatusOK || w.Header().Get(headerContentType) != "text/csv; charset=utf-8" || !slices.Contains(w.Header().Values(headerVary), headerAccept) {
+		t.Fatalf("expected a csv leaderboard, got %d with headers %v", w.Code, w.Header())
+	}
+	if want := "rank,user,correct,total,accuracy\n1,user,1,2,0.5\n"; w.Body.String() != want {
+		t.Fatalf("expected %q, got %q", want, w.Body.String())
+	}
+
+	w = get("/quiz/user", "application/msgpack", "")
+	if w.Code != http.StatusOK || w.Header().Get(headerContentType) != valueContentTypeMsgPack {
+		t.Fatalf("expected msgpack results, got %d with headers %v", w.Code, w.Header())
+	}
+	var results quiz.QuizResults
+	dec := msgpack.NewDecoder(w.Body)
+	dec.SetCustomStructTag("json")
+	if err := dec.Decode(&results); err != nil {
+		t.Fatalf("failed to decode results: %v", err)
+	}
+	if results.Correct != 1 || results.Total != 2 {
+		t.Fatalf("expected 1 of 2 correct, got %+v", results)
+	}
+
+	// every representation has its own ETag
+	asJSON, asCSV := get("/quiz/user", "", ""), get("/quiz/user", "text/csv", "")
+	if asJSON.Header().Get(headerETag) == asCSV.Header().Get(headerETag) {
+		t.Fatalf("expected different ETags, got %s", asJSON.Header().Get(headerETag))
+	}
+	if w := get("/quiz/user", "text/csv", asJSON.Header().Get(headerETag)); w.Code != http.StatusOK {
+		t.Fatalf("expected the csv modified for the ETag of the json, got %d", w.Code)
+	}
+	if w := get("/quiz/user", "text/csv", asCSV.Header().Get(headerETag)); w.Code != http.StatusNotModified {
+		t.Fatalf("expected the csv not modified, got %d", w.Code)
+	}
+
+	w = get("/quiz", "text/csv", "")
+	if w.Code != http.StatusNotAcceptable || w.Header().Get(headerContentType) != quiz.ContentTypeProblem {
+		t.Fatalf("expected a 406 problem, got %d with headers %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// openAPISpec documents every route of the current version, handle refuses undocumented routes
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func loadOpenAPI() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid openapi.json: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi.json: %w", err)
+	}
+	return doc, nil
+}
+
+// openAPIRoute finds the operation of the route, path as registered like `/quiz/{user}`
+func openAPIRoute(doc *openapi3.T, method string, path string) (*routers.Route, error) {
+	pathItem := doc.Paths.Value(path)
+	if pathItem == nil || pathItem.GetOperation(method) == nil {
+		return nil, fmt.Errorf("route `%s %s` is not documented in openapi.json", method, path)
+	}
+	return &routers.Route{Spec: doc, Path: path, PathItem: pathItem, Method: method, Operation: pathItem.GetOperation(method)}, nil
+}
+
+// openAPIInput is what the validation of openapi3filter needs to know about the request
+func openAPIInput(route *routers.Route, r *http.Request) *openapi3filter.RequestValidationInput {
+	params := map[string]string{}
+	for _, p := range route.PathItem.Parameters {
+		if p.Value.In == openapi3.ParameterInPath {
+			params[p.Value.Name] = r.PathValue(p.Value.Name)
+		}
+	}
+
+	return &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: params,
+		Route:      route,
+		// handlers authenticate themselves
+		Options: &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+	}
+}
+
+// acceptsIdempotencyKey tells whether the operation documents the `Idempotency-Key` header,
+// operations that stream their body do not so it is never buffered
+func acceptsIdempotencyKey(route *routers.Route) bool {
+	for _, p := range route.Operation.Parameters {
+		if p.Value != nil && p.Value.In == openapi3.ParameterInHeader && p.Value.Name == headerIdempotencyKey {
+			return true
+		}
+	}
+	return false
+}
+
+// streamsBody tells whether the operation takes a body that is not JSON, like an NDJSON archive
+// or an image, which is read by the handler instead of being validated as a whole
+func streamsBody(route *routers.Route) bool {
+	body := route.Operation.RequestBody
+	return body != nil && body.Value != nil && body.Value.Content.Get(valueContentTypeJSON) == nil
+}
+
+// withOpenAPIValidation rejects requests that do not follow the documented parameters and body.
+// Bodies are always JSON, whatever content type clients like curl send, except the streamed ones.
+func withOpenAPIValidation(slog *slog.Logger, route *routers.Route, next http.HandlerFunc) http.HandlerFunc {
+	streamed := streamsBody(route)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get(headerContentType)); mediaType != valueContentTypeJSON && !streamed {
+			r.Header.Set(headerContentType, valueContentTypeJSON)
+		}
+
+		input := openAPIInput(route, r)
+		input.Options.ExcludeRequestBody = streamed
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			slog.ErrorContext(r.Context(), http.StatusText(http.StatusBadRequest), "error", err, "method", r.Method, "path", r.URL.Path, headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+			writeProblem(w, r, newProblem(r, http.StatusBadRequest, err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(headerContentType, valueContentTypeJSON)
+	w.Header().Set(headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+	w.Write(openAPISpec)
+}
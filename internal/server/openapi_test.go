@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+var pathParamRegexp = regexp.MustCompile(`{[a-z]+}`)
+
+func init() {
+	openapi3filter.RegisterBodyDecoder(quiz.ContentTypeNDJSON, openapi3filter.FileBodyDecoder)
+	for _, mediaType := range quiz.BlobMediaTypes {
+		openapi3filter.RegisterBodyDecoder(mediaType, openapi3filter.FileBodyDecoder)
+	}
+	// the responses validate like their JSON representation
+	openapi3filter.RegisterBodyDecoder(valueContentTypeMsgPack, func(body io.Reader, _ http.Header, _ *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (any, error) {
+		var data any
+		if err := msgpack.NewDecoder(body).Decode(&data); err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(b, &data)
+		return data, err
+	})
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	for path, pathItem := range handler.openapi.Paths.Map() {
+		prefix := "/" + apiVersionCurrent
+		if len(pathItem.Servers) > 0 {
+			prefix = strings.TrimSuffix(pathItem.Servers[0].URL, "/")
+		}
+
+		for method := range pathItem.Operations() {
+			url := prefix + pathParamRegexp.ReplaceAllString(path, "0")
+			r, err := http.NewRequestWithContext(context.Background(), method, url, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			if _, pattern := handler.Mux.Handler(r); pattern != method+" "+prefix+path {
+				t.Fatalf("expected `%s %s` to be served, got pattern `%s`", method, prefix+path, pattern)
+			}
+		}
+	}
+}
+
+// TestOpenAPIResponses fails when a handler answers something the specification does not document,
+// every operation must be exercised at least once
+func TestOpenAPIResponses(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	stranger, err := handler.db.InsertUser(context.Background(), "stranger")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	// the tokens of the users, by their name
+	tokens := map[string]string{"user": testUserToken(t, handler.db, "user"), "stranger": stranger}
+
+	tests := []struct {
+		method     string
+		url        string
+		token      string
+		key        string
+		etag       string
+		accept     string
+		body       string
+		statusCode int
+	}{
+		{method: http.MethodGet, url: "/health", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/versions", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/openapi.json", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/quiz?category=geography&tag=capitals", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/quiz?mode=review&user=user", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/quiz?mode=other", statusCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v1/users/other", statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/users/other", statusCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v1/quiz/user", body: `{"0": "Paris"}`, statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/quiz/other", body: `{"1": "Berlin"}`, statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/quiz/user", body: `[]`, statusCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v1/quiz/user", key: "retry", body: `{"3": "2"}`, statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/quiz/user", key: "retry", body: `{"3": "2"}`, statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/quiz/user", key: "retry", body: `{"4": "2"}`, statusCode: http.StatusUnprocessableEntity},
+		{method: http.MethodGet, url: "/v1/quiz/user", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/quiz/unknown", statusCode: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/v1/quiz/user", etag: "*", statusCode: http.StatusNotModified},
+		{method: http.MethodGet, url: "/v1/quiz/user", accept: "text/csv", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/quiz/user", accept: "application/msgpack", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/quiz/user", accept: "image/png", statusCode: http.StatusNotAcceptable},
+		{method: http.MethodGet, url: "/v1/quiz", accept: "text/csv", statusCode: http.StatusNotAcceptable},
+		{method: http.MethodPut, url: "/v1/quiz/user", accept: "text/csv;q=high", body: `{"0": "Paris"}`, statusCode: http.StatusNotAcceptable},
+		{method: http.MethodGet, url: "/v1/statistics/user", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/statistics/user?group=unknown", statusCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/statistics/user", etag: "*", statusCode: http.StatusNotModified},
+		{method: http.MethodPut, url: "/v1/admin/quizzes/capitals", token: testAdminToken, body: `{"title": "Capitals", "question_ids": [0, 1], "pass_mark": 0.5}`, statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/admin/quizzes/capitals", token: testAdminToken, body: `{"pass_mark": 2}`, statusCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v1/admin/quizzes/capitals", token: testAdminToken, body: `{"unknown": true}`, statusCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v1/admin/quizzes/capitals", body: `{}`, statusCode: http.StatusUnauthorized},
+		{method: http.MethodGet, url: "/v1/quizzes", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/quizzes/capitals", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/quizzes/unknown", statusCode: http.StatusNotFound},
+		{method: http.MethodPost, url: "/v1/sessions/user?time_limit=1m", statusCode: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/sessions/user?quiz=capitals", statusCode: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/sessions/user?quiz=unknown", statusCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/sessions/user/0", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/sessions/user/99", statusCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/sessions/user/abc", statusCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v1/sessions/user/1", body: `{"0": "0"}`, statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/sessions/user/1", body: `{"0": "0"}`, statusCode: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/v1/quiz/user?quiz=capitals", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/quiz/user?quiz=capitals", accept: "text/csv", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/statistics/other?quiz=capitals", statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/groups/team", token: "user", body: `{"kind": "team"}`, statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/groups/team", body: `{"kind": "team"}`, statusCode: http.StatusUnauthorized},
+		{method: http.MethodPut, url: "/v1/groups/class", token: "user", body: `{"kind": "guild"}`, statusCode: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/v1/groups", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/groups/team", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/groups/unknown", statusCode: http.StatusNotFound},
+		{method: http.MethodPut, url: "/v1/groups/team/members/other?role=owner", token: "user", statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/groups/team/members/user", token: "stranger", statusCode: http.StatusForbidden},
+		{method: http.MethodGet, url: "/v1/leaderboard", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/leaderboard?group=team", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/leaderboard?group=unknown", statusCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/leaderboard/groups", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/leaderboard", accept: "text/csv", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/leaderboard/groups", accept: "text/*, application/json;q=0.5", statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/leaderboard/groups", accept: "application/msgpack", statusCode: http.StatusOK},
+		{method: http.MethodDelete, url: "/v1/groups/team/members/other", token: "user", statusCode: http.StatusOK},
+		{method: http.MethodDelete, url: "/v1/groups/team/members/user", token: "user", statusCode: http.StatusBadRequest},
+		{method: http.MethodDelete, url: "/v1/admin/quizzes/capitals", token: testAdminToken, statusCode: http.StatusOK},
+		{method: http.MethodDelete, url: "/v1/admin/quizzes/capitals", token: testAdminToken, statusCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/rooms", statusCode: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/v1/rooms?quiz=unknown", statusCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/rooms/ABCDEF?user=alice", statusCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/rooms/ABCDEF", statusCode: http.StatusBadRequest},
+		{method: http.MethodPost, url: "/v1/graphql", body: `{"query": "{ user(name: \"user\") { name leaderboardRank } }"}`, statusCode: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/graphql", body: `{"query": "{ users(names: [\"unknown\"]) { name } }"}`, statusCode: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/graphql", body: `{"query": "{ user("}`, statusCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v1/admin/webhooks/chat", token: testAdminToken, body: `{"url": "http://localhost/hook", "events": ["quiz.passed"], "secret": "0123456789abcdef"}`, statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/admin/webhooks/chat", token: testAdminToken, body: `{"url": "http://localhost/hook", "events": ["quiz.failed"], "secret": "0123456789abcdef"}`, statusCode: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/v1/admin/webhooks", token: testAdminToken, statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/admin/webhooks", statusCode: http.StatusUnauthorized},
+		{method: http.MethodGet, url: "/v1/admin/dead-letters", token: testAdminToken, statusCode: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/admin/dead-letters/99/redeliver", token: testAdminToken, statusCode: http.StatusNotFound},
+		{method: http.MethodPost, url: "/v1/admin/dead-letters/abc/redeliver", token: testAdminToken, statusCode: http.StatusBadRequest},
+		{method: http.MethodDelete, url: "/v1/admin/webhooks/chat", token: testAdminToken, statusCode: http.StatusOK},
+		{method: http.MethodDelete, url: "/v1/admin/webhooks/chat", token: testAdminToken, statusCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/admin/export", token: testAdminToken, statusCode: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/admin/import?dry_run=true", token: testAdminToken, body: testArchive, statusCode: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/admin/import?mode=fail", token: testAdminToken, body: testArchive + `{"kind": "user", "user": {"name": "user"}}`, statusCode: http.StatusConflict},
+		{method: http.MethodPost, url: "/v1/admin/import", token: testAdminToken, body: `{"kind": "user", "user": {"name": "user"}}`, statusCode: http.StatusBadRequest},
+		{method: http.MethodPost, url: "/v1/admin/blobs", token: testAdminToken, body: testPNG, statusCode: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/admin/blobs", token: testAdminToken, body: "<svg></svg>", statusCode: http.StatusUnsupportedMediaType},
+		{method: http.MethodPost, url: "/v1/admin/blobs", token: testAdminToken, body: testPNG + strings.Repeat("a", quiz.MaxBlobSize), statusCode: http.StatusRequestEntityTooLarge},
+		{method: http.MethodGet, url: "/v1/blobs/" + quiz.BlobID([]byte(testPNG)), statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/blobs/" + quiz.BlobID([]byte(testPNG)), etag: `"` + quiz.BlobID([]byte(testPNG)) + `"`, statusCode: http.StatusNotModified},
+		{method: http.MethodGet, url: "/v1/blobs/" + quiz.BlobID(nil), statusCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/blobs/abc", statusCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v1/admin/questions/3", token: testAdminToken, body: `{"id": 3, "text": "Is 1 + 1 two?", "options": ["yes", "no"], "answer": "yes", "category": "arithmetic"}`, statusCode: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/admin/questions/3", token: testAdminToken, body: `{"id": 2, "text": "Is 1 + 1 two?", "options": ["yes", "no"], "answer": "yes"}`, statusCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v1/admin/questions/3", token: testAdminToken, body: `{"id": 3, "text": "Is 1 + 1 two?", "options": ["yes", "yes"], "answer": "yes"}`, statusCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v1/admin/questions/99", token: testAdminToken, body: `{"id": 99, "text": "?", "options": ["a"], "answer": "a"}`, statusCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/admin/questions/0/versions", token: testAdminToken, statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/admin/questions/99/versions", token: testAdminToken, statusCode: http.StatusNotFound},
+		{method: http.MethodPost, url: "/v1/admin/questions/0/regrade?dry_run=true", token: testAdminToken, statusCode: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/admin/questions/0/regrade", token: testAdminToken, statusCode: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/admin/questions/99/regrade", token: testAdminToken, statusCode: http.StatusNotFound},
+		{method: http.MethodPost, url: "/v1/admin/questions/abc/regrade", token: testAdminToken, statusCode: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/v1/admin/audit", token: testAdminToken, statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/admin/audit?outcome=denied&limit=1", token: testAdminToken, statusCode: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/admin/audit", statusCode: http.StatusUnauthorized},
+	}
+
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		r, err := http.NewRequestWithContext(context.Background(), tt.method, tt.url, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if token, ok := tokens[tt.token]; ok {
+			r.Header.Set("Authorization", "Bearer "+token)
+		} else if tt.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		if tt.key != "" {
+			r.Header.Set(headerIdempotencyKey, tt.key)
+		}
+		if tt.etag != "" {
+			r.Header.Set(headerIfNoneMatch, tt.etag)
+		}
+		if tt.accept != "" {
+			r.Header.Set(headerAccept, tt.accept)
+		}
+
+		w := httptest.NewRecorder()
+		handler.Mux.ServeHTTP(w, r)
+
+		if w.Code != tt.statusCode {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", tt.method, tt.url, tt.statusCode, w.Code, w.Body.String())
+		}
+
+		_, pattern := handler.Mux.Handler(r)
+		_, path, _ := strings.Cut(pattern, " ")
+		if route := "/" + apiVersionCurrent; strings.HasPrefix(path, route+"/") {
+			path = strings.TrimPrefix(path, route)
+		}
+
+		route, err := openAPIRoute(handler.openapi, tt.method, path)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.url, err)
+		}
+		covered[route.Operation.OperationID] = true
+
+		r, err = http.NewRequestWithContext(context.Background(), tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		r.Pattern = pattern
+
+		input := openAPIInput(route, r)
+		input.Options.IncludeResponseStatus = true
+		err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 w.Code,
+			Header:                 w.Header(),
+			Body:                   io.NopCloser(w.Body),
+			Options:                input.Options,
+		})
+		if err != nil {
+			t.Fatalf("%s %s: response drifted from openapi.json: %v", tt.method, tt.url, err)
+		}
+	}
+
+	for _, pathItem := range handler.openapi.Paths.Map() {
+		for method, operation := range pathItem.Operations() {
+			if !covered[operation.OperationID] {
+				t.Errorf("operation %s %s is not exercised", method, operation.OperationID)
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// problemCodes are stable, clients rely on them instead of the error messages
+var problemCodes = map[error]string{
+	ErrUserAlreadyExists:           quiz.CodeUserAlreadyExists,
+	ErrUserNotFound:                quiz.CodeUserNotFound,
+	ErrNotEnoughUsersForStatistics: quiz.CodeNotEnoughUsers,
+	ErrSessionNotFound:             quiz.CodeSessionNotFound,
+	ErrQuestionNotInSession:        quiz.CodeQuestionNotInSession,
+	ErrQuestionAlreadyAnswered:     quiz.CodeQuestionAlreadyAnswered,
+	ErrInvalidOption:               quiz.CodeInvalidOption,
+	ErrBatchedAnswers:              quiz.CodeBatchedAnswers,
+	ErrQuestionNotFound:            quiz.CodeQuestionNotFound,
+	ErrQuizNotFound:                quiz.CodeQuizNotFound,
+	ErrQuizClosed:                  quiz.CodeQuizClosed,
+	ErrMaxAttemptsReached:          quiz.CodeMaxAttemptsReached,
+	ErrGroupNotFound:               quiz.CodeGroupNotFound,
+	ErrGroupAlreadyExists:          quiz.CodeGroupAlreadyExists,
+	ErrNotGroupOwner:               quiz.CodeNotGroupOwner,
+	ErrLastGroupOwner:              quiz.CodeLastGroupOwner,
+	ErrRoomNotFound:                quiz.CodeRoomNotFound,
+	ErrUnsupportedVersion:          quiz.CodeUnsupportedVersion,
+	ErrQueryTooExpensive:           quiz.CodeQueryTooExpensive,
+	ErrWebhookNotFound:             quiz.CodeWebhookNotFound,
+	ErrDeliveryNotFound:            quiz.CodeDeliveryNotFound,
+	ErrIdempotencyKeyReused:        quiz.CodeIdempotencyKeyReused,
+	ErrIdempotencyKeyInUse:         quiz.CodeIdempotencyKeyInUse,
+	ErrNotAcceptable:               quiz.CodeNotAcceptable,
+	ErrInvalidArchive:              quiz.CodeInvalidArchive,
+	ErrImportConflict:              quiz.CodeImportConflict,
+	ErrBlobNotFound:                quiz.CodeBlobNotFound,
+	ErrBlobTooLarge:                quiz.CodeBlobTooLarge,
+	ErrUnsupportedMediaType:        quiz.CodeUnsupportedMediaType,
+}
+
+// statusCodes of the errors that are not one of problemCodes
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          quiz.CodeInvalidRequest,
+	http.StatusUnauthorized:        quiz.CodeUnauthorized,
+	http.StatusForbidden:           quiz.CodeForbidden,
+	http.StatusNotFound:            quiz.CodeNotFound,
+	http.StatusInternalServerError: quiz.CodeInternal,
+}
+
+// writeError logs the error and answers it as an RFC 7807 problem, internal errors are not detailed
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	h.logError(r, http.StatusText(status), err)
+	writeProblem(w, r, newProblem(r, status, err))
+}
+
+func newProblem(r *http.Request, status int, err error) *quiz.Problem {
+	printer := requestPrinter(r)
+	p := &quiz.Problem{
+		Type:      quiz.ProblemTypeDefault,
+		Title:     printer.Sprintf(http.StatusText(status)),
+		Status:    status,
+		Detail:    err.Error(),
+		Instance:  r.URL.Path,
+		Code:      statusCodes[status],
+		RequestID: fromContext(r, xRequestIDHeaderKey),
+		Errors:    fieldErrors(err),
+	}
+
+	if code := problemCode(err); code != "" {
+		p.Code = code
+	}
+	if p.Code == "" {
+		p.Code = quiz.CodeInvalidRequest
+	}
+	// only the sentinel errors are translated, wrapped ones carry details of the request
+	if _, ok := problemCodes[err]; ok {
+		p.Detail = printer.Sprintf(err.Error())
+	}
+	if status >= http.StatusInternalServerError {
+		p.Detail = p.Title
+	}
+	return p
+}
+
+// problemCode of the sentinel error err wraps, empty when it wraps none
+func problemCode(err error) string {
+	for sentinel, code := range problemCodes {
+		if errors.Is(err, sentinel) {
+			return code
+		}
+	}
+	return ""
+}
+
+// fieldErrors finds which fields are invalid, in errors of the handlers or of the openapi validation
+func fieldErrors(err error) []quiz.FieldError {
+	var fieldErr *quiz.FieldError
+	if errors.As(err, &fieldErr) {
+		return []quiz.FieldError{*fieldErr}
+	}
+
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return nil
+	}
+
+	var schemaErr *openapi3.SchemaError
+	reason := requestErr.Reason
+	if errors.As(err, &schemaErr) {
+		reason = schemaErr.Reason
+	}
+	if reason == "" && requestErr.Err != nil {
+		reason = requestErr.Err.Error()
+	}
+
+	switch {
+	case requestErr.Parameter != nil:
+		return []quiz.FieldError{{Field: requestErr.Parameter.Name, Reason: reason}}
+	case schemaErr != nil && len(schemaErr.JSONPointer()) > 0:
+		return []quiz.FieldError{{Field: strings.Join(schemaErr.JSONPointer(), "."), Reason: reason}}
+	default:
+		return []quiz.FieldError{{Field: "body", Reason: reason}}
+	}
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, p *quiz.Problem) {
+	w.Header().Set(headerContentType, quiz.ContentTypeProblem)
+	w.Header().Set(headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+	w.Header().Set(headerContentLanguage, quiz.MessageLocale(requestLocales(r)...).String())
+	// problems are not versioned like the data the handler tagged
+	w.Header().Del(headerETag)
+	w.Header().Del(headerCacheControl)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerProblems(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		header     string
+		value      string
+		body       string
+		statusCode int
+		code       string
+		field      string
+	}{
+		{name: "sentinel", method: http.MethodGet, url: "/v1/quiz/unknown", statusCode: http.StatusBadRequest, code: quiz.CodeUserNotFound},
+		{name: "not enough users", method: http.MethodGet, url: "/v1/statistics/user", statusCode: http.StatusBadRequest, code: quiz.CodeNotEnoughUsers},
+		{name: "not found", method: http.MethodGet, url: "/v1/quizzes/unknown", statusCode: http.StatusNotFound, code: quiz.CodeQuizNotFound},
+		{name: "unauthorized", method: http.MethodDelete, url: "/v1/admin/quizzes/capitals", statusCode: http.StatusUnauthorized, code: quiz.CodeUnauthorized},
+		{name: "unsupported version", method: http.MethodGet, url: "/quizzes", header: "API-Version", value: "v0", statusCode: http.StatusBadRequest, code: quiz.CodeUnsupportedVersion},
+		{name: "handler field", method: http.MethodPut, url: "/v1/admin/quizzes/Capitals", header: "Authorization", value: "Bearer " + testAdminToken, body: `{}`, statusCode: http.StatusBadRequest, code: quiz.CodeInvalidRequest, field: "slug"},
+		{name: "openapi parameter", method: http.MethodGet, url: "/v1/sessions/user/abc", statusCode: http.StatusBadRequest, code: quiz.CodeInvalidRequest, field: "session"},
+		{name: "openapi body", method: http.MethodPut, url: "/v1/admin/quizzes/capitals", header: "Authorization", value: "Bearer " + testAdminToken, body: `{"pass_mark": 2}`, statusCode: http.StatusBadRequest, code: quiz.CodeInvalidRequest, field: "pass_mark"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+
+			w := httptest.NewRecorder()
+			handler.Mux.ServeHTTP(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.statusCode, w.Code, w.Body.String())
+			}
+
+			if got := w.Header().Get("Content-Type"); got != quiz.ContentTypeProblem {
+				t.Fatalf("expected content type %s, got %s", quiz.ContentTypeProblem, got)
+			}
+
+			var problem quiz.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("failed to unmarshal body: %v", err)
+			}
+
+			if problem.Code != tt.code || problem.Status != tt.statusCode || problem.RequestID != "123" || problem.Instance != r.URL.Path {
+				t.Fatalf("expected code %s with the request ID, got %+v", tt.code, problem)
+			}
+
+			if tt.field == "" {
+				return
+			}
+			if len(problem.Errors) != 1 || problem.Errors[0].Field != tt.field || problem.Errors[0].Reason == "" {
+				t.Fatalf("expected field error of %s, got %+v", tt.field, problem.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package server
+
+import (
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	roomCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	roomCodeLength   = 6
+	// used when neither the host nor the quiz sets a question time limit
+	roomQuestionTimeLimit = 20 * time.Second
+	// between the ranking of a question and the next one
+	roomPause        = 3 * time.Second
+	roomWriteTimeout = 5 * time.Second
+	roomReadLimit    = 4096
+	// a connection that answers no ping within roomPongWait is gone, it leaves the room
+	roomPongWait = 60 * time.Second
+)
+
+var ErrRoomNotFound = errors.New("room not found")
+
+// rooms are live, they only exist in memory while their host is connected
+type rooms struct {
+	lock     sync.Mutex
+	rooms    map[string]*room
+	pause    time.Duration
+	pongWait time.Duration
+}
+
+func newRooms() *rooms {
+	return &rooms{rooms: map[string]*room{}, pause: roomPause, pongWait: roomPongWait}
+}
+
+// open creates a room under a new code and runs it until the quiz finishes or the host leaves
+func (rs *rooms) open(slog *slog.Logger, host *websocket.Conn, q quiz.Quiz, questions []quiz.Question, timeLimit time.Duration) *room {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	code := newRoomCode()
+	for rs.rooms[code] != nil {
+		code = newRoomCode()
+	}
+
+	r := &room{
+		slog:      slog.With("room", code, "quiz", q.Slug),
+		code:      code,
+		questions: questions,
+		timeLimit: timeLimit,
+		pause:     rs.pause,
+		pongWait:  rs.pongWait,
+		events:    make(chan roomEvent),
+		done:      make(chan struct{}),
+		host:      host,
+		players:   map[string]*websocket.Conn{},
+		scores:    map[string]*quiz.RoomScore{},
+	}
+	rs.rooms[code] = r
+
+	go func() {
+		r.run()
+		rs.lock.Lock()
+		delete(rs.rooms, code)
+		rs.lock.Unlock()
+	}()
+	return r
+}
+
+func (rs *rooms) get(code string) (*room, error) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	r, ok := rs.rooms[code]
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+	return r, nil
+}
+
+func newRoomCode() string {
+	b := make([]byte, roomCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = roomCodeAlphabet[int(b[i])%len(roomCodeAlphabet)]
+	}
+	return string(b)
+}
+
+// roomEvent is whatever happens to a connection, a join, a message or a leave
+type roomEvent struct {
+	player  string
+	conn    *websocket.Conn
+	join    bool
+	leave   bool
+	message quiz.RoomMessage
+}
+
+// room state is owned by the run goroutine, connections only talk to it through events,
+// which also makes run the only writer of every connection
+type room struct {
+	slog      *slog.Logger
+	code      string
+	questions []quiz.Question
+	timeLimit time.Duration
+	pause     time.Duration
+	pongWait  time.Duration
+	events    chan roomEvent
+	done      chan struct{}
+
+	host    *websocket.Conn
+	players map[string]*websocket.Conn
+	scores  map[string]*quiz.RoomScore
+	started bool
+}
+
+// dispatch hands the event to the room, false once the room is closed
+func (r *room) dispatch(e roomEvent) bool {
+	select {
+	case r.events <- e:
+		return true
+	case <-r.done:
+		return false
+	}
+}
+
+// read dispatches the messages of the connection until it is closed or stops answering the pings
+func (r *room) read(player string, conn *websocket.Conn) {
+	conn.SetReadLimit(roomReadLimit)
+	conn.SetReadDeadline(time.Now().Add(r.pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(r.pongWait))
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go r.ping(conn, stop)
+
+	for {
+		var m quiz.RoomMessage
+		if err := conn.ReadJSON(&m); err != nil {
+			r.dispatch(roomEvent{player: player, conn: conn, leave: true})
+			return
+		}
+		if !r.dispatch(roomEvent{player: player, conn: conn, message: m}) {
+			return
+		}
+	}
+}
+
+// ping keeps the read deadline of an idle connection alive, WriteControl is safe next to the writes of run
+func (r *room) ping(conn *websocket.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(r.pongWait * 9 / 10)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(roomWriteTimeout)); err != nil {
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (r *room) run() {
+	defer r.close()
+
+	r.slog.Info("room opened")
+	r.send(r.host, quiz.RoomMessage{Type: quiz.RoomMessageRoom, Code: r.code, Count: len(r.questions), TimeLimitMs: r.timeLimit.Milliseconds()})
+
+	if !r.lobby() {
+		return
+	}
+
+	for i, q := range r.questions {
+		if !r.ask(i+1, q) {
+			return
+		}
+		if i < len(r.questions)-1 && !r.wait(r.pause) {
+			return
+		}
+	}
+
+	r.broadcast(quiz.RoomMessage{Type: quiz.RoomMessageFinished, Count: len(r.questions), Ranking: r.ranking()})
+}
+
+// lobby waits for players until the host starts, false when the host left
+func (r *room) lobby() bool {
+	for e := range r.events {
+		if !r.handle(e) {
+			return false
+		}
+		if e.conn != r.host || e.message.Type != quiz.RoomMessageStart {
+			continue
+		}
+		if len(r.players) == 0 {
+			r.send(r.host, quiz.RoomMessage{Type: quiz.RoomMessageError, Error: "no players yet"})
+			continue
+		}
+		r.started = true
+		return true
+	}
+	return false
+}
+
+// ask pushes the question to everyone and scores the answers until all players answered
+// or the time is up, false when the host left
+func (r *room) ask(number int, q quiz.Question) bool {
+	for _, score := range r.scores {
+		score.Points = 0
+	}
+
+	r.broadcast(quiz.RoomMessage{Type: quiz.RoomMessageQuestion, Question: &q, Number: number, Count: len(r.questions), TimeLimitMs: r.timeLimit.Milliseconds()})
+	asked := time.Now()
+	timer := time.NewTimer(r.timeLimit)
+	defer timer.Stop()
+
+	answered := map[string]bool{}
+	for r.waiting(answered) {
+		select {
+		case <-timer.C:
+			r.rank(number, q)
+			return true
+		case e := <-r.events:
+			if !r.handle(e) {
+				return false
+			}
+
+			// late answers of a previous question are ignored
+			m := e.message
+			if e.conn == r.host || m.Type != quiz.RoomMessageAnswer || m.Number != number || m.Option == nil || answered[e.player] {
+				continue
+			}
+
+			answered[e.player] = true
+			score := r.scores[e.player]
+			score.Points = quiz.RoomPoints(q.IsCorrect(*m.Option), time.Since(asked), r.timeLimit)
+			if score.Points > 0 {
+				score.Correct++
+			}
+			score.Score += score.Points
+		}
+	}
+	r.rank(number, q)
+	return true
+}
+
+// waiting tells if a connected player has not answered yet
+func (r *room) waiting(answered map[string]bool) bool {
+	for player := range r.players {
+		if !answered[player] {
+			return true
+		}
+	}
+	return false
+}
+
+// rank reveals the correct option with the ranking so far
+func (r *room) rank(number int, q quiz.Question) {
+	r.broadcast(quiz.RoomMessage{Type: quiz.RoomMessageRanking, Number: number, Count: len(r.questions), Option: &q.Answer, Ranking: r.ranking()})
+}
+
+// wait keeps handling joins and leaves for a while, false when the host left
+func (r *room) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case e := <-r.events:
+			if !r.handle(e) {
+				return false
+			}
+		}
+	}
+}
+
+// handle takes care of joins and leaves, false when the host left
+func (r *room) handle(e roomEvent) bool {
+	switch {
+	case e.join && r.started:
+		r.reject(e.conn, "room already started")
+	case e.join && r.players[e.player] != nil:
+		r.reject(e.conn, "player name already taken")
+	case e.join:
+		r.players[e.player] = e.conn
+		if r.scores[e.player] == nil {
+			r.scores[e.player] = &quiz.RoomScore{Player: e.player}
+		}
+		r.slog.Info("player joined", "player", e.player)
+		r.broadcast(quiz.RoomMessage{Type: quiz.RoomMessagePlayers, Players: r.names()})
+	case e.leave && e.conn == r.host:
+		r.slog.Info("host left")
+		return false
+	case e.leave && r.players[e.player] == e.conn:
+		delete(r.players, e.player)
+		// once started, players who left keep their place in the ranking
+		if !r.started {
+			delete(r.scores, e.player)
+		}
+		r.slog.Info("player left", "player", e.player)
+		r.broadcast(quiz.RoomMessage{Type: quiz.RoomMessagePlayers, Players: r.names()})
+	}
+	return true
+}
+
+func (r *room) reject(conn *websocket.Conn, reason string) {
+	r.send(conn, quiz.RoomMessage{Type: quiz.RoomMessageError, Error: reason})
+	conn.Close()
+}
+
+func (r *room) names() []string {
+	names := make([]string, 0, len(r.players))
+	for player := range r.players {
+		names = append(names, player)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (r *room) ranking() []quiz.RoomScore {
+	ranking := make([]quiz.RoomScore, 0, len(r.scores))
+	for _, score := range r.scores {
+		ranking = append(ranking, *score)
+	}
+	return quiz.Rank(ranking)
+}
+
+func (r *room) broadcast(m quiz.RoomMessage) {
+	r.send(r.host, m)
+	for _, conn := range r.players {
+		r.send(conn, m)
+	}
+}
+
+// send gives up on slow connections, their reader notices and the player leaves
+func (r *room) send(conn *websocket.Conn, m quiz.RoomMessage) {
+	conn.SetWriteDeadline(time.Now().Add(roomWriteTimeout))
+	if err := conn.WriteJSON(m); err != nil {
+		r.slog.Debug("room write failed", "error", err)
+		conn.Close()
+	}
+}
+
+func (r *room) close() {
+	close(r.done)
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	for _, conn := range append(slices.Collect(maps.Values(r.players)), r.host) {
+		conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(roomWriteTimeout))
+		conn.Close()
+	}
+	r.slog.Info("room closed")
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// RotatingFile appends to the file at path, once a write would grow it past maxSize the file is
+// renamed to path.1, path.1 to path.2 and so on, keeping that many backups. Every write lands in
+// a single file, so lines written at once are never split. It is safe for concurrent use.
+type RotatingFile struct {
+	path    string
+	maxSize int64
+	backups int
+	lock    sync.Mutex
+	file    *os.File
+	size    int64
+}
+
+func OpenRotatingFile(path string, maxSize int64, backups int) (*RotatingFile, error) {
+	if maxSize <= 0 || backups < 0 {
+		return nil, fmt.Errorf("rotating file `%s`: use a positive size and backups", path)
+	}
+	f := &RotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+func (f *RotatingFile) Write(b []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file == nil {
+		return 0, fs.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(b)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate must be called holding lock, the oldest backup is overwritten. When a rename fails
+// the file is opened again, so the writes that follow are not lost.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if err := f.renameBackups(); err != nil {
+		return errors.Join(err, f.open())
+	}
+	return f.open()
+}
+
+func (f *RotatingFile) renameBackups() error {
+	for i := f.backups - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if f.backups > 0 {
+		return os.Rename(f.path, f.path+".1")
+	}
+	return os.Remove(f.path)
+}
+
+func (f *RotatingFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// userTokenSize in random bytes, the token is their base64
+const userTokenSize = 32
+
+// newUserToken is the bearer token of a new user, only its hash is kept
+func newUserToken() string {
+	b := make([]byte, userTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken is the SHA-256 of the token in hex, what the store keeps of it
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const tracerName = "github.com/vrnvu/temp/internal/server"
+
+// the W3C `traceparent` and `tracestate`, the only propagation the cli and the server speak
+var propagator = propagation.TraceContext{}
+
+var attributeRequestID = attribute.Key("request.id")
+
+// withTracing continues the trace of the `traceparent` of the request, or starts one, in a span of the
+// route, logs written with the context of the request carry its trace ID
+func withTracing(tracer trace.Tracer, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Pattern, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.HTTPRoute(route(r)),
+			semconv.URLPath(r.URL.Path),
+			attributeRequestID.String(fromContext(r, xRequestIDHeaderKey)),
+		))
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	}
+}
+
+// route is the pattern of the mux without its method, like `/v1/quiz/{user}`
+func route(r *http.Request) string {
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return path
+	}
+	return r.Pattern
+}
+
+// withGRPCTracing mirrors withTracing, the `traceparent` travels in the metadata
+func withGRPCTracing(tracer trace.Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = propagator.Extract(ctx, metadataCarrier(md))
+
+		service, method, _ := strings.Cut(strings.TrimPrefix(info.FullMethod, "/"), "/")
+		ctx, span := tracer.Start(ctx, info.FullMethod, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(method),
+			attributeRequestID.String(grpcRequestID(ctx)),
+		))
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			span.SetStatus(codes.Error, status.Convert(err).Message())
+		}
+		return resp, err
+	}
+}
+
+// metadataCarrier reads the propagated fields of incoming gRPC metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// TraceLogHandler adds the trace and span IDs of the context to the records, so the logs of a request
+// are found from its trace. Only the records logged with a context, like slog.InfoContext, have them.
+type TraceLogHandler struct {
+	slog.Handler
+}
+
+func (h TraceLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h TraceLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return TraceLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h TraceLogHandler) WithGroup(name string) slog.Handler {
+	return TraceLogHandler{h.Handler.WithGroup(name)}
+}
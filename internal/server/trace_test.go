@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHandlerTracing(t *testing.T) {
+	t.Parallel()
+	recorder := tracetest.NewSpanRecorder()
+	logs := &bytes.Buffer{}
+	handler, err := FromConfig(&Config{
+		Slog: slog.New(TraceLogHandler{slog.NewJSONHandler(logs, nil)}),
+		RequestIDGenerator: func() string {
+			return "123"
+		},
+		AdminToken:     testAdminToken,
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/v1/quiz", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	r.Header.Set(headerContentType, valueContentTypeJSON)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Get(string(xRequestIDHeaderKey)); got != "123" {
+		t.Fatalf("expected the request ID 123 in the response, got %q", got)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	server, ok := spans["GET /v1/quiz"]
+	if !ok {
+		t.Fatalf("expected a span of the route, got %v", spans)
+	}
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Fatalf("expected a server span, got %v", server.SpanKind())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected the trace of the traceparent, got %s", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Fatalf("expected the span of the traceparent as parent, got %s", got)
+	}
+	attributes := map[string]string{}
+	for _, a := range server.Attributes() {
+		attributes[string(a.Key)] = a.Value.Emit()
+	}
+	for key, want := range map[string]string{"http.route": "/v1/quiz", "request.id": "123", "http.response.status_code": "200"} {
+		if attributes[key] != want {
+			t.Fatalf("expected %s %q, got %q", key, want, attributes[key])
+		}
+	}
+
+	store, ok := spans["InMemoryDB.GetQuestions"]
+	if !ok {
+		t.Fatalf("expected a span of the store, got %v", spans)
+	}
+	if store.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatalf("expected the span of the store in the span of the route")
+	}
+
+	var record map[string]any
+	if err := json.NewDecoder(logs).Decode(&record); err != nil {
+		t.Fatalf("failed to decode log: %v", err)
+	}
+	if record["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || record["span_id"] != server.SpanContext().SpanID().String() {
+		t.Fatalf("expected the trace and span IDs in the log, got %v", record)
+	}
+}
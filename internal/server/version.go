@@ -0,0 +1,87 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	headerAPIVersion  = "API-Version"
+	headerDeprecation = "Deprecation"
+	headerSunset      = "Sunset"
+	headerLink        = "Link"
+)
+
+const apiVersionCurrent = "v1"
+
+var apiVersionsSupported = []string{apiVersionCurrent}
+
+var ErrUnsupportedVersion = errors.New("unsupported " + headerAPIVersion)
+
+var (
+	// when the unversioned paths became aliases of apiVersionCurrent
+	unversionedDeprecation = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	unversionedSunset      = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// handle mounts the route under every supported version, the unversioned path stays as an alias
+// of the current version. Like the mux with conflicting patterns, it panics on undocumented routes.
+func (h *Handler) handle(method string, path string, requestIDGenerator func() string, next http.HandlerFunc) {
+	route, err := openAPIRoute(h.openapi, method, path)
+	if err != nil {
+		panic(err)
+	}
+	next = withOpenAPIValidation(h.Slog, route, next)
+	if acceptsIdempotencyKey(route) {
+		next = withIdempotency(h.Slog, h.idempotency, next)
+	}
+	next = withNegotiation(h.Slog, operationMediaTypes(route), next)
+	if audited(route) {
+		next = h.withAudit(route, next)
+	}
+
+	for _, version := range apiVersionsSupported {
+		h.Mux.HandleFunc(method+" /"+version+path, withBaseMiddleware(h.Slog, h.metrics, h.tracer, requestIDGenerator, withAPIVersion(version, next)))
+	}
+	h.Mux.HandleFunc(method+" "+path, withBaseMiddleware(h.Slog, h.metrics, h.tracer, requestIDGenerator, withUnversioned(h.Slog, next)))
+}
+
+func withAPIVersion(version string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerAPIVersion, version)
+		next.ServeHTTP(w, r)
+	}
+}
+
+// withUnversioned serves the version asked in the `API-Version` header, without it the request
+// is answered by the current version with the deprecation headers of RFC 9745 and RFC 8594
+func withUnversioned(slog *slog.Logger, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := r.Header.Get(headerAPIVersion)
+		if version == "" {
+			w.Header().Set(headerDeprecation, fmt.Sprintf("@%d", unversionedDeprecation.Unix()))
+			w.Header().Set(headerSunset, unversionedSunset.Format(http.TimeFormat))
+			w.Header().Set(headerLink, fmt.Sprintf(`</%s%s>; rel="successor-version"`, apiVersionCurrent, r.URL.Path))
+			withAPIVersion(apiVersionCurrent, next).ServeHTTP(w, r)
+			return
+		}
+
+		if !slices.Contains(apiVersionsSupported, version) {
+			err := fmt.Errorf("%w: `%s`, try: %v", ErrUnsupportedVersion, version, apiVersionsSupported)
+			slog.ErrorContext(r.Context(), http.StatusText(http.StatusBadRequest), "error", err, "method", r.Method, "path", r.URL.Path, headerXRequestID, fromContext(r, xRequestIDHeaderKey))
+			writeProblem(w, r, newProblem(r, http.StatusBadRequest, err))
+			return
+		}
+		withAPIVersion(version, next).ServeHTTP(w, r)
+	}
+}
+
+func (h *Handler) getVersions(w http.ResponseWriter, r *http.Request) {
+	h.writeData(w, r, quiz.APIVersions{Current: apiVersionCurrent, Supported: apiVersionsSupported, Sunset: unversionedSunset})
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func TestHandlerVersions(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+
+	tests := []struct {
+		name        string
+		url         string
+		apiVersion  string
+		statusCode  int
+		deprecated  bool
+		wantVersion string
+	}{
+		{name: "versioned", url: "/v1/quiz/user", statusCode: http.StatusOK, deprecated: false, wantVersion: "v1"},
+		{name: "unversioned alias", url: "/quiz/user", statusCode: http.StatusOK, deprecated: true, wantVersion: "v1"},
+		{name: "version header", url: "/quiz/user", apiVersion: "v1", statusCode: http.StatusOK, deprecated: false, wantVersion: "v1"},
+		{name: "unsupported version header", url: "/quiz/user", apiVersion: "v0", statusCode: http.StatusBadRequest, deprecated: false, wantVersion: ""},
+		{name: "unknown version", url: "/v0/quiz/user", statusCode: http.StatusNotFound, deprecated: false, wantVersion: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if tt.apiVersion != "" {
+				r.Header.Set("API-Version", tt.apiVersion)
+			}
+
+			w := httptest.NewRecorder()
+			handler.Mux.ServeHTTP(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.statusCode, w.Code, w.Body.String())
+			}
+
+			if got := w.Header().Get("API-Version"); got != tt.wantVersion {
+				t.Fatalf("expected API-Version `%s`, got `%s`", tt.wantVersion, got)
+			}
+
+			deprecated := w.Header().Get("Deprecation") != "" && w.Header().Get("Sunset") != ""
+			if deprecated != tt.deprecated {
+				t.Fatalf("expected deprecated %t, got headers %v", tt.deprecated, w.Header())
+			}
+			if tt.deprecated && w.Header().Get("Link") != `</v1/quiz/user>; rel="successor-version"` {
+				t.Fatalf("expected a link to the successor version, got `%s`", w.Header().Get("Link"))
+			}
+		})
+	}
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/versions", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.Mux.ServeHTTP(w, r)
+
+	var versions quiz.APIVersions
+	if err := json.Unmarshal(w.Body.Bytes(), &versions); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+
+	if versions.Current != "v1" || len(versions.Supported) != 1 || versions.Sunset.IsZero() {
+		t.Fatalf("expected v1 with a sunset of the aliases, got %+v", versions)
+	}
+}
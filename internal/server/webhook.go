@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+const (
+	defaultWebhookTimeout = 10 * time.Second
+	defaultWebhookBackoff = time.Second
+)
+
+type webhookConfig struct {
+	client  *http.Client
+	backoff time.Duration
+}
+
+func newWebhookConfig(c *Config) webhookConfig {
+	config := webhookConfig{client: c.WebhookClient, backoff: c.WebhookBackoff}
+	if config.client == nil {
+		config.client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+	if config.backoff <= 0 {
+		config.backoff = defaultWebhookBackoff
+	}
+	return config
+}
+
+// RunWebhooks delivers the queued webhooks until ctx is done. Every subscription has a worker of its
+// own while it has deliveries due, so a slow receiver only delays its own deliveries. A worker sends
+// them one at a time, failed ones are retried with an exponential backoff until they go to the dead
+// letters.
+func (h *Handler) RunWebhooks(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	var workers sync.WaitGroup
+	defer workers.Wait()
+	// busy are the subscriptions with a worker, done receives them once it is finished
+	busy := map[string]bool{}
+	done := make(chan string)
+
+	for {
+		due, webhooks, next := h.db.dueDeliveries(h.db.now())
+		queues := map[string][]quiz.WebhookDelivery{}
+		for _, d := range due {
+			if !busy[d.Webhook] {
+				queues[d.Webhook] = append(queues[d.Webhook], d)
+			}
+		}
+		for slug, queue := range queues {
+			busy[slug] = true
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				h.deliverQueue(ctx, webhooks[slug], queue)
+				select {
+				case done <- slug:
+				case <-ctx.Done():
+				}
+			}()
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if !next.IsZero() {
+			timer.Reset(next.Sub(h.db.now()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case slug := <-done:
+			// recorded failures may be due before next
+			delete(busy, slug)
+		case <-h.db.webhookSignal:
+		case <-timer.C:
+		}
+	}
+}
+
+// deliverQueue sends the due deliveries of one subscription in order
+func (h *Handler) deliverQueue(ctx context.Context, w quiz.Webhook, queue []quiz.WebhookDelivery) {
+	for _, d := range queue {
+		if ctx.Err() != nil {
+			return
+		}
+		err := h.deliver(ctx, w, d)
+		if err != nil {
+			h.Slog.Warn("webhook delivery failed", "webhook", d.Webhook, "delivery", d.ID, "attempt", d.Attempts+1, "error", err)
+		}
+		h.db.recordDelivery(d.ID, err, h.webhooks.backoff)
+	}
+}
+
+func (h *Handler) deliver(ctx context.Context, w quiz.Webhook, d quiz.WebhookDelivery) error {
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set(headerContentType, valueContentTypeJSON)
+	r.Header.Set(quiz.HeaderWebhookEvent, d.Event.Type)
+	r.Header.Set(quiz.HeaderWebhookDelivery, strconv.FormatUint(d.ID, 10))
+	r.Header.Set(quiz.HeaderWebhookSignature, quiz.SignWebhook(w.Secret, body, time.Now()))
+
+	resp, err := h.webhooks.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drained so the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
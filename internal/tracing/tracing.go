@@ -0,0 +1,91 @@
+// Package tracing configures the OpenTelemetry traces of the server and the cli from the environment
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Exporters of OTEL_TRACES_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+var Exporters = []string{ExporterNone, ExporterStdout, ExporterFile, ExporterOTLP}
+
+// FromEnv answers the tracer provider of the service and its shutdown, which sends the spans not sent yet.
+// OTEL_TRACES_EXPORTER tells where the spans go:
+//   - none, the default, records nothing
+//   - stdout, writes them as JSON to stdout
+//   - file, appends them as JSON to OTEL_TRACES_FILE, so traces work without a collector
+//   - otlp, sends them over HTTP to OTEL_EXPORTER_OTLP_ENDPOINT, http://localhost:4318 by default
+func FromEnv(ctx context.Context, service string) (trace.TracerProvider, func(context.Context) error, error) {
+	name, ok := os.LookupEnv("OTEL_TRACES_EXPORTER")
+	if !ok {
+		name = ExporterNone
+	}
+
+	var exporter sdktrace.SpanExporter
+	var file io.Closer
+	switch name {
+	case ExporterNone:
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		e, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter = e
+	case ExporterFile:
+		path := os.Getenv("OTEL_TRACES_FILE")
+		if path == "" {
+			return nil, nil, fmt.Errorf("OTEL_TRACES_EXPORTER `%s` needs OTEL_TRACES_FILE", name)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, nil, err
+		}
+		e, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		exporter, file = e, f
+	case ExporterOTLP:
+		e, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter = e
+	default:
+		return nil, nil, fmt.Errorf("invalid OTEL_TRACES_EXPORTER: `%s`, try: %v", name, Exporters)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+	shutdown := func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}
+	return provider, shutdown, nil
+}
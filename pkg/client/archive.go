@@ -0,0 +1,164 @@
+package client
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// ImportOptions of an import, the zero value stops at the first record that already exists
+type ImportOptions struct {
+	// Mode is one of quiz.ImportModes, quiz.ImportFail when empty
+	Mode string
+	// DryRun counts what the import would change without writing anything
+	DryRun bool
+}
+
+// Export writes the NDJSON archive of the server to w as it arrives, it needs the admin token.
+// Archives are streamed, so the call is neither retried nor bounded by the AttemptTimeout.
+func (c *Client) Export(ctx context.Context, w io.Writer) error {
+	resp, err := c.stream(ctx, http.MethodGet, "/admin/export", nil, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Import sends the NDJSON archive as it reads it, it needs the admin token. The records imported
+// before a failure stay imported, a dry run finds the failures first.
+// Archives are streamed, so the call is neither retried nor bounded by the AttemptTimeout.
+func (c *Client) Import(ctx context.Context, archive io.Reader, opts ImportOptions) (quiz.ImportSummary, error) {
+	query := url.Values{}
+	if opts.Mode != "" {
+		query.Set("mode", opts.Mode)
+	}
+	if opts.DryRun {
+		query.Set("dry_run", "true")
+	}
+
+	resp, err := c.stream(ctx, http.MethodPost, "/admin/import", query, quiz.ContentTypeNDJSON, archive)
+	if err != nil {
+		return quiz.ImportSummary{}, err
+	}
+	defer resp.Body.Close()
+
+	var summary quiz.ImportSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return quiz.ImportSummary{}, fmt.Errorf("invalid response of %s %s: %w", http.MethodPost, "/admin/import", err)
+	}
+	return summary, nil
+}
+
+// ExportQuestions are every question of the server with its answer, read from the archive of Export
+func (c *Client) ExportQuestions(ctx context.Context) ([]quiz.Question, error) {
+	resp, err := c.stream(ctx, http.MethodGet, "/admin/export", nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	questions := []quiz.Question{}
+	d := json.NewDecoder(resp.Body)
+	for {
+		var record quiz.ArchiveRecord
+		err := d.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return questions, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid response of %s %s: %w", http.MethodGet, "/admin/export", err)
+		}
+		if record.Kind == quiz.RecordQuestion && record.Question != nil {
+			questions = append(questions, record.Question.Question())
+		}
+	}
+}
+
+// ImportQuestions sends the questions as an archive to Import, like the ones of quiz.ParseQuestions.
+// The questions without ID are new, they are numbered after the last question of the server.
+func (c *Client) ImportQuestions(ctx context.Context, questions []quiz.ParsedQuestion, opts ImportOptions) (quiz.ImportSummary, error) {
+	records := make([]quiz.ArchiveQuestion, 0, len(questions))
+	var next uint64
+	news := false
+	for _, q := range questions {
+		if q.HasID {
+			next = max(next, q.ID+1)
+		}
+		news = news || !q.HasID
+	}
+	if news {
+		existing, err := c.ExportQuestions(ctx)
+		if err != nil {
+			return quiz.ImportSummary{}, err
+		}
+		for _, q := range existing {
+			next = max(next, q.ID+1)
+		}
+	}
+
+	for _, q := range questions {
+		if !q.HasID {
+			q.ID = next
+			next++
+		}
+		records = append(records, quiz.NewArchiveQuestion(q.Question))
+	}
+	// a question can only be created right after the last one, so they are sent in order
+	slices.SortStableFunc(records, func(a, b quiz.ArchiveQuestion) int { return cmp.Compare(a.ID, b.ID) })
+
+	var archive bytes.Buffer
+	e := json.NewEncoder(&archive)
+	if err := e.Encode(quiz.ArchiveRecord{Kind: quiz.RecordArchive, Archive: &quiz.ArchiveHeader{Version: quiz.ArchiveVersion, ExportedAt: time.Now().UTC()}}); err != nil {
+		return quiz.ImportSummary{}, err
+	}
+	for _, record := range records {
+		if err := e.Encode(quiz.ArchiveRecord{Kind: quiz.RecordQuestion, Question: &record}); err != nil {
+			return quiz.ImportSummary{}, err
+		}
+	}
+	return c.Import(ctx, &archive, opts)
+}
+
+// stream sends the call once with body as it is read, the caller closes the body of the response.
+// Its span ends once the response is answered, before its body is read.
+func (c *Client) stream(ctx context.Context, method string, path string, query url.Values, contentType string, body io.Reader) (_ *http.Response, err error) {
+	ctx, span := c.startSpan(ctx, method, path)
+	defer func() { endSpan(span, err) }()
+
+	u := c.apiBase + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	c.header(ctx, req.Header)
+	if body != nil {
+		req.Header.Set(headerContentType, contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, problemOf(resp)
+	}
+	return resp, nil
+}
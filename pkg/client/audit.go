@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// AuditFilter of the audit log, empty fields match any event
+type AuditFilter struct {
+	Actor   string
+	Action  string
+	Outcome string
+	// Before is the ID of the last event of the previous page
+	Before uint64
+	// Limit defaults to 100 events
+	Limit int
+}
+
+// AuditLog answers the audit events matching the filter, the latest first, it needs the admin token
+func (c *Client) AuditLog(ctx context.Context, filter AuditFilter) ([]quiz.AuditEvent, error) {
+	query := url.Values{}
+	for name, value := range map[string]string{"actor": filter.Actor, "action": filter.Action, "outcome": filter.Outcome} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if filter.Before != 0 {
+		query.Set("before", strconv.FormatUint(filter.Before, 10))
+	}
+	if filter.Limit != 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	var events []quiz.AuditEvent
+	err := c.do(ctx, call{method: http.MethodGet, path: "/admin/audit", query: query, out: &events, idempotent: true})
+	return events, err
+}
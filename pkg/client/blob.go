@@ -0,0 +1,56 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// UploadBlob stores the image for the questions to attach, it needs the admin token. Blobs are
+// addressed by their content, so uploading the same image again gives the same blob.
+func (c *Client) UploadBlob(ctx context.Context, image io.Reader) (quiz.Blob, error) {
+	// one byte more than the limit tells a blob too large apart without reading all of it
+	data, err := io.ReadAll(io.LimitReader(image, quiz.MaxBlobSize+1))
+	if err != nil {
+		return quiz.Blob{}, err
+	}
+	if len(data) > quiz.MaxBlobSize {
+		return quiz.Blob{}, fmt.Errorf("blob larger than %d bytes", quiz.MaxBlobSize)
+	}
+
+	resp, err := c.stream(ctx, http.MethodPost, "/admin/blobs", nil, http.DetectContentType(data), bytes.NewReader(data))
+	if err != nil {
+		return quiz.Blob{}, err
+	}
+	defer resp.Body.Close()
+
+	var blob quiz.Blob
+	if err := json.NewDecoder(resp.Body).Decode(&blob); err != nil {
+		return quiz.Blob{}, fmt.Errorf("invalid response of %s %s: %w", http.MethodPost, "/admin/blobs", err)
+	}
+	return blob, nil
+}
+
+// Blob writes the image to w and answers its media type
+func (c *Client) Blob(ctx context.Context, id string, w io.Writer) (string, error) {
+	resp, err := c.stream(ctx, http.MethodGet, "/blobs/"+id, nil, "", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return "", err
+	}
+	return resp.Header.Get(headerContentType), nil
+}
+
+// BlobURL where the image is served, for the callers that link to it rather than download it
+func (c *Client) BlobURL(id string) string {
+	return c.apiBase + "/blobs/" + id
+}
@@ -0,0 +1,306 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	headerContentType    = "Content-Type"
+	headerAuthorization  = "Authorization"
+	headerXRequestID     = "X-Request-ID"
+	headerIdempotencyKey = "Idempotency-Key"
+	headerAcceptLanguage = "Accept-Language"
+	valueContentTypeJSON = "application/json"
+)
+
+const (
+	defaultRetries      = 2
+	defaultRetryBackoff = 100 * time.Millisecond
+)
+
+type Config struct {
+	// BaseURL of the server without a version, e.g. https://localhost:8080
+	BaseURL string
+	// Token is sent as `Authorization: Bearer <token>`, the token CreateUser returned or the admin token
+	Token string
+	// TLS of the default HTTP client and of the room websockets
+	TLS *tls.Config
+	// HTTPClient replaces the default client, TLS is then ignored for plain requests
+	HTTPClient *http.Client
+	// Retries of calls that failed on the network or with a 502, 503 or 504,
+	// zero uses the default and a negative value disables them
+	Retries int
+	// RetryBackoff doubles after every retry
+	RetryBackoff time.Duration
+	// AttemptTimeout bounds every attempt of a call, a timed out attempt is retried, zero means no bound
+	AttemptTimeout time.Duration
+	// Language is sent as `Accept-Language`, the questions and problems come translated into it
+	Language string
+	// TracerProvider of the spans of the calls, defaults to the global one of otel. Calls send the
+	// trace of their context as a `traceparent` header either way.
+	TracerProvider trace.TracerProvider
+}
+
+// Client calls every endpoint of the quiz server, errors answered by the server are *quiz.Problem
+type Client struct {
+	baseURL        string
+	apiBase        string
+	token          string
+	tls            *tls.Config
+	http           *http.Client
+	retries        int
+	retryBackoff   time.Duration
+	attemptTimeout time.Duration
+	language       string
+	tracer         trace.Tracer
+}
+
+func FromConfig(c *Config) (*Client, error) {
+	baseURL, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base url: `%s`, use http or https", c.BaseURL)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: c.TLS}}
+	}
+
+	retries := c.Retries
+	switch {
+	case retries == 0:
+		retries = defaultRetries
+	case retries < 0:
+		retries = 0
+	}
+
+	retryBackoff := c.RetryBackoff
+	if retryBackoff == 0 {
+		retryBackoff = defaultRetryBackoff
+	}
+
+	tracerProvider := c.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+
+	base := strings.TrimSuffix(baseURL.String(), "/")
+	return &Client{
+		baseURL:        base,
+		apiBase:        base + "/" + SupportedVersions[0],
+		token:          c.Token,
+		tls:            c.TLS,
+		http:           httpClient,
+		retries:        retries,
+		retryBackoff:   retryBackoff,
+		attemptTimeout: c.AttemptTimeout,
+		language:       c.Language,
+		tracer:         tracerProvider.Tracer(tracerName),
+	}, nil
+}
+
+type requestIDKey struct{}
+
+// WithRequestID sends the ID as the `X-Request-ID` of the calls made with ctx, the server logs
+// and answers with it, so a request can be followed from the caller to the server
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+type idempotencyKeyKey struct{}
+
+// WithIdempotencyKey sends the key as the `Idempotency-Key` of the writes made with ctx, instead of
+// a key generated per call, e.g. to retry a write after a restart of the caller
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// HasCode tells whether the server answered err as a problem with that code
+func HasCode(err error, code string) bool {
+	var problem *quiz.Problem
+	return errors.As(err, &problem) && problem.Code == code
+}
+
+// call is a request to the api, body is encoded as JSON and the response decoded into out
+type call struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	out    any
+	// calls that are not idempotent, like answers, are sent with an `Idempotency-Key` so their
+	// retries are answered with the response of the first attempt instead of being recorded again
+	idempotent bool
+	// root calls are not versioned, like the probes and the discovery
+	root bool
+}
+
+func (c *Client) do(ctx context.Context, call call) (err error) {
+	ctx, span := c.startSpan(ctx, call.method, call.path)
+	defer func() { endSpan(span, err) }()
+
+	base := c.apiBase
+	if call.root {
+		base = c.baseURL
+	}
+	u := base + call.path
+	if len(call.query) > 0 {
+		u += "?" + call.query.Encode()
+	}
+
+	var body []byte
+	if call.body != nil {
+		var err error
+		if body, err = json.Marshal(call.body); err != nil {
+			return err
+		}
+	}
+
+	header := http.Header{}
+	c.header(ctx, header)
+	if !call.idempotent {
+		key, ok := ctx.Value(idempotencyKeyKey{}).(string)
+		if !ok || key == "" {
+			key = newIdempotencyKey()
+		}
+		header.Set(headerIdempotencyKey, key)
+	}
+
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, call, u, header, body)
+		// the context being done is final, the timeout of an attempt is not
+		if err == nil || attempt >= c.retries || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, call call, u string, header http.Header, body []byte) error {
+	if c.attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.attemptTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, call.method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header.Clone()
+	if body != nil {
+		req.Header.Set(headerContentType, valueContentTypeJSON)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return problemOf(resp)
+	}
+	if call.out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(call.out); err != nil {
+		return fmt.Errorf("invalid response of %s %s: %w", call.method, call.path, err)
+	}
+	return nil
+}
+
+func (c *Client) header(ctx context.Context, header http.Header) {
+	if c.token != "" {
+		header.Set(headerAuthorization, "Bearer "+c.token)
+	}
+	if c.language != "" {
+		header.Set(headerAcceptLanguage, c.language)
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok && id != "" {
+		header.Set(headerXRequestID, id)
+	}
+	inject(ctx, header)
+}
+
+// retryable errors are the ones a later attempt may not get, like the first attempt of the same
+// Idempotency-Key still being processed
+func retryable(err error) bool {
+	var problem *quiz.Problem
+	if !errors.As(err, &problem) {
+		return true
+	}
+	switch problem.Status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return problem.Code == quiz.CodeIdempotencyKeyInUse
+	}
+}
+
+// statusCodes are the codes of responses that are not problems, e.g. of a proxy in front of the server
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          quiz.CodeInvalidRequest,
+	http.StatusUnauthorized:        quiz.CodeUnauthorized,
+	http.StatusForbidden:           quiz.CodeForbidden,
+	http.StatusNotFound:            quiz.CodeNotFound,
+	http.StatusInternalServerError: quiz.CodeInternal,
+}
+
+// problemOf reads the problem the server answered, other responses become a problem of their status
+func problemOf(resp *http.Response) *quiz.Problem {
+	var problem quiz.Problem
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(body, &problem); err == nil && problem.Code != "" {
+		return &problem
+	}
+
+	code, ok := statusCodes[resp.StatusCode]
+	if !ok {
+		code = quiz.CodeInternal
+		if resp.StatusCode < http.StatusInternalServerError {
+			code = quiz.CodeInvalidRequest
+		}
+	}
+	return &quiz.Problem{
+		Type:      quiz.ProblemTypeDefault,
+		Title:     http.StatusText(resp.StatusCode),
+		Status:    resp.StatusCode,
+		Detail:    fmt.Sprintf("unexpected status: %s", resp.Status),
+		Instance:  resp.Request.URL.Path,
+		Code:      code,
+		RequestID: resp.Header.Get(headerXRequestID),
+	}
+}
@@ -0,0 +1,626 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/temp/internal/server"
+	"github.com/vrnvu/temp/pkg/quiz"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testAdminToken = "admin-token"
+
+func testServer(t *testing.T) *httptest.Server {
+	handler, err := server.FromConfig(&server.Config{
+		Slog: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		RequestIDGenerator: func() string {
+			return "123"
+		},
+		AdminToken: testAdminToken,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	s := httptest.NewTLSServer(handler)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testClient(t *testing.T, s *httptest.Server, token string) *Client {
+	c, err := FromConfig(&Config{
+		BaseURL:      s.URL,
+		Token:        token,
+		TLS:          s.Client().Transport.(*http.Transport).TLSClientConfig,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c
+}
+
+func TestClientQuiz(t *testing.T) {
+	t.Parallel()
+	s := testServer(t)
+	c := testClient(t, s, "")
+	ctx := context.Background()
+
+	if err := c.Health(ctx); err != nil {
+		t.Fatalf("Health() got: %v", err)
+	}
+	if err := c.Discover(ctx); err != nil || c.apiBase != s.URL+"/v1" {
+		t.Fatalf("Discover() got: %v, api base %s", err, c.apiBase)
+	}
+
+	questions, err := c.Questions(ctx, Filter{Category: "geography", Tags: []string{"capitals"}})
+	if err != nil || len(questions) != 2 {
+		t.Fatalf("Questions() got: %d questions, %v", len(questions), err)
+	}
+
+	if _, err := c.Statistics(ctx, "user", StatisticsOptions{}); !HasCode(err, quiz.CodeNotEnoughUsers) {
+		t.Fatalf("Statistics() of a single user got: %v", err)
+	}
+
+	if _, err := c.CreateUser(ctx, "other"); err != nil {
+		t.Fatalf("CreateUser() got: %v", err)
+	}
+	if _, err := c.CreateUser(ctx, "other"); !HasCode(err, quiz.CodeUserAlreadyExists) {
+		t.Fatalf("CreateUser() of an existing user got: %v", err)
+	}
+
+	session, err := c.StartSession(ctx, "user", SessionOptions{Filter: Filter{Category: "arithmetic"}})
+	if err != nil || len(session.Questions) == 0 {
+		t.Fatalf("StartSession() got: %+v, %v", session, err)
+	}
+
+	q := session.Questions[0]
+	records, err := c.SubmitSessionAnswers(ctx, "user", session.ID, quiz.QuizAnswer{q.ID: q.OptionIDs[0]})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("SubmitSessionAnswers() got: %+v, %v", records, err)
+	}
+	if _, err := c.SubmitSessionAnswers(ctx, "user", session.ID, quiz.QuizAnswer{q.ID: q.OptionIDs[0]}); !HasCode(err, quiz.CodeQuestionAlreadyAnswered) {
+		t.Fatalf("SubmitSessionAnswers() twice got: %v", err)
+	}
+
+	got, err := c.Session(ctx, "user", session.ID)
+	if err != nil || len(got.Answers) != 1 {
+		t.Fatalf("Session() got: %+v, %v", got, err)
+	}
+
+	if err := c.SubmitAnswers(ctx, "other", quiz.QuizAnswer{0: "Paris"}); err != nil {
+		t.Fatalf("SubmitAnswers() got: %v", err)
+	}
+
+	results, err := c.Results(ctx, "other", "")
+	if err != nil || results.Correct != 1 {
+		t.Fatalf("Results() got: %+v, %v", results, err)
+	}
+
+	if _, err := c.Statistics(ctx, "user", StatisticsOptions{}); err != nil {
+		t.Fatalf("Statistics() got: %v", err)
+	}
+
+	if _, err := c.ReviewQuestions(ctx, "other", Filter{}); err != nil {
+		t.Fatalf("ReviewQuestions() got: %v", err)
+	}
+}
+
+func TestClientNamedQuizzesAndGroups(t *testing.T) {
+	t.Parallel()
+	s := testServer(t)
+	admin := testClient(t, s, testAdminToken)
+	ctx := context.Background()
+	owner, err := testClient(t, s, "").CreateUser(ctx, "owner")
+	if err != nil {
+		t.Fatalf("CreateUser() got: %v", err)
+	}
+	c := testClient(t, s, owner.Token)
+
+	if _, err := c.PutQuiz(ctx, quiz.Quiz{Slug: "capitals", Title: "Capitals", QuestionIDs: []uint64{0, 1}}); !HasCode(err, quiz.CodeUnauthorized) {
+		t.Fatalf("PutQuiz() without the admin token got: %v", err)
+	}
+	if _, err := admin.PutQuiz(ctx, quiz.Quiz{Slug: "capitals", Title: "Capitals", QuestionIDs: []uint64{0, 1}, PassMark: 0.5}); err != nil {
+		t.Fatalf("PutQuiz() got: %v", err)
+	}
+
+	quizzes, err := c.Quizzes(ctx)
+	if err != nil || len(quizzes) != 1 {
+		t.Fatalf("Quizzes() got: %+v, %v", quizzes, err)
+	}
+	if q, err := c.Quiz(ctx, "capitals"); err != nil || q.Title != "Capitals" {
+		t.Fatalf("Quiz() got: %+v, %v", q, err)
+	}
+
+	if err := admin.DeleteQuiz(ctx, "capitals"); err != nil {
+		t.Fatalf("DeleteQuiz() got: %v", err)
+	}
+	if _, err := c.Quiz(ctx, "capitals"); !HasCode(err, quiz.CodeQuizNotFound) {
+		t.Fatalf("Quiz() of a deleted quiz got: %v", err)
+	}
+
+	if _, err := c.CreateGroup(ctx, quiz.Group{Slug: "team", Kind: "team"}); err != nil {
+		t.Fatalf("CreateGroup() got: %v", err)
+	}
+	if _, err := c.CreateUser(ctx, "other"); err != nil {
+		t.Fatalf("CreateUser() got: %v", err)
+	}
+	if group, err := c.AddGroupMember(ctx, "team", "other", false); err != nil || !group.IsMember("other") {
+		t.Fatalf("AddGroupMember() got: %+v, %v", group, err)
+	}
+	if group, err := c.Group(ctx, "team"); err != nil || len(group.Members) != 2 {
+		t.Fatalf("Group() got: %+v, %v", group, err)
+	}
+	if groups, err := c.Groups(ctx); err != nil || len(groups) != 1 {
+		t.Fatalf("Groups() got: %+v, %v", groups, err)
+	}
+	if leaderboard, err := c.Leaderboard(ctx, "team"); err != nil || len(leaderboard) != 2 {
+		t.Fatalf("Leaderboard() got: %+v, %v", leaderboard, err)
+	}
+	if leaderboard, err := c.GroupLeaderboard(ctx); err != nil || len(leaderboard) != 1 {
+		t.Fatalf("GroupLeaderboard() got: %+v, %v", leaderboard, err)
+	}
+	if group, err := c.RemoveGroupMember(ctx, "team", "other"); err != nil || group.IsMember("other") {
+		t.Fatalf("RemoveGroupMember() got: %+v, %v", group, err)
+	}
+}
+
+func TestClientQuery(t *testing.T) {
+	t.Parallel()
+	s := testServer(t)
+	ctx := context.Background()
+	other, err := testClient(t, s, "").CreateUser(ctx, "other")
+	if err != nil {
+		t.Fatalf("CreateUser() got: %v", err)
+	}
+	c := testClient(t, s, other.Token)
+
+	var data struct {
+		Me struct {
+			Name string `json:"name"`
+		} `json:"me"`
+		Users []*struct {
+			Name string `json:"name"`
+		} `json:"users"`
+	}
+	err = c.Query(ctx, `query($names: [String!]!) { me { name } users(names: $names) { name } }`, map[string]any{"names": []string{"other", "unknown"}}, &data)
+
+	var errs quiz.GraphQLErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Code() != quiz.CodeUserNotFound {
+		t.Fatalf("Query() got: %v, want the unknown user error", err)
+	}
+	if data.Me.Name != "other" || len(data.Users) != 2 || data.Users[0].Name != "other" || data.Users[1] != nil {
+		t.Fatalf("Query() got data: %+v", data)
+	}
+
+	if err := c.Query(ctx, `{ me {`, nil, &data); !HasCode(err, quiz.CodeInvalidRequest) {
+		t.Fatalf("Query() of an invalid query got: %v", err)
+	}
+}
+
+func TestClientWebhooks(t *testing.T) {
+	t.Parallel()
+	s := testServer(t)
+	admin := testClient(t, s, testAdminToken)
+	ctx := context.Background()
+
+	webhook := quiz.Webhook{Slug: "chat", URL: "https://chat.example.com/hook", Events: []string{quiz.EventQuizPassed}, Secret: "0123456789abcdef"}
+	if _, err := testClient(t, s, "user").PutWebhook(ctx, webhook); !HasCode(err, quiz.CodeUnauthorized) {
+		t.Fatalf("PutWebhook() without the admin token got: %v", err)
+	}
+	saved, err := admin.PutWebhook(ctx, webhook)
+	if err != nil || saved.Slug != "chat" || saved.Secret != "" {
+		t.Fatalf("PutWebhook() got: %+v, %v", saved, err)
+	}
+
+	webhooks, err := admin.Webhooks(ctx)
+	if err != nil || len(webhooks) != 1 || webhooks[0].URL != webhook.URL {
+		t.Fatalf("Webhooks() got: %+v, %v", webhooks, err)
+	}
+
+	if dead, err := admin.DeadLetters(ctx); err != nil || len(dead) != 0 {
+		t.Fatalf("DeadLetters() got: %+v, %v", dead, err)
+	}
+	if _, err := admin.Redeliver(ctx, 99); !HasCode(err, quiz.CodeDeliveryNotFound) {
+		t.Fatalf("Redeliver() of an unknown delivery got: %v", err)
+	}
+
+	if err := admin.DeleteWebhook(ctx, "chat"); err != nil {
+		t.Fatalf("DeleteWebhook() got: %v", err)
+	}
+	if err := admin.DeleteWebhook(ctx, "chat"); !HasCode(err, quiz.CodeWebhookNotFound) {
+		t.Fatalf("DeleteWebhook() of a deleted webhook got: %v", err)
+	}
+}
+
+func TestClientArchive(t *testing.T) {
+	t.Parallel()
+	source, target := testServer(t), testServer(t)
+	ctx := context.Background()
+
+	if _, err := testClient(t, source, "").CreateUser(ctx, "other"); err != nil {
+		t.Fatalf("CreateUser() got: %v", err)
+	}
+
+	var archive bytes.Buffer
+	if err := testClient(t, source, "user").Export(ctx, &archive); !HasCode(err, quiz.CodeUnauthorized) {
+		t.Fatalf("Export() without the admin token got: %v", err)
+	}
+	if err := testClient(t, source, testAdminToken).Export(ctx, &archive); err != nil {
+		t.Fatalf("Export() got: %v", err)
+	}
+
+	admin := testClient(t, target, testAdminToken)
+	summary, err := admin.Import(ctx, bytes.NewReader(archive.Bytes()), ImportOptions{Mode: quiz.ImportSkip, DryRun: true})
+	if err != nil || !summary.DryRun || summary.Users.Created != 1 || summary.Users.Skipped != 1 {
+		t.Fatalf("Import() dry run got: %+v, %v", summary, err)
+	}
+	if _, err := admin.Import(ctx, bytes.NewReader(archive.Bytes()), ImportOptions{}); !HasCode(err, quiz.CodeImportConflict) {
+		t.Fatalf("Import() of existing records got: %v", err)
+	}
+	if summary, err := admin.Import(ctx, bytes.NewReader(archive.Bytes()), ImportOptions{Mode: quiz.ImportSkip}); err != nil || summary.Users.Created != 1 {
+		t.Fatalf("Import() got: %+v, %v", summary, err)
+	}
+	if _, err := admin.CreateUser(ctx, "other"); !HasCode(err, quiz.CodeUserAlreadyExists) {
+		t.Fatalf("CreateUser() of an imported user got: %v", err)
+	}
+}
+
+func TestClientImportQuestions(t *testing.T) {
+	t.Parallel()
+	s := testServer(t)
+	admin := testClient(t, s, testAdminToken)
+	ctx := context.Background()
+
+	const gift = `// [id:0]
+What is the capital of France? {=Paris ~London ~Madrid}
+
+What is 3 + 3? {=6 ~5}
+`
+	questions, err := quiz.ParseQuestions(quiz.FormatGIFT, strings.NewReader(gift))
+	if err != nil {
+		t.Fatalf("ParseQuestions() got: %v", err)
+	}
+
+	summary, err := admin.ImportQuestions(ctx, questions, ImportOptions{Mode: quiz.ImportOverwrite})
+	if err != nil || summary.Questions.Created != 1 || summary.Questions.Overwritten != 1 {
+		t.Fatalf("ImportQuestions() got: %+v, %v", summary, err)
+	}
+
+	exported, err := admin.ExportQuestions(ctx)
+	if err != nil {
+		t.Fatalf("ExportQuestions() got: %v", err)
+	}
+	if len(exported) != 6 || len(exported[0].Options) != 3 || exported[5].OptionAt(exported[5].Answer) != "6" {
+		t.Fatalf("expected the new question after the others, got %+v", exported)
+	}
+}
+
+func TestClientBlobs(t *testing.T) {
+	t.Parallel()
+	s := testServer(t)
+	admin := testClient(t, s, testAdminToken)
+	ctx := context.Background()
+
+	const png = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	if _, err := testClient(t, s, "user").UploadBlob(ctx, strings.NewReader(png)); !HasCode(err, quiz.CodeUnauthorized) {
+		t.Fatalf("UploadBlob() without the admin token got: %v", err)
+	}
+	blob, err := admin.UploadBlob(ctx, strings.NewReader(png))
+	if err != nil || blob.ID != quiz.BlobID([]byte(png)) || blob.MediaType != "image/png" {
+		t.Fatalf("UploadBlob() got: %+v, %v", blob, err)
+	}
+	if _, err := admin.UploadBlob(ctx, strings.NewReader("plain text")); !HasCode(err, quiz.CodeUnsupportedMediaType) {
+		t.Fatalf("UploadBlob() of text got: %v", err)
+	}
+
+	var image bytes.Buffer
+	mediaType, err := testClient(t, s, "").Blob(ctx, blob.ID, &image)
+	if err != nil || mediaType != "image/png" || image.String() != png {
+		t.Fatalf("Blob() got: %s %q, %v", mediaType, image.String(), err)
+	}
+	if _, err := admin.Blob(ctx, quiz.BlobID(nil), io.Discard); !HasCode(err, quiz.CodeBlobNotFound) {
+		t.Fatalf("Blob() of a missing blob got: %v", err)
+	}
+	if !strings.HasSuffix(admin.BlobURL(blob.ID), "/v1/blobs/"+blob.ID) {
+		t.Fatalf("BlobURL() got: %s", admin.BlobURL(blob.ID))
+	}
+}
+
+func TestClientRegrade(t *testing.T) {
+	t.Parallel()
+	s := testServer(t)
+	admin := testClient(t, s, testAdminToken)
+	ctx := context.Background()
+
+	session, err := admin.StartSession(ctx, "user", SessionOptions{Filter: Filter{Tags: []string{"addition"}}})
+	if err != nil {
+		t.Fatalf("StartSession() got: %v", err)
+	}
+	q := session.Questions[0]
+	if _, err := admin.SubmitSessionAnswers(ctx, "user", session.ID, quiz.QuizAnswer{q.ID: q.OptionIDs[slices.Index(q.Options, "3")]}); err != nil {
+		t.Fatalf("SubmitSessionAnswers() got: %v", err)
+	}
+
+	questions, err := quiz.ParseQuestions(quiz.FormatGIFT, strings.NewReader("// [id:2]\nWhat is 2 + 2? {~1 ~2 =3 ~4}\n"))
+	if err != nil {
+		t.Fatalf("ParseQuestions() got: %v", err)
+	}
+	if _, err := admin.ImportQuestions(ctx, questions, ImportOptions{Mode: quiz.ImportOverwrite}); err != nil {
+		t.Fatalf("ImportQuestions() got: %v", err)
+	}
+	if versions, err := admin.QuestionVersions(ctx, 2); err != nil || len(versions) != 2 || versions[1].Question.Answer != "3" {
+		t.Fatalf("QuestionVersions() got: %+v, %v", versions, err)
+	}
+
+	report, err := admin.Regrade(ctx, 2, false)
+	if err != nil || report.Answers != 1 || len(report.Users) != 1 || report.Users[0].CorrectAfter != 1 {
+		t.Fatalf("Regrade() got: %+v, %v", report, err)
+	}
+
+	// rewording the answer is a third version, the answer recorded by index stays right
+	versions, err := admin.QuestionVersions(ctx, 2)
+	if err != nil {
+		t.Fatalf("QuestionVersions() got: %v", err)
+	}
+	fixed := versions[1].Question
+	fixed.Options = []string{"1", "2", "three", "4"}
+	fixed.Answer = "three"
+	if version, err := admin.PutQuestion(ctx, fixed); err != nil || version.Version != 3 {
+		t.Fatalf("PutQuestion() got: %+v, %v", version, err)
+	}
+	if report, err := admin.Regrade(ctx, 2, false); err != nil || report.Answers != 1 || len(report.Users) != 0 {
+		t.Fatalf("Regrade() after the rewording got: %+v, %v", report, err)
+	}
+	if _, err := testClient(t, s, "user").Regrade(ctx, 2, true); !HasCode(err, quiz.CodeUnauthorized) {
+		t.Fatalf("Regrade() without the admin token got: %v", err)
+	}
+}
+
+func TestClientAuditLog(t *testing.T) {
+	t.Parallel()
+	s := testServer(t)
+	admin := testClient(t, s, testAdminToken)
+	ctx := WithRequestID(context.Background(), "caller-id")
+
+	if _, err := testClient(t, s, "user").Webhooks(ctx); !HasCode(err, quiz.CodeUnauthorized) {
+		t.Fatalf("Webhooks() without the admin token got: %v", err)
+	}
+	if _, err := admin.Webhooks(ctx); err != nil {
+		t.Fatalf("Webhooks() got: %v", err)
+	}
+
+	events, err := admin.AuditLog(ctx, AuditFilter{Action: "getWebhooks"})
+	if err != nil || len(events) != 2 {
+		t.Fatalf("AuditLog() got: %+v, %v", events, err)
+	}
+	if e := events[0]; !e.Admin || e.Outcome != quiz.AuditSuccess || e.RequestID != "caller-id" {
+		t.Fatalf("AuditLog() got the latest event %+v", e)
+	}
+	if e := events[1]; e.Admin || e.Actor != "" || e.Outcome != quiz.AuditDenied {
+		t.Fatalf("AuditLog() got the denied event %+v", e)
+	}
+
+	if page, err := admin.AuditLog(ctx, AuditFilter{Before: events[0].ID, Limit: 1}); err != nil || len(page) != 1 || page[0].ID != events[1].ID {
+		t.Fatalf("AuditLog() before %d got: %+v, %v", events[0].ID, page, err)
+	}
+}
+
+func TestClientRequestID(t *testing.T) {
+	t.Parallel()
+	s := testServer(t)
+	c := testClient(t, s, "")
+
+	ctx := WithRequestID(context.Background(), "caller-id")
+	_, err := c.Results(ctx, "unknown", "")
+
+	var problem *quiz.Problem
+	if !errors.As(err, &problem) || problem.Code != quiz.CodeUserNotFound || problem.RequestID != "caller-id" {
+		t.Fatalf("Results() got: %v, want the problem with the request ID", err)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	t.Parallel()
+
+	var attempts atomic.Int32
+	keys := make(chan string, 3)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			keys <- r.Header.Get(headerIdempotencyKey)
+		}
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(s.Close)
+	c := testClient(t, s, "")
+	ctx := context.Background()
+
+	if _, err := c.Quizzes(ctx); err != nil || attempts.Load() != 3 {
+		t.Fatalf("Quizzes() got: %v after %d attempts, want 3", err, attempts.Load())
+	}
+
+	// writes are retried with the same key, so the server records them once
+	attempts.Store(0)
+	if err := c.SubmitAnswers(ctx, "user", quiz.QuizAnswer{0: "Paris"}); err != nil || attempts.Load() != 3 {
+		t.Fatalf("SubmitAnswers() got: %v after %d attempts, want 3", err, attempts.Load())
+	}
+	first := <-keys
+	if first == "" || <-keys != first || <-keys != first {
+		t.Fatalf("SubmitAnswers() retried without the key %q of the first attempt", first)
+	}
+}
+
+func TestClientIdempotencyKey(t *testing.T) {
+	t.Parallel()
+	handler, err := server.FromConfig(&server.Config{
+		Slog: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		RequestIDGenerator: func() string {
+			return "123"
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	// the first answers are recorded but their response is too late for the client
+	var attempts atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		if r.Method == http.MethodPut && attempts.Add(1) == 1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	c, err := FromConfig(&Config{BaseURL: s.URL, Token: "user", RetryBackoff: time.Millisecond, AttemptTimeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := c.SubmitAnswers(ctx, "user", quiz.QuizAnswer{0: "Paris"}); err != nil || attempts.Load() != 2 {
+		t.Fatalf("SubmitAnswers() got: %v after %d attempts, want 2", err, attempts.Load())
+	}
+	if results, err := c.Results(ctx, "user", ""); err != nil || results.Total != 1 {
+		t.Fatalf("Results() got: %+v, %v, want the answers recorded once", results, err)
+	}
+
+	ctx = WithIdempotencyKey(ctx, "practice-1")
+	if err := c.SubmitAnswers(ctx, "user", quiz.QuizAnswer{1: "Berlin"}); err != nil {
+		t.Fatalf("SubmitAnswers() got: %v", err)
+	}
+	if err := c.SubmitAnswers(ctx, "user", quiz.QuizAnswer{2: "1"}); !HasCode(err, quiz.CodeIdempotencyKeyReused) {
+		t.Fatalf("SubmitAnswers() with a reused key got: %v", err)
+	}
+}
+
+func TestClientDiscoverUnversioned(t *testing.T) {
+	t.Parallel()
+	s := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(s.Close)
+	c := testClient(t, s, "")
+
+	if err := c.Discover(context.Background()); err != nil || c.apiBase != s.URL {
+		t.Fatalf("Discover() got: %v, api base %s", err, c.apiBase)
+	}
+}
+
+func TestClientRoom(t *testing.T) {
+	t.Parallel()
+	s := testServer(t)
+	admin := testClient(t, s, testAdminToken)
+	c := testClient(t, s, "")
+	ctx := context.Background()
+
+	if _, err := admin.PutQuiz(ctx, quiz.Quiz{Slug: "capitals", Title: "Capitals", QuestionIDs: []uint64{0, 1}}); err != nil {
+		t.Fatalf("PutQuiz() got: %v", err)
+	}
+
+	if _, err := c.JoinRoom(ctx, "ABCDEF", "alice"); !HasCode(err, quiz.CodeRoomNotFound) {
+		t.Fatalf("JoinRoom() of an unknown room got: %v", err)
+	}
+
+	host, err := c.HostRoom(ctx, "capitals", 0)
+	if err != nil {
+		t.Fatalf("HostRoom() got: %v", err)
+	}
+	defer host.Close()
+
+	m := <-host.Messages()
+	if m.Type != quiz.RoomMessageRoom || m.Code == "" {
+		t.Fatalf("expected the room code, got %+v", m)
+	}
+
+	player, err := c.JoinRoom(ctx, m.Code, "alice")
+	if err != nil {
+		t.Fatalf("JoinRoom() got: %v", err)
+	}
+	defer player.Close()
+
+	if m := <-host.Messages(); m.Type != quiz.RoomMessagePlayers || len(m.Players) != 1 {
+		t.Fatalf("expected alice to join, got %+v", m)
+	}
+}
+
+func TestClientTracing(t *testing.T) {
+	t.Parallel()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	handler, err := server.FromConfig(&server.Config{
+		Slog: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		RequestIDGenerator: func() string {
+			return "123"
+		},
+		AdminToken:     testAdminToken,
+		TracerProvider: provider,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	s := httptest.NewTLSServer(handler)
+	t.Cleanup(s.Close)
+
+	c, err := FromConfig(&Config{
+		BaseURL:        s.URL,
+		TLS:            s.Client().Transport.(*http.Transport).TLSClientConfig,
+		RetryBackoff:   time.Millisecond,
+		TracerProvider: provider,
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	ctx, root := provider.Tracer("test").Start(context.Background(), "test")
+	if _, err := c.Questions(ctx, Filter{}); err != nil {
+		t.Fatalf("Questions() got: %v", err)
+	}
+	if _, err := c.Results(ctx, "unknown", ""); err == nil {
+		t.Fatalf("Results() expected an error")
+	}
+	root.End()
+
+	var clients, servers []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() != root.SpanContext().TraceID() {
+			t.Fatalf("expected every span in the trace of the caller, got %s", span.Name())
+		}
+		switch span.SpanKind() {
+		case trace.SpanKindClient:
+			clients = append(clients, span)
+		case trace.SpanKindServer:
+			servers = append(servers, span)
+		}
+	}
+	if len(clients) != 2 || len(servers) != 2 {
+		t.Fatalf("expected 2 client and 2 server spans, got %d and %d", len(clients), len(servers))
+	}
+	for i := range clients {
+		if clients[i].Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Fatalf("expected the client span %s in the span of the caller", clients[i].Name())
+		}
+		if servers[i].Parent().SpanID() != clients[i].SpanContext().SpanID() {
+			t.Fatalf("expected the server span %s to continue the client span %s", servers[i].Name(), clients[i].Name())
+		}
+	}
+	if clients[0].Status().Code != codes.Unset || clients[1].Status().Code != codes.Error {
+		t.Fatalf("expected only the failed call with an error status, got %v and %v", clients[0].Status(), clients[1].Status())
+	}
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// Query runs a GraphQL query and decodes its data into out. Fields that failed are returned as
+// quiz.GraphQLErrors, out then holds the data that could be resolved.
+func (c *Client) Query(ctx context.Context, query string, variables map[string]any, out any) error {
+	var resp quiz.GraphQLResponse
+	request := quiz.GraphQLRequest{Query: query, Variables: variables}
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/graphql", body: request, out: &resp, idempotent: true}); err != nil {
+		return err
+	}
+
+	if out != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return fmt.Errorf("invalid data of the query: %w", err)
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+func (c *Client) Groups(ctx context.Context) ([]quiz.Group, error) {
+	var groups []quiz.Group
+	err := c.do(ctx, call{method: http.MethodGet, path: "/groups", out: &groups, idempotent: true})
+	return groups, err
+}
+
+func (c *Client) Group(ctx context.Context, slug string) (quiz.Group, error) {
+	var group quiz.Group
+	err := c.do(ctx, call{method: http.MethodGet, path: "/groups/" + url.PathEscape(slug), out: &group, idempotent: true})
+	return group, err
+}
+
+// CreateGroup makes the user of the client Token its owner, the owners and members of group are ignored
+func (c *Client) CreateGroup(ctx context.Context, group quiz.Group) (quiz.Group, error) {
+	body := struct {
+		Slug string `json:"slug"`
+		Kind string `json:"kind"`
+	}{Slug: group.Slug, Kind: group.Kind}
+
+	var created quiz.Group
+	err := c.do(ctx, call{method: http.MethodPut, path: "/groups/" + url.PathEscape(group.Slug), body: body, out: &created})
+	return created, err
+}
+
+// AddGroupMember needs the client Token to be an owner of the group, or the admin token. With owner
+// the user becomes an owner too.
+func (c *Client) AddGroupMember(ctx context.Context, slug string, user string, owner bool) (quiz.Group, error) {
+	query := url.Values{}
+	if owner {
+		query.Set("role", "owner")
+	}
+
+	var group quiz.Group
+	err := c.do(ctx, call{method: http.MethodPut, path: groupMemberPath(slug, user), query: query, out: &group, idempotent: true})
+	return group, err
+}
+
+// RemoveGroupMember needs the client Token to be an owner of the group, or the admin token
+func (c *Client) RemoveGroupMember(ctx context.Context, slug string, user string) (quiz.Group, error) {
+	var group quiz.Group
+	err := c.do(ctx, call{method: http.MethodDelete, path: groupMemberPath(slug, user), out: &group})
+	return group, err
+}
+
+func groupMemberPath(slug string, user string) string {
+	return "/groups/" + url.PathEscape(slug) + "/members/" + url.PathEscape(user)
+}
+
+// Leaderboard of every user, only of the members of the group when slug is not empty
+func (c *Client) Leaderboard(ctx context.Context, slug string) ([]quiz.LeaderboardEntry, error) {
+	query := url.Values{}
+	if slug != "" {
+		query.Set("group", slug)
+	}
+
+	var leaderboard []quiz.LeaderboardEntry
+	err := c.do(ctx, call{method: http.MethodGet, path: "/leaderboard", query: query, out: &leaderboard, idempotent: true})
+	return leaderboard, err
+}
+
+func (c *Client) GroupLeaderboard(ctx context.Context) ([]quiz.GroupStatistics, error) {
+	var leaderboard []quiz.GroupStatistics
+	err := c.do(ctx, call{method: http.MethodGet, path: "/leaderboard/groups", out: &leaderboard, idempotent: true})
+	return leaderboard, err
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// QuestionVersions is the trail of the edits of the question, the first version first, it needs
+// the admin token
+func (c *Client) QuestionVersions(ctx context.Context, questionID uint64) ([]quiz.QuestionVersion, error) {
+	var versions []quiz.QuestionVersion
+	err := c.do(ctx, call{method: http.MethodGet, path: "/admin/questions/" + strconv.FormatUint(questionID, 10) + "/versions", out: &versions, idempotent: true})
+	return versions, err
+}
+
+// PutQuestion edits the question into its next version and answers that version, it needs the
+// admin token. The options keep their places, the answers recorded them by index.
+func (c *Client) PutQuestion(ctx context.Context, q quiz.ArchiveQuestion) (quiz.QuestionVersion, error) {
+	var version quiz.QuestionVersion
+	err := c.do(ctx, call{method: http.MethodPut, path: "/admin/questions/" + strconv.FormatUint(q.ID, 10), body: q, out: &version, idempotent: true})
+	return version, err
+}
+
+// Regrade scores the answers of the question again against its latest version, after a fix of its
+// answer, and reports how the results changed. A dry run only reports it. It needs the admin token.
+func (c *Client) Regrade(ctx context.Context, questionID uint64, dryRun bool) (quiz.RegradeReport, error) {
+	query := url.Values{}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+
+	var report quiz.RegradeReport
+	err := c.do(ctx, call{method: http.MethodPost, path: "/admin/questions/" + strconv.FormatUint(questionID, 10) + "/regrade", query: query, out: &report})
+	return report, err
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// Filter of the questions, empty fields match every question
+type Filter struct {
+	Category string
+	Tags     []string
+}
+
+func (f Filter) query() url.Values {
+	query := url.Values{}
+	if f.Category != "" {
+		query.Set("category", f.Category)
+	}
+	for _, tag := range f.Tags {
+		query.Add("tag", tag)
+	}
+	return query
+}
+
+func (c *Client) Questions(ctx context.Context, filter Filter) ([]quiz.Question, error) {
+	var questions []quiz.Question
+	err := c.do(ctx, call{method: http.MethodGet, path: "/quiz", query: filter.query(), out: &questions, idempotent: true})
+	return questions, err
+}
+
+// ReviewQuestions are the questions due today for the user
+func (c *Client) ReviewQuestions(ctx context.Context, user string, filter Filter) ([]quiz.Question, error) {
+	query := filter.query()
+	query.Set("mode", "review")
+	query.Set("user", user)
+
+	var questions []quiz.Question
+	err := c.do(ctx, call{method: http.MethodGet, path: "/quiz", query: query, out: &questions, idempotent: true})
+	return questions, err
+}
+
+func (c *Client) SubmitAnswers(ctx context.Context, user string, answers quiz.QuizAnswer) error {
+	return c.do(ctx, call{method: http.MethodPut, path: "/quiz/" + url.PathEscape(user), body: answers})
+}
+
+// Results of the user, only of the named quiz when slug is not empty
+func (c *Client) Results(ctx context.Context, user string, slug string) (quiz.QuizResults, error) {
+	query := url.Values{}
+	if slug != "" {
+		query.Set("quiz", slug)
+	}
+
+	var results quiz.QuizResults
+	err := c.do(ctx, call{method: http.MethodGet, path: "/quiz/" + url.PathEscape(user), query: query, out: &results, idempotent: true})
+	return results, err
+}
+
+// CreateUser returns the bearer token of the user, the server only sends it once
+func (c *Client) CreateUser(ctx context.Context, user string) (quiz.UserToken, error) {
+	var token quiz.UserToken
+	err := c.do(ctx, call{method: http.MethodPut, path: "/users/" + url.PathEscape(user), out: &token})
+	return token, err
+}
+
+// StatisticsOptions compare the user only with the takers of a named quiz or the members of a group
+type StatisticsOptions struct {
+	Quiz  string
+	Group string
+}
+
+func (c *Client) Statistics(ctx context.Context, user string, options StatisticsOptions) (quiz.StatisticsResults, error) {
+	query := url.Values{}
+	if options.Quiz != "" {
+		query.Set("quiz", options.Quiz)
+	}
+	if options.Group != "" {
+		query.Set("group", options.Group)
+	}
+
+	var statistics quiz.StatisticsResults
+	err := c.do(ctx, call{method: http.MethodGet, path: "/statistics/" + url.PathEscape(user), query: query, out: &statistics, idempotent: true})
+	return statistics, err
+}
+
+// SessionOptions of a new session, a named quiz brings its own questions and limits
+type SessionOptions struct {
+	Quiz              string
+	Filter            Filter
+	TimeLimit         time.Duration
+	QuestionTimeLimit time.Duration
+}
+
+func (c *Client) StartSession(ctx context.Context, user string, options SessionOptions) (quiz.Session, error) {
+	query := options.Filter.query()
+	if options.Quiz != "" {
+		query.Set("quiz", options.Quiz)
+	}
+	if options.TimeLimit > 0 {
+		query.Set("time_limit", options.TimeLimit.String())
+	}
+	if options.QuestionTimeLimit > 0 {
+		query.Set("question_time_limit", options.QuestionTimeLimit.String())
+	}
+
+	var session quiz.Session
+	err := c.do(ctx, call{method: http.MethodPost, path: "/sessions/" + url.PathEscape(user), query: query, out: &session})
+	return session, err
+}
+
+func (c *Client) Session(ctx context.Context, user string, id uint64) (quiz.Session, error) {
+	var session quiz.Session
+	err := c.do(ctx, call{method: http.MethodGet, path: sessionPath(user, id), out: &session, idempotent: true})
+	return session, err
+}
+
+// SubmitSessionAnswers returns what the server saw of every answer, late answers score zero
+func (c *Client) SubmitSessionAnswers(ctx context.Context, user string, id uint64, answers quiz.QuizAnswer) ([]quiz.AnswerRecord, error) {
+	var records []quiz.AnswerRecord
+	err := c.do(ctx, call{method: http.MethodPut, path: sessionPath(user, id), body: answers, out: &records})
+	return records, err
+}
+
+func sessionPath(user string, id uint64) string {
+	return fmt.Sprintf("/sessions/%s/%s", url.PathEscape(user), strconv.FormatUint(id, 10))
+}
+
+func (c *Client) Quizzes(ctx context.Context) ([]quiz.Quiz, error) {
+	var quizzes []quiz.Quiz
+	err := c.do(ctx, call{method: http.MethodGet, path: "/quizzes", out: &quizzes, idempotent: true})
+	return quizzes, err
+}
+
+func (c *Client) Quiz(ctx context.Context, slug string) (quiz.Quiz, error) {
+	var q quiz.Quiz
+	err := c.do(ctx, call{method: http.MethodGet, path: "/quizzes/" + url.PathEscape(slug), out: &q, idempotent: true})
+	return q, err
+}
+
+// PutQuiz creates or replaces the named quiz, the client Token must be the admin token
+func (c *Client) PutQuiz(ctx context.Context, q quiz.Quiz) (quiz.Quiz, error) {
+	var saved quiz.Quiz
+	err := c.do(ctx, call{method: http.MethodPut, path: "/admin/quizzes/" + url.PathEscape(q.Slug), body: q, out: &saved, idempotent: true})
+	return saved, err
+}
+
+// DeleteQuiz needs the admin token, its retries are answered like the first attempt
+func (c *Client) DeleteQuiz(ctx context.Context, slug string) error {
+	return c.do(ctx, call{method: http.MethodDelete, path: "/admin/quizzes/" + url.PathEscape(slug)})
+}
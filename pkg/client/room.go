@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// Room is a live room over a websocket, the room closes when the host leaves
+type Room struct {
+	conn     *websocket.Conn
+	messages chan quiz.RoomMessage
+	done     chan struct{}
+	closed   sync.Once
+}
+
+// HostRoom opens a live room of the named quiz, a zero questionTimeLimit uses the one of the quiz.
+// The first message tells the code players join with.
+func (c *Client) HostRoom(ctx context.Context, slug string, questionTimeLimit time.Duration) (*Room, error) {
+	query := url.Values{}
+	query.Set("quiz", slug)
+	if questionTimeLimit > 0 {
+		query.Set("question_time_limit", questionTimeLimit.String())
+	}
+	return c.dialRoom(ctx, "/rooms", query)
+}
+
+func (c *Client) JoinRoom(ctx context.Context, code string, user string) (*Room, error) {
+	query := url.Values{}
+	query.Set("user", user)
+	return c.dialRoom(ctx, "/rooms/"+url.PathEscape(code), query)
+}
+
+func (c *Client) dialRoom(ctx context.Context, path string, query url.Values) (*Room, error) {
+	dialer := websocket.Dialer{Proxy: http.ProxyFromEnvironment, TLSClientConfig: c.tls}
+	u := "ws" + strings.TrimPrefix(c.apiBase, "http") + path + "?" + query.Encode()
+
+	header := http.Header{}
+	c.header(ctx, header)
+	conn, resp, err := dialer.DialContext(ctx, u, header)
+	if err != nil {
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			return nil, problemOf(resp)
+		}
+		return nil, err
+	}
+
+	room := &Room{conn: conn, messages: make(chan quiz.RoomMessage), done: make(chan struct{})}
+	go room.read()
+	return room, nil
+}
+
+// read forwards the messages of the room until the connection is closed
+func (r *Room) read() {
+	defer close(r.messages)
+	for {
+		var m quiz.RoomMessage
+		if err := r.conn.ReadJSON(&m); err != nil {
+			return
+		}
+		select {
+		case r.messages <- m:
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// Messages of the room, closed with the connection
+func (r *Room) Messages() <-chan quiz.RoomMessage {
+	return r.messages
+}
+
+// Start asks the questions to the players that joined, only the host can start
+func (r *Room) Start() error {
+	return r.conn.WriteJSON(quiz.RoomMessage{Type: quiz.RoomMessageStart})
+}
+
+// Answer the question number with the index of the option, in the order the room sent them
+func (r *Room) Answer(number int, option int) error {
+	return r.conn.WriteJSON(quiz.RoomMessage{Type: quiz.RoomMessageAnswer, Number: number, Option: &option})
+}
+
+func (r *Room) Close() error {
+	r.closed.Do(func() { close(r.done) })
+	// the room may have closed the connection already, there is nobody to say goodbye to then
+	_ = r.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	return r.conn.Close()
+}
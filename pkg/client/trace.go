@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/vrnvu/temp/pkg/client"
+
+// the W3C `traceparent` and `tracestate`, the server continues the trace of the caller from them
+var propagator = propagation.TraceContext{}
+
+// startSpan of a call to the api, its retries are part of it
+func (c *Client) startSpan(ctx context.Context, method string, path string) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, method+" "+path, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(method),
+		semconv.URLPath(path),
+	))
+}
+
+// endSpan of a call with its error, and the status of the problem the server answered
+func endSpan(span trace.Span, err error) {
+	var problem *quiz.Problem
+	if errors.As(err, &problem) {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(problem.Status))
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// inject the trace of ctx into the header of a request
+func inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
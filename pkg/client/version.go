@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// SupportedVersions of the api this client speaks, the newest first
+var SupportedVersions = []string{"v1"}
+
+// ErrUnsupportedVersions means the client is too old or too new for the server
+var ErrUnsupportedVersions = errors.New("no api version supported by both the client and the server")
+
+// Health fails when the server is down
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, call{method: http.MethodGet, path: "/health", idempotent: true, root: true})
+}
+
+func (c *Client) Versions(ctx context.Context) (quiz.APIVersions, error) {
+	var versions quiz.APIVersions
+	err := c.do(ctx, call{method: http.MethodGet, path: "/versions", out: &versions, idempotent: true, root: true})
+	return versions, err
+}
+
+// OpenAPI is the specification the server validates the requests and responses against
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var spec json.RawMessage
+	err := c.do(ctx, call{method: http.MethodGet, path: "/openapi.json", out: &spec, idempotent: true, root: true})
+	return spec, err
+}
+
+// Discover picks the newest version both the client and the server support. Servers without
+// the discovery endpoint only have the unversioned routes, those are used as they are.
+func (c *Client) Discover(ctx context.Context) error {
+	versions, err := c.Versions(ctx)
+	if HasCode(err, quiz.CodeNotFound) {
+		c.apiBase = c.baseURL
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, version := range SupportedVersions {
+		if slices.Contains(versions.Supported, version) {
+			c.apiBase = fmt.Sprintf("%s/%s", c.baseURL, version)
+			return nil
+		}
+	}
+	return errors.Join(ErrUnsupportedVersions, fmt.Errorf("server supports %v, this client %v", versions.Supported, SupportedVersions))
+}
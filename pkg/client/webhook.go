@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/vrnvu/temp/pkg/quiz"
+)
+
+// Webhooks answers the subscriptions without their secrets, the client Token must be the admin token
+func (c *Client) Webhooks(ctx context.Context) ([]quiz.Webhook, error) {
+	var webhooks []quiz.Webhook
+	err := c.do(ctx, call{method: http.MethodGet, path: "/admin/webhooks", out: &webhooks, idempotent: true})
+	return webhooks, err
+}
+
+// PutWebhook creates or replaces the subscription, it needs the admin token
+func (c *Client) PutWebhook(ctx context.Context, w quiz.Webhook) (quiz.Webhook, error) {
+	var saved quiz.Webhook
+	err := c.do(ctx, call{method: http.MethodPut, path: "/admin/webhooks/" + url.PathEscape(w.Slug), body: w, out: &saved, idempotent: true})
+	return saved, err
+}
+
+// DeleteWebhook needs the admin token, its retries are answered like the first attempt
+func (c *Client) DeleteWebhook(ctx context.Context, slug string) error {
+	return c.do(ctx, call{method: http.MethodDelete, path: "/admin/webhooks/" + url.PathEscape(slug)})
+}
+
+// DeadLetters answers the deliveries that failed every attempt, it needs the admin token
+func (c *Client) DeadLetters(ctx context.Context) ([]quiz.WebhookDelivery, error) {
+	var dead []quiz.WebhookDelivery
+	err := c.do(ctx, call{method: http.MethodGet, path: "/admin/dead-letters", out: &dead, idempotent: true})
+	return dead, err
+}
+
+// Redeliver queues a dead letter again, it needs the admin token
+func (c *Client) Redeliver(ctx context.Context, id uint64) (quiz.WebhookDelivery, error) {
+	var d quiz.WebhookDelivery
+	err := c.do(ctx, call{method: http.MethodPost, path: "/admin/dead-letters/" + strconv.FormatUint(id, 10) + "/redeliver", out: &d})
+	return d, err
+}
@@ -0,0 +1,273 @@
+package quiz
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// ArchiveVersion of the archives the server exports, it imports the versions up to this one.
+// Version 2 added the blobs, version 3 the option and the question version of the answers, version
+// 4 the token hash of the users.
+const ArchiveVersion = 4
+
+// ContentTypeNDJSON of the archives, one ArchiveRecord per line
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// Kinds of the records of an archive, exported in this order so that records only refer to the ones before them
+const (
+	RecordArchive  = "archive"
+	RecordBlob     = "blob"
+	RecordQuestion = "question"
+	RecordUser     = "user"
+	RecordResult   = "result"
+	RecordAttempt  = "attempt"
+)
+
+// Modes of an import, what to do with the records that already exist
+const (
+	ImportSkip      = "skip"
+	ImportOverwrite = "overwrite"
+	ImportFail      = "fail"
+)
+
+var ImportModes = []string{ImportSkip, ImportOverwrite, ImportFail}
+
+// ArchiveRecord is one line of an archive, only the field of its kind is set.
+// The first record of an archive is its header, of kind archive.
+type ArchiveRecord struct {
+	Kind     string           `json:"kind"`
+	Archive  *ArchiveHeader   `json:"archive,omitempty"`
+	Blob     *ArchiveBlob     `json:"blob,omitempty"`
+	Question *ArchiveQuestion `json:"question,omitempty"`
+	User     *ArchiveUser     `json:"user,omitempty"`
+	Result   *ArchiveResult   `json:"result,omitempty"`
+	Attempt  *ArchiveAttempt  `json:"attempt,omitempty"`
+}
+
+type ArchiveHeader struct {
+	Version    uint64    `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// ArchiveQuestion is a Question with its answer, which the api never sends otherwise
+type ArchiveQuestion struct {
+	ID      uint64   `json:"id"`
+	Text    string   `json:"text"`
+	Options []string `json:"options"`
+	// Answer is the text of the right option, the options of a question never repeat
+	Answer      string   `json:"answer"`
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Explanation string   `json:"explanation,omitempty"`
+	Attachments []string `json:"attachments,omitempty"`
+	// Translations per locale, like `es` or `pt-BR`
+	Translations map[string]Translation `json:"translations,omitempty"`
+}
+
+func (q ArchiveQuestion) Question() Question {
+	return Question{ID: q.ID, Text: q.Text, Options: q.Options, Answer: slices.Index(q.Options, q.Answer), Explanation: q.Explanation, Attachments: q.Attachments, Category: q.Category, Tags: q.Tags, Translations: q.Translations}
+}
+
+func NewArchiveQuestion(q Question) ArchiveQuestion {
+	return ArchiveQuestion{ID: q.ID, Text: q.Text, Options: q.Options, Answer: q.OptionAt(q.Answer), Explanation: q.Explanation, Attachments: q.Attachments, Category: q.Category, Tags: q.Tags, Translations: q.Translations}
+}
+
+// ArchiveBlob is a Blob with its content, in base64 like every []byte in JSON
+type ArchiveBlob struct {
+	ID        string `json:"id"`
+	MediaType string `json:"media_type"`
+	Data      []byte `json:"data"`
+}
+
+type ArchiveUser struct {
+	Name string `json:"name"`
+	// TokenHash is the SHA-256 in hex of the bearer token, the token itself is never kept
+	TokenHash string `json:"token_hash,omitempty"`
+}
+
+// ArchiveResult is everything a user answered, its totals and the review state of every question
+type ArchiveResult struct {
+	User       string            `json:"user"`
+	Correct    uint64            `json:"correct"`
+	Total      uint64            `json:"total"`
+	Categories []CategoryResults `json:"categories"`
+	Reviews    []Review          `json:"reviews"`
+}
+
+// ArchiveAttempt is a session, the options of its questions are shuffled again when imported
+type ArchiveAttempt struct {
+	User                string          `json:"user"`
+	Quiz                string          `json:"quiz,omitempty"`
+	StartedAt           time.Time       `json:"started_at"`
+	TimeLimitMs         int64           `json:"time_limit_ms,omitempty"`
+	QuestionTimeLimitMs int64           `json:"question_time_limit_ms,omitempty"`
+	QuestionIDs         []uint64        `json:"question_ids"`
+	Answers             []ArchiveAnswer `json:"answers"`
+}
+
+// ArchiveAnswer is an AnswerRecord with whether it was correct and the option chosen, which the api
+// never sends otherwise
+type ArchiveAnswer struct {
+	QuestionID      uint64    `json:"question_id"`
+	SubmittedAt     time.Time `json:"submitted_at"`
+	TimeTakenMs     int64     `json:"time_taken_ms"`
+	Late            bool      `json:"late"`
+	Correct         bool      `json:"correct"`
+	QuestionVersion uint64    `json:"question_version,omitempty"`
+	Option          string    `json:"option,omitempty"`
+}
+
+// AnswerRecord of the answer to q, the question version it was scored against, whose options the
+// option is looked up in
+func (a ArchiveAnswer) AnswerRecord(q Question) AnswerRecord {
+	option := noAnswer
+	if a.Option != "" {
+		option = slices.Index(q.Options, a.Option)
+	}
+	return AnswerRecord{QuestionID: a.QuestionID, SubmittedAt: a.SubmittedAt, TimeTakenMs: a.TimeTakenMs, Late: a.Late, Correct: a.Correct, QuestionVersion: a.QuestionVersion, Option: option}
+}
+
+// NewArchiveAnswer of the answer to q, the question version it was scored against, the archive
+// keeps the text of the option like the answer of the question
+func NewArchiveAnswer(a AnswerRecord, q Question) ArchiveAnswer {
+	return ArchiveAnswer{QuestionID: a.QuestionID, SubmittedAt: a.SubmittedAt, TimeTakenMs: a.TimeTakenMs, Late: a.Late, Correct: a.Correct, QuestionVersion: a.QuestionVersion, Option: q.OptionAt(a.Option)}
+}
+
+// Validate checks the record on its own, whether what it refers to exists is up to the import
+func (r ArchiveRecord) Validate() error {
+	set := 0
+	for _, field := range []bool{r.Archive != nil, r.Blob != nil, r.Question != nil, r.User != nil, r.Result != nil, r.Attempt != nil} {
+		if field {
+			set++
+		}
+	}
+	if set != 1 {
+		return &FieldError{Field: "kind", Reason: "set exactly the field of the kind of the record"}
+	}
+
+	switch {
+	case r.Kind == RecordArchive && r.Archive != nil:
+		if r.Archive.Version == 0 || r.Archive.Version > ArchiveVersion {
+			return &FieldError{Field: "archive.version", Reason: fmt.Sprintf("`%d`, use a version up to %d", r.Archive.Version, ArchiveVersion)}
+		}
+	case r.Kind == RecordBlob && r.Blob != nil:
+		if BlobID(r.Blob.Data) != r.Blob.ID {
+			return &FieldError{Field: "blob.id", Reason: fmt.Sprintf("`%s` is not the SHA-256 of the data", r.Blob.ID)}
+		}
+		if len(r.Blob.Data) > MaxBlobSize {
+			return &FieldError{Field: "blob.data", Reason: fmt.Sprintf("%d bytes, the limit is %d", len(r.Blob.Data), MaxBlobSize)}
+		}
+		if !IsBlobMediaType(r.Blob.MediaType) {
+			return &FieldError{Field: "blob.media_type", Reason: fmt.Sprintf("`%s`, try: %v", r.Blob.MediaType, BlobMediaTypes)}
+		}
+	case r.Kind == RecordQuestion && r.Question != nil:
+		if r.Question.Text == "" {
+			return &FieldError{Field: "question.text", Reason: "cannot be empty"}
+		}
+		if !slices.Contains(r.Question.Options, r.Question.Answer) {
+			return &FieldError{Field: "question.answer", Reason: fmt.Sprintf("`%s` is not one of the options", r.Question.Answer)}
+		}
+		for i, option := range r.Question.Options {
+			if slices.Contains(r.Question.Options[i+1:], option) {
+				return &FieldError{Field: "question.options", Reason: fmt.Sprintf("the option `%s` is repeated", option)}
+			}
+		}
+		if err := validateAttachments(r.Question.Attachments); err != nil {
+			return err
+		}
+		if err := validateTranslations(r.Question.Options, r.Question.Translations); err != nil {
+			return err
+		}
+	case r.Kind == RecordUser && r.User != nil:
+		if r.User.Name == "" {
+			return &FieldError{Field: "user.name", Reason: "cannot be empty"}
+		}
+		if hash, err := hex.DecodeString(r.User.TokenHash); err != nil || (len(hash) != 0 && len(hash) != sha256.Size) {
+			return &FieldError{Field: "user.token_hash", Reason: "is not a SHA-256 in hex"}
+		}
+	case r.Kind == RecordResult && r.Result != nil:
+		if r.Result.User == "" {
+			return &FieldError{Field: "result.user", Reason: "cannot be empty"}
+		}
+		if r.Result.Correct > r.Result.Total {
+			return &FieldError{Field: "result.correct", Reason: "cannot be more than the total"}
+		}
+	case r.Kind == RecordAttempt && r.Attempt != nil:
+		if r.Attempt.User == "" {
+			return &FieldError{Field: "attempt.user", Reason: "cannot be empty"}
+		}
+		for _, a := range r.Attempt.Answers {
+			if !slices.Contains(r.Attempt.QuestionIDs, a.QuestionID) {
+				return &FieldError{Field: "attempt.answers", Reason: fmt.Sprintf("question `%d` is not one of the question_ids", a.QuestionID)}
+			}
+		}
+	default:
+		return &FieldError{Field: "kind", Reason: fmt.Sprintf("`%s`, try: %v", r.Kind, []string{RecordArchive, RecordBlob, RecordQuestion, RecordUser, RecordResult, RecordAttempt})}
+	}
+	return nil
+}
+
+// validateAttachments of a question, whether their blobs exist is up to the import
+func validateAttachments(attachments []string) error {
+	if len(attachments) > MaxAttachments {
+		return &FieldError{Field: "question.attachments", Reason: fmt.Sprintf("%d attachments, the limit is %d", len(attachments), MaxAttachments)}
+	}
+	for i, id := range attachments {
+		if !IsBlobID(id) || slices.Contains(attachments[:i], id) {
+			return &FieldError{Field: "question.attachments", Reason: fmt.Sprintf("`%s` is not the ID of a blob, or it is attached twice", id)}
+		}
+	}
+	return nil
+}
+
+// validateTranslations of a question, keyed by canonical locales that translate its options
+func validateTranslations(options []string, translations map[string]Translation) error {
+	for locale, t := range translations {
+		tag, err := language.Parse(locale)
+		if err != nil || tag.String() != locale {
+			return &FieldError{Field: "question.translations", Reason: fmt.Sprintf("`%s` is not a locale like `es` or `pt-BR`", locale)}
+		}
+		for option := range t.Options {
+			if !slices.Contains(options, option) {
+				return &FieldError{Field: "question.translations", Reason: fmt.Sprintf("`%s` of `%s` is not one of the options", option, locale)}
+			}
+		}
+		// the options left untranslated are shown as they are, next to the translated ones
+		shown := map[string]bool{}
+		for _, option := range options {
+			if translation, ok := t.Options[option]; ok {
+				option = translation
+			}
+			if option == "" || shown[option] {
+				return &FieldError{Field: "question.translations", Reason: fmt.Sprintf("the options of `%s` must be distinct and not empty", locale)}
+			}
+			shown[option] = true
+		}
+	}
+	return nil
+}
+
+// ImportCounts of the records of one kind
+type ImportCounts struct {
+	Created     uint64 `json:"created"`
+	Overwritten uint64 `json:"overwritten"`
+	Skipped     uint64 `json:"skipped"`
+}
+
+// ImportSummary reports what an import changed, or what it would change in a dry run
+type ImportSummary struct {
+	Mode      string       `json:"mode"`
+	DryRun    bool         `json:"dry_run"`
+	Version   uint64       `json:"version"`
+	Records   uint64       `json:"records"`
+	Blobs     ImportCounts `json:"blobs"`
+	Questions ImportCounts `json:"questions"`
+	Users     ImportCounts `json:"users"`
+	Results   ImportCounts `json:"results"`
+	Attempts  ImportCounts `json:"attempts"`
+}
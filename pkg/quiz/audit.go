@@ -0,0 +1,45 @@
+package quiz
+
+import "time"
+
+// Outcomes of an audited action
+const (
+	AuditSuccess = "success"
+	// AuditDenied is a request without a valid token, or with a token not allowed to do it
+	AuditDenied  = "denied"
+	AuditFailure = "failure"
+)
+
+var AuditOutcomes = []string{AuditSuccess, AuditDenied, AuditFailure}
+
+// AuditEvent is a privileged or security relevant request: who did what to what, from where and how it went.
+// Events are never changed once recorded.
+type AuditEvent struct {
+	ID   uint64    `json:"id"`
+	Time time.Time `json:"time"`
+	// Actor is the user of the bearer token, it is empty for the admin token and for a missing or
+	// wrong one, tokens are never recorded
+	Actor string `json:"actor,omitempty"`
+	// Admin tells the request came with the admin token
+	Admin bool `json:"admin"`
+	// Action is the operation of the api, like `exportArchive`
+	Action string `json:"action"`
+	// Target are the path parameters of the request, like `group=go user=alice`
+	Target    string `json:"target,omitempty"`
+	RequestID string `json:"request_id"`
+	SourceIP  string `json:"source_ip"`
+	Outcome   string `json:"outcome"`
+	Status    int    `json:"status"`
+}
+
+// AuditOutcome of a response with this status
+func AuditOutcome(status int) string {
+	switch {
+	case status < 400:
+		return AuditSuccess
+	case status == 401 || status == 403:
+		return AuditDenied
+	default:
+		return AuditFailure
+	}
+}
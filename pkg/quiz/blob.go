@@ -0,0 +1,50 @@
+package quiz
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+)
+
+const (
+	// MaxBlobSize of an attachment, in bytes
+	MaxBlobSize = 1 << 20
+	// MaxAttachments of a question
+	MaxAttachments = 4
+)
+
+// BlobMediaTypes are the images a question can attach, told by their content rather than by
+// what the uploader claims
+var BlobMediaTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Blob is an attachment, addressed by the SHA-256 of its content, so it never changes and
+// uploading the same content twice gives the same ID
+type Blob struct {
+	ID        string `json:"id"`
+	MediaType string `json:"media_type"`
+	Size      int64  `json:"size"`
+}
+
+// BlobID of the content, its SHA-256 in lower case hex
+func BlobID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// IsBlobID tells whether the ID could be the one of a blob
+func IsBlobID(id string) bool {
+	if len(id) != 2*sha256.Size {
+		return false
+	}
+	for _, r := range id {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// IsBlobMediaType tells whether questions can attach blobs of the media type
+func IsBlobMediaType(mediaType string) bool {
+	return slices.Contains(BlobMediaTypes, mediaType)
+}
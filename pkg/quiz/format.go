@@ -0,0 +1,217 @@
+package quiz
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Formats of the question banks authors keep outside of the server
+const (
+	FormatGIFT      = "gift"
+	FormatMoodleXML = "moodle"
+	FormatCSV       = "csv"
+	FormatMarkdown  = "markdown"
+)
+
+var Formats = []string{FormatGIFT, FormatMoodleXML, FormatCSV, FormatMarkdown}
+
+// Options of the true or false questions, the formats that have them are mapped to a choice of these two
+const (
+	OptionTrue  = "True"
+	OptionFalse = "False"
+)
+
+// noAnswer of a parsed question until one of its options is marked as the right one
+const noAnswer = -1
+
+// ParsedQuestion is a question read from a file, with its ID only when the file gives one.
+// A question without ID is a new one, a question with an ID replaces the one of the server.
+type ParsedQuestion struct {
+	Question
+	HasID bool
+	// Line the question starts at, 1 is the first one
+	Line int
+}
+
+// LineError tells which question of a file is invalid and why
+type LineError struct {
+	Line   int
+	Reason string
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// ParseErrors are every invalid question of a file, in the order of the file
+type ParseErrors []*LineError
+
+func (e ParseErrors) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseQuestions reads every question of r in the format, one of Formats. Questions of a type
+// that is not a single choice, like essays or matching, are reported as errors rather than dropped.
+// The error is ParseErrors when the file is readable, with every invalid question.
+func ParseQuestions(format string, r io.Reader) ([]ParsedQuestion, error) {
+	var questions []ParsedQuestion
+	var errs ParseErrors
+	var err error
+	switch format {
+	case FormatGIFT:
+		questions, errs, err = parseGIFT(r)
+	case FormatMoodleXML:
+		questions, errs, err = parseMoodleXML(r)
+	case FormatCSV:
+		questions, errs, err = parseCSV(r)
+	case FormatMarkdown:
+		questions, errs, err = parseMarkdown(r)
+	default:
+		return nil, &FieldError{Field: "format", Reason: fmt.Sprintf("`%s`, try: %v", format, Formats)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[uint64]int{}
+	for _, q := range questions {
+		if err := validateParsed(q); err != nil {
+			errs = append(errs, &LineError{Line: q.Line, Reason: err.Error()})
+			continue
+		}
+		if !q.HasID {
+			continue
+		}
+		if line, ok := ids[q.ID]; ok {
+			errs = append(errs, &LineError{Line: q.Line, Reason: fmt.Sprintf("id %d is already the one of line %d", q.ID, line)})
+			continue
+		}
+		ids[q.ID] = q.Line
+	}
+	if len(errs) > 0 {
+		slices.SortStableFunc(errs, func(a, b *LineError) int { return a.Line - b.Line })
+		return nil, errs
+	}
+	return questions, nil
+}
+
+func validateParsed(q ParsedQuestion) error {
+	if q.Text == "" {
+		return errors.New("the question has no text")
+	}
+	if len(q.Options) == 0 {
+		return errors.New("the question has no options")
+	}
+	if q.Answer < 0 || q.Answer >= len(q.Options) {
+		return errors.New("the question has no right option")
+	}
+	for i, option := range q.Options {
+		if option == "" {
+			return errors.New("an option is empty")
+		}
+		if slices.Contains(q.Options[i+1:], option) {
+			return fmt.Errorf("the option `%s` is repeated", option)
+		}
+	}
+	return nil
+}
+
+// WriteQuestions writes the questions to w in the format, one of Formats, so that ParseQuestions
+// reads them back with their IDs
+func WriteQuestions(format string, w io.Writer, questions []Question) error {
+	switch format {
+	case FormatGIFT:
+		return writeGIFT(w, questions)
+	case FormatMoodleXML:
+		return writeMoodleXML(w, questions)
+	case FormatCSV:
+		return writeCSV(w, questions)
+	case FormatMarkdown:
+		return writeMarkdown(w, questions)
+	default:
+		return &FieldError{Field: "format", Reason: fmt.Sprintf("`%s`, try: %v", format, Formats)}
+	}
+}
+
+// trueFalse maps the answer of a true or false question, ok is false for any other answer
+func trueFalse(answer string) (question Question, ok bool) {
+	switch strings.ToUpper(strings.TrimSpace(answer)) {
+	case "T", "TRUE":
+		return Question{Options: []string{OptionTrue, OptionFalse}, Answer: 0}, true
+	case "F", "FALSE":
+		return Question{Options: []string{OptionTrue, OptionFalse}, Answer: 1}, true
+	default:
+		return Question{}, false
+	}
+}
+
+func isTrueFalse(q Question) bool {
+	return slices.Equal(q.Options, []string{OptionTrue, OptionFalse})
+}
+
+// categoryRoot of the category paths of Moodle, like `$course$/top/geography`
+const categoryRoot = "$course$/top"
+
+// categoryOf the last level of a Moodle category path, the levels above it are not kept
+func categoryOf(path string) string {
+	levels := strings.Split(strings.Trim(path, "/ "), "/")
+	last := strings.TrimSpace(levels[len(levels)-1])
+	if last == "top" || strings.HasPrefix(last, "$") {
+		return ""
+	}
+	return last
+}
+
+func categoryPath(category string) string {
+	if category == "" {
+		return categoryRoot
+	}
+	return categoryRoot + "/" + category
+}
+
+var (
+	htmlTagRegexp = regexp.MustCompile(`<[^>]*>`)
+	// metadataRegexp matches the `[id:5]` and `[tag:europe]` that Moodle reads in GIFT comments
+	metadataRegexp = regexp.MustCompile(`\[(id|tag):([^\]]*)\]`)
+)
+
+// plainText of an HTML text, questions are plain text
+func plainText(text string) string {
+	return strings.TrimSpace(html.UnescapeString(htmlTagRegexp.ReplaceAllString(text, "")))
+}
+
+// parseMetadata sets the ID and tags of the `[id:5] [tag:europe]` in the text, IDs that are
+// not a number, like the ones authors give in Moodle, leave the question new
+func parseMetadata(q *ParsedQuestion, text string) {
+	for _, m := range metadataRegexp.FindAllStringSubmatch(text, -1) {
+		value := strings.TrimSpace(m[2])
+		switch m[1] {
+		case "id":
+			if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+				q.ID, q.HasID = id, true
+			}
+		case "tag":
+			if value != "" && !slices.Contains(q.Tags, value) {
+				q.Tags = append(q.Tags, value)
+			}
+		}
+	}
+}
+
+func formatMetadata(q Question) string {
+	metadata := []string{fmt.Sprintf("[id:%d]", q.ID)}
+	for _, tag := range q.Tags {
+		metadata = append(metadata, fmt.Sprintf("[tag:%s]", tag))
+	}
+	return strings.Join(metadata, " ")
+}
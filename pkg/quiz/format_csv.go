@@ -0,0 +1,141 @@
+package quiz
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CSV files have a header, the columns are told apart by their name, in any order:
+//
+//	id,text,answer,category,tags,option 1,option 2
+//	0,What is the capital of France?,Paris,geography,europe;capitals,London,Paris
+//	,Is Paris in France?,true,geography,,,
+//
+// An empty id is a new question, tags are separated by `;` and an answer of true or false
+// without options is a true or false question.
+const (
+	csvID       = "id"
+	csvText     = "text"
+	csvAnswer   = "answer"
+	csvCategory = "category"
+	csvTags     = "tags"
+	csvOption   = "option"
+	csvTagSep   = ";"
+)
+
+func parseCSV(r io.Reader) ([]ParsedQuestion, ParseErrors, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := map[string]int{}
+	var options []int
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if strings.HasPrefix(name, csvOption) {
+			options = append(options, i)
+			continue
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{csvText, csvAnswer} {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, &LineError{Line: 1, Reason: fmt.Sprintf("the header has no `%s` column", name)}
+		}
+	}
+
+	var questions []ParsedQuestion
+	var errs ParseErrors
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		cell := func(i int, ok bool) string {
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		column := func(name string) string {
+			i, ok := columns[name]
+			return cell(i, ok)
+		}
+
+		q := ParsedQuestion{Line: line, Question: Question{Text: column(csvText), Category: column(csvCategory)}}
+		answer := column(csvAnswer)
+		if id := column(csvID); id != "" {
+			q.ID, err = strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				errs = append(errs, &LineError{Line: line, Reason: fmt.Sprintf("the id `%s` is not a number", id)})
+				continue
+			}
+			q.HasID = true
+		}
+		for _, tag := range strings.Split(column(csvTags), csvTagSep) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				q.Tags = append(q.Tags, tag)
+			}
+		}
+		for _, i := range options {
+			if option := cell(i, true); option != "" {
+				q.Options = append(q.Options, option)
+			}
+		}
+		q.Answer = slices.Index(q.Options, answer)
+		if len(q.Options) == 0 {
+			if tf, ok := trueFalse(answer); ok {
+				q.Options, q.Answer = tf.Options, tf.Answer
+			}
+		}
+		questions = append(questions, q)
+	}
+	return questions, errs, nil
+}
+
+func writeCSV(w io.Writer, questions []Question) error {
+	options := 0
+	for _, q := range questions {
+		options = max(options, len(q.Options))
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{csvID, csvText, csvAnswer, csvCategory, csvTags}
+	for i := range options {
+		header = append(header, fmt.Sprintf("%s %d", csvOption, i+1))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, q := range questions {
+		record := []string{strconv.FormatUint(q.ID, 10), q.Text, q.OptionAt(q.Answer), q.Category, strings.Join(q.Tags, csvTagSep)}
+		record = append(record, q.Options...)
+		for len(record) < len(header) {
+			record = append(record, "")
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
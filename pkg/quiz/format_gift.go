@@ -0,0 +1,265 @@
+package quiz
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GIFT is the plain text format of Moodle, questions are separated by blank lines:
+//
+//	$CATEGORY: $course$/top/geography
+//
+//	// [id:0] [tag:europe]
+//	::Capital::What is the capital of France? {=Paris ~London #feedback}
+//	Is Paris in France? {TRUE}
+const giftSpecial = `\~=#{}:`
+
+var giftFormatRegexp = regexp.MustCompile(`^\[(html|moodle|plain|markdown)\]`)
+
+func parseGIFT(r io.Reader) ([]ParsedQuestion, ParseErrors, error) {
+	var questions []ParsedQuestion
+	var errs ParseErrors
+
+	category := ""
+	var block []string
+	next := ParsedQuestion{Question: Question{Answer: noAnswer}}
+	flush := func() {
+		if len(block) > 0 {
+			next.Category = category
+			if err := parseGIFTQuestion(&next, strings.Join(block, "\n")); err != nil {
+				errs = append(errs, &LineError{Line: next.Line, Reason: err.Error()})
+			} else {
+				questions = append(questions, next)
+			}
+		}
+		block = nil
+		next = ParsedQuestion{Question: Question{Answer: noAnswer}}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case text == "":
+			flush()
+		case strings.HasPrefix(text, "//"):
+			if next.Line == 0 {
+				next.Line = line
+			}
+			parseMetadata(&next, text)
+		case strings.HasPrefix(text, "$CATEGORY:"):
+			flush()
+			category = categoryOf(strings.TrimPrefix(text, "$CATEGORY:"))
+		default:
+			if next.Line == 0 {
+				next.Line = line
+			}
+			block = append(block, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	flush()
+	return questions, errs, nil
+}
+
+func parseGIFTQuestion(q *ParsedQuestion, text string) error {
+	if strings.HasPrefix(text, "::") {
+		end := indexUnescaped(text, "::", 2)
+		if end < 0 {
+			return errors.New("the title is not closed with ::")
+		}
+		text = strings.TrimSpace(text[end+2:])
+	}
+
+	open := indexUnescaped(text, "{", 0)
+	if open < 0 {
+		return errors.New("descriptions without answers are not supported")
+	}
+	end := indexUnescaped(text, "}", open)
+	if end < 0 {
+		return errors.New("the answers are not closed with }")
+	}
+
+	q.Text = giftText(text[:open])
+	if after := giftText(text[end+1:]); after != "" {
+		// a missing word question, the answers fill the blank
+		q.Text += " _____ " + after
+	}
+
+	answers := strings.TrimSpace(text[open+1 : end])
+	switch {
+	case answers == "":
+		return errors.New("essay questions are not supported")
+	case strings.HasPrefix(answers, "#"):
+		return errors.New("numerical questions are not supported")
+	}
+	if tf, ok := trueFalse(withoutFeedback(answers)); ok {
+		q.Options, q.Answer = tf.Options, tf.Answer
+		return nil
+	}
+	return parseGIFTChoices(q, answers)
+}
+
+// parseGIFTChoices of `=right ~wrong`, with `~%100%right` being another way to write a right one
+func parseGIFTChoices(q *ParsedQuestion, answers string) error {
+	starts := []int{}
+	for i := 0; i < len(answers); i++ {
+		switch answers[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			starts = append(starts, i)
+		}
+	}
+	if len(starts) == 0 || strings.TrimSpace(answers[:starts[0]]) != "" {
+		return errors.New("the answers start with neither = nor ~")
+	}
+
+	wrong := false
+	right := 0
+	for i, start := range starts {
+		end := len(answers)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		marker, choice := answers[start], strings.TrimSpace(withoutFeedback(answers[start+1:end]))
+
+		weight := 0
+		if marker == '=' {
+			weight = 100
+		}
+		if strings.HasPrefix(choice, "%") {
+			percent := strings.Index(choice[1:], "%")
+			if percent < 0 {
+				return errors.New("the weight of an answer is not closed with %")
+			}
+			w, err := strconv.ParseFloat(choice[1:percent+1], 64)
+			if err != nil {
+				return fmt.Errorf("the weight `%s` is not a number", choice[1:percent+1])
+			}
+			if w > 0 && w < 100 {
+				return errors.New("answers with partial credit are not supported")
+			}
+			weight = int(w)
+			choice = strings.TrimSpace(choice[percent+2:])
+		}
+		if marker == '=' && strings.Contains(choice, "->") {
+			return errors.New("matching questions are not supported")
+		}
+
+		option := giftUnescape(choice)
+		q.Options = append(q.Options, option)
+		if weight == 100 {
+			right++
+			q.Answer = len(q.Options) - 1
+		}
+		wrong = wrong || marker == '~'
+	}
+
+	if !wrong {
+		return errors.New("short answer questions are not supported")
+	}
+	if right > 1 {
+		return errors.New("several right options are not supported")
+	}
+	return nil
+}
+
+func giftText(text string) string {
+	text = strings.TrimSpace(text)
+	if m := giftFormatRegexp.FindStringSubmatch(text); m != nil {
+		text = strings.TrimSpace(text[len(m[0]):])
+		if m[1] == "html" {
+			return plainText(giftUnescape(text))
+		}
+	}
+	return giftUnescape(text)
+}
+
+func withoutFeedback(answer string) string {
+	if i := indexUnescaped(answer, "#", 0); i >= 0 {
+		return answer[:i]
+	}
+	return answer
+}
+
+// indexUnescaped of substr in s from the byte from, skipping the characters escaped with a backslash
+func indexUnescaped(s string, substr string, from int) int {
+	for i := from; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func giftUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func giftEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case strings.ContainsRune(giftSpecial, r):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func writeGIFT(w io.Writer, questions []Question) error {
+	bw := bufio.NewWriter(w)
+	category := ""
+	for _, q := range questions {
+		if q.Category != category {
+			category = q.Category
+			fmt.Fprintf(bw, "$CATEGORY: %s\n\n", categoryPath(category))
+		}
+
+		fmt.Fprintf(bw, "// %s\n", formatMetadata(q))
+		if isTrueFalse(q) {
+			fmt.Fprintf(bw, "%s {%s}\n\n", giftEscape(q.Text), strings.ToUpper(q.OptionAt(q.Answer)))
+			continue
+		}
+
+		fmt.Fprintf(bw, "%s {\n", giftEscape(q.Text))
+		for i, option := range q.Options {
+			marker := "~"
+			if i == q.Answer {
+				marker = "="
+			}
+			fmt.Fprintf(bw, "\t%s%s\n", marker, giftEscape(option))
+		}
+		fmt.Fprint(bw, "}\n\n")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,147 @@
+package quiz
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Markdown files have the questions of a category under its heading, options are a task list
+// with the right one checked:
+//
+//	# geography
+//
+//	<!-- [id:0] [tag:europe] -->
+//	What is the capital of France?
+//
+//	- [ ] London
+//	- [x] Paris
+//
+// Fenced code blocks are part of the text as they are, their lines are never options, headings or
+// comments.
+var (
+	markdownOptionRegexp  = regexp.MustCompile(`^[-*+]\s+\[([ xX])\]\s*(.*)$`)
+	markdownCommentRegexp = regexp.MustCompile(`^<!--(.*)-->$`)
+)
+
+func parseMarkdown(r io.Reader) ([]ParsedQuestion, ParseErrors, error) {
+	var questions []ParsedQuestion
+	var errs ParseErrors
+
+	category := ""
+	// fence is the marker of the code block the line is in, like ``` or ~~~
+	fence := ""
+	var text []string
+	right := 0
+	next := ParsedQuestion{Question: Question{Answer: noAnswer}}
+	flush := func() {
+		if len(text) > 0 || len(next.Options) > 0 {
+			next.Category = category
+			next.Text = strings.Join(text, "\n")
+			if right > 1 {
+				errs = append(errs, &LineError{Line: next.Line, Reason: "several right options are not supported"})
+			} else {
+				questions = append(questions, next)
+			}
+		}
+		text = nil
+		right = 0
+		next = ParsedQuestion{Question: Question{Answer: noAnswer}}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), " \t\r")
+		trimmed := strings.TrimSpace(raw)
+		if fence != "" {
+			text = append(text, raw)
+			if strings.HasPrefix(trimmed, fence) && strings.TrimLeft(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if m := markdownOptionRegexp.FindStringSubmatch(trimmed); m != nil {
+			if next.Line == 0 {
+				next.Line = line
+			}
+			option := strings.TrimSpace(m[2])
+			next.Options = append(next.Options, option)
+			if m[1] != " " {
+				right++
+				next.Answer = len(next.Options) - 1
+			}
+			continue
+		}
+
+		// anything but a blank line after the options starts the next question
+		if len(next.Options) > 0 && trimmed != "" {
+			flush()
+		}
+		switch {
+		case trimmed == "":
+		case trimmed == "#" || strings.HasPrefix(trimmed, "# "):
+			flush()
+			category = strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+		case markdownCommentRegexp.MatchString(trimmed):
+			if next.Line == 0 {
+				next.Line = line
+			}
+			parseMetadata(&next, trimmed)
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			if next.Line == 0 {
+				next.Line = line
+			}
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+			text = append(text, trimmed)
+		default:
+			if next.Line == 0 {
+				next.Line = line
+			}
+			text = append(text, trimmed)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	flush()
+	return questions, errs, nil
+}
+
+func writeMarkdown(w io.Writer, questions []Question) error {
+	// a heading sets the category of the questions below it, so the ones without come first
+	questions = slices.Clone(questions)
+	slices.SortStableFunc(questions, func(a, b Question) int {
+		switch {
+		case a.Category == "" && b.Category != "":
+			return -1
+		case a.Category != "" && b.Category == "":
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	bw := bufio.NewWriter(w)
+	category := ""
+	for _, q := range questions {
+		if q.Category != category {
+			category = q.Category
+			fmt.Fprintf(bw, "# %s\n\n", category)
+		}
+
+		fmt.Fprintf(bw, "<!-- %s -->\n%s\n\n", formatMetadata(q), q.Text)
+		for i, option := range q.Options {
+			check := " "
+			if i == q.Answer {
+				check = "x"
+			}
+			fmt.Fprintf(bw, "- [%s] %s\n", check, option)
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
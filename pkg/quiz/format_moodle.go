@@ -0,0 +1,222 @@
+package quiz
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// moodleQuestion is a <question> of a Moodle XML file, which also holds the categories
+type moodleQuestion struct {
+	Type         string         `xml:"type,attr"`
+	Category     *moodleText    `xml:"category,omitempty"`
+	Name         *moodleText    `xml:"name,omitempty"`
+	QuestionText *moodleText    `xml:"questiontext,omitempty"`
+	IDNumber     string         `xml:"idnumber,omitempty"`
+	Single       string         `xml:"single,omitempty"`
+	Answers      []moodleAnswer `xml:"answer"`
+	Tags         *moodleTags    `xml:"tags,omitempty"`
+}
+
+type moodleTags struct {
+	Tags []moodleText `xml:"tag"`
+}
+
+type moodleText struct {
+	Format string `xml:"format,attr,omitempty"`
+	Text   string `xml:"text"`
+}
+
+// plain text of the text, Moodle keeps most of them as HTML
+func (t moodleText) plain() string {
+	if t.Format == "" || t.Format == "html" || t.Format == "moodle_auto_format" {
+		return plainText(t.Text)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+type moodleAnswer struct {
+	Fraction string `xml:"fraction,attr"`
+	Format   string `xml:"format,attr,omitempty"`
+	Text     string `xml:"text"`
+}
+
+// moodleUnsupported are the common types of Moodle that are not a single choice, by their name in the file
+var moodleUnsupported = map[string]string{
+	"shortanswer": "short answer",
+	"numerical":   "numerical",
+	"essay":       "essay",
+	"matching":    "matching",
+	"description": "description",
+	"multianswer": "embedded answers",
+	"calculated":  "calculated",
+}
+
+func parseMoodleXML(r io.Reader) ([]ParsedQuestion, ParseErrors, error) {
+	var questions []ParsedQuestion
+	var errs ParseErrors
+
+	category := ""
+	d := xml.NewDecoder(r)
+	for {
+		token, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			line, _ := d.InputPos()
+			return nil, nil, &LineError{Line: line, Reason: err.Error()}
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "question" {
+			continue
+		}
+
+		line, _ := d.InputPos()
+		var mq moodleQuestion
+		if err := d.DecodeElement(&mq, &start); err != nil {
+			return nil, nil, &LineError{Line: line, Reason: err.Error()}
+		}
+		if mq.Type == "category" {
+			if mq.Category != nil {
+				category = categoryOf(mq.Category.Text)
+			}
+			continue
+		}
+
+		q := ParsedQuestion{Line: line, Question: Question{Answer: noAnswer, Category: category}}
+		if err := parseMoodleQuestion(&q, mq); err != nil {
+			errs = append(errs, &LineError{Line: line, Reason: err.Error()})
+			continue
+		}
+		questions = append(questions, q)
+	}
+	return questions, errs, nil
+}
+
+func parseMoodleQuestion(q *ParsedQuestion, mq moodleQuestion) error {
+	if name, ok := moodleUnsupported[mq.Type]; ok {
+		return fmt.Errorf("%s questions are not supported", name)
+	}
+	if mq.Type != "multichoice" && mq.Type != "truefalse" {
+		return fmt.Errorf("the question type `%s` is not supported", mq.Type)
+	}
+
+	if mq.QuestionText != nil {
+		q.Text = mq.QuestionText.plain()
+	}
+	if id, err := strconv.ParseUint(strings.TrimSpace(mq.IDNumber), 10, 64); err == nil {
+		q.ID, q.HasID = id, true
+	}
+	if mq.Tags != nil {
+		for _, tag := range mq.Tags.Tags {
+			if tag := strings.TrimSpace(tag.Text); tag != "" {
+				q.Tags = append(q.Tags, tag)
+			}
+		}
+	}
+
+	right := 0
+	for _, a := range mq.Answers {
+		option := moodleText{Format: a.Format, Text: a.Text}.plain()
+		fraction, err := strconv.ParseFloat(strings.TrimSpace(a.Fraction), 64)
+		if err != nil {
+			return fmt.Errorf("the fraction `%s` is not a number", a.Fraction)
+		}
+		if fraction > 0 && fraction < 100 {
+			return errors.New("answers with partial credit are not supported")
+		}
+		if mq.Type == "truefalse" {
+			// Moodle names the options of true or false questions `true` and `false`
+			if tf, ok := trueFalse(option); ok {
+				option = tf.OptionAt(tf.Answer)
+			}
+		}
+
+		q.Options = append(q.Options, option)
+		if fraction == 100 {
+			right++
+			q.Answer = len(q.Options) - 1
+		}
+	}
+	if right > 1 {
+		return errors.New("several right options are not supported")
+	}
+	return nil
+}
+
+func writeMoodleXML(w io.Writer, questions []Question) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	root := xml.StartElement{Name: xml.Name{Local: "quiz"}}
+	if err := e.EncodeToken(root); err != nil {
+		return err
+	}
+
+	category := ""
+	for _, q := range questions {
+		if q.Category != category {
+			category = q.Category
+			c := moodleQuestion{Type: "category", Category: &moodleText{Text: categoryPath(category)}}
+			if err := e.EncodeElement(c, xml.StartElement{Name: xml.Name{Local: "question"}}); err != nil {
+				return err
+			}
+		}
+
+		mq := moodleQuestion{
+			Type:         "multichoice",
+			Name:         &moodleText{Text: moodleName(q.Text)},
+			QuestionText: &moodleText{Format: "html", Text: html.EscapeString(q.Text)},
+			IDNumber:     strconv.FormatUint(q.ID, 10),
+			Single:       "true",
+		}
+		if isTrueFalse(q) {
+			mq.Type, mq.Single = "truefalse", ""
+		}
+		for i, option := range q.Options {
+			fraction := "0"
+			if i == q.Answer {
+				fraction = "100"
+			}
+			if mq.Type == "truefalse" {
+				option = strings.ToLower(option)
+			}
+			mq.Answers = append(mq.Answers, moodleAnswer{Fraction: fraction, Format: "html", Text: html.EscapeString(option)})
+		}
+		if len(q.Tags) > 0 {
+			mq.Tags = &moodleTags{}
+			for _, tag := range q.Tags {
+				mq.Tags.Tags = append(mq.Tags.Tags, moodleText{Text: tag})
+			}
+		}
+		if err := e.EncodeElement(mq, xml.StartElement{Name: xml.Name{Local: "question"}}); err != nil {
+			return err
+		}
+	}
+
+	if err := e.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	if err := e.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// moodleName of the question, Moodle lists questions by a short name
+func moodleName(text string) string {
+	name := strings.Join(strings.Fields(text), " ")
+	if runes := []rune(name); len(runes) > 50 {
+		return string(runes[:50]) + "..."
+	}
+	return name
+}
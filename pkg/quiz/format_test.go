@@ -0,0 +1,164 @@
+package quiz
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFormatsRoundTrip(t *testing.T) {
+	t.Parallel()
+	questions := []Question{
+		{ID: 0, Text: "What is the capital of France?", Options: []string{"London", "Paris"}, Answer: 1, Category: "geography", Tags: []string{"europe", "capitals"}},
+		{ID: 1, Text: "Is Paris in France?", Options: []string{OptionTrue, OptionFalse}, Answer: 0, Category: "geography"},
+		{ID: 2, Text: "What is 1 + 1?", Options: []string{"1", "2"}, Answer: 1},
+		{ID: 7, Text: `Which one is a "map": {a=1}, <b> or a & c?`, Options: []string{"{a=1}", "<b>", "a & c", "x, y; z # ~"}, Answer: 0, Category: "go"},
+	}
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteQuestions(format, &b, questions); err != nil {
+				t.Fatalf("WriteQuestions() got: %v", err)
+			}
+
+			parsed, err := ParseQuestions(format, &b)
+			if err != nil {
+				t.Fatalf("ParseQuestions() got: %v", err)
+			}
+			got := make([]Question, 0, len(parsed))
+			for _, q := range parsed {
+				if !q.HasID {
+					t.Fatalf("expected the question of line %d with its ID", q.Line)
+				}
+				got = append(got, q.Question)
+			}
+			slices.SortFunc(got, func(a, b Question) int { return cmp.Compare(a.ID, b.ID) })
+			if !reflect.DeepEqual(got, questions) {
+				t.Fatalf("expected the questions back:\nwant %+v\ngot  %+v", questions, got)
+			}
+		})
+	}
+}
+
+func TestParseGIFT(t *testing.T) {
+	t.Parallel()
+	const gift = `// a bank of questions
+$CATEGORY: $course$/top/Default for course/geography
+
+// [id:3] [tag:europe]
+::Capital::What is the capital of France? {
+	=Paris#right
+	~London#wrong
+}
+
+[html]<p>Is <b>Paris</b> in France?</p>{T}
+
+The capital of Spain is {~%100%Madrid ~%-50%Barcelona} of course.
+`
+	questions, err := ParseQuestions(FormatGIFT, strings.NewReader(gift))
+	if err != nil {
+		t.Fatalf("ParseQuestions() got: %v", err)
+	}
+
+	want := []ParsedQuestion{
+		{Question: Question{ID: 3, Text: "What is the capital of France?", Options: []string{"Paris", "London"}, Answer: 0, Category: "geography", Tags: []string{"europe"}}, HasID: true, Line: 4},
+		{Question: Question{Text: "Is Paris in France?", Options: []string{OptionTrue, OptionFalse}, Answer: 0, Category: "geography"}, Line: 10},
+		{Question: Question{Text: "The capital of Spain is _____ of course.", Options: []string{"Madrid", "Barcelona"}, Answer: 0, Category: "geography"}, Line: 12},
+	}
+	if !reflect.DeepEqual(questions, want) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v", want, questions)
+	}
+}
+
+func TestParseMarkdownCodeBlock(t *testing.T) {
+	t.Parallel()
+	const markdown = "# go\n\nWhat does it print?\n```go\nfunc main() {\n\t// - [x] not an option\n\n\tfmt.Println(1)\n}\n```\n\n- [x] 1\n- [ ] 2\n"
+	questions, err := ParseQuestions(FormatMarkdown, strings.NewReader(markdown))
+	if err != nil {
+		t.Fatalf("ParseQuestions() got: %v", err)
+	}
+
+	want := []ParsedQuestion{
+		{Question: Question{Text: "What does it print?\n```go\nfunc main() {\n\t// - [x] not an option\n\n\tfmt.Println(1)\n}\n```", Options: []string{"1", "2"}, Answer: 0, Category: "go"}, Line: 3},
+	}
+	if !reflect.DeepEqual(questions, want) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v", want, questions)
+	}
+}
+
+func TestParseMoodleXML(t *testing.T) {
+	t.Parallel()
+	const moodle = `<?xml version="1.0" encoding="UTF-8"?>
+<quiz>
+  <question type="category">
+    <category><text>$course$/top/geography</text></category>
+  </question>
+  <question type="multichoice">
+    <name><text>Capital</text></name>
+    <questiontext format="html"><text><![CDATA[<p>What is the capital of <i>France</i>?</p>]]></text></questiontext>
+    <idnumber>geo-1</idnumber>
+    <answer fraction="0" format="html"><text>London</text></answer>
+    <answer fraction="100" format="html"><text>Paris</text></answer>
+    <tags><tag><text>europe</text></tag></tags>
+  </question>
+  <question type="truefalse">
+    <questiontext format="plain_text"><text>Is Paris in France?</text></questiontext>
+    <answer fraction="100"><text>true</text></answer>
+    <answer fraction="0"><text>false</text></answer>
+  </question>
+</quiz>
+`
+	questions, err := ParseQuestions(FormatMoodleXML, strings.NewReader(moodle))
+	if err != nil {
+		t.Fatalf("ParseQuestions() got: %v", err)
+	}
+
+	want := []ParsedQuestion{
+		{Question: Question{Text: "What is the capital of France?", Options: []string{"London", "Paris"}, Answer: 1, Category: "geography", Tags: []string{"europe"}}, Line: 6},
+		{Question: Question{Text: "Is Paris in France?", Options: []string{OptionTrue, OptionFalse}, Answer: 0, Category: "geography"}, Line: 14},
+	}
+	if !reflect.DeepEqual(questions, want) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v", want, questions)
+	}
+}
+
+func TestParseQuestionsErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		lines  []int
+	}{
+		{name: "gift types", format: FormatGIFT, input: "Essay? {}\n\nNumber? {#2}\n\nShort? {=a =b}\n\nMatch? {=a -> 1 =b -> 2}\n\nPartial? {~%50%a ~%50%b}\n\nTwo? {=a =b ~c}\n\nNo answers", lines: []int{1, 3, 5, 7, 9, 11, 13}},
+		{name: "gift same id", format: FormatGIFT, input: "// [id:1]\nA? {=a ~b}\n\n// [id:1]\nB? {=a ~b}", lines: []int{4}},
+		{name: "moodle types", format: FormatMoodleXML, input: `<quiz><question type="essay"></question>` + "\n" + `<question type="multichoice"><answer fraction="50"><text>a</text></answer></question></quiz>`, lines: []int{1, 2}},
+		{name: "csv", format: FormatCSV, input: "id,text,answer,option 1,option 2\nx,A?,a,a,b\n,B?,c,a,b\n,,a,a,b\n,C?,true,,", lines: []int{2, 3, 4}},
+		{name: "markdown", format: FormatMarkdown, input: "A?\n\n- [x] a\n- [x] b\n\nB?\n\n- [ ] a\n- [ ] b\n\nC?\n\n- [x] a\n- [ ] a", lines: []int{1, 6, 11}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQuestions(tt.format, strings.NewReader(tt.input))
+			var errs ParseErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected ParseErrors, got %v", err)
+			}
+			lines := []int{}
+			for _, e := range errs {
+				lines = append(lines, e.Line)
+			}
+			if !slices.Equal(lines, tt.lines) {
+				t.Fatalf("expected errors on lines %v, got:\n%v", tt.lines, err)
+			}
+		})
+	}
+
+	if _, err := ParseQuestions("docx", strings.NewReader("")); err == nil {
+		t.Fatal("expected an unknown format to fail")
+	}
+}
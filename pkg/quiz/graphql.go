@@ -0,0 +1,42 @@
+package quiz
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// GraphQLRequest is the body of POST /graphql
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// GraphQLResponse holds the data that could be resolved, fields that failed are null and explained in Errors
+type GraphQLResponse struct {
+	Data   json.RawMessage `json:"data,omitempty"`
+	Errors GraphQLErrors   `json:"errors,omitempty"`
+}
+
+// GraphQLError carries the stable code of the problems in its extensions
+type GraphQLError struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// Code of the error, one of the problem codes
+func (e GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
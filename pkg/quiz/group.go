@@ -0,0 +1,62 @@
+package quiz
+
+import (
+	"fmt"
+	"slices"
+)
+
+var GroupKinds = []string{"team", "cohort", "class"}
+
+// Group of users, owners manage the members and are members themselves
+type Group struct {
+	Slug    string   `json:"slug"`
+	Kind    string   `json:"kind"`
+	Owners  []string `json:"owners"`
+	Members []string `json:"members"`
+}
+
+func (g Group) Validate() error {
+	if !slugRegexp.MatchString(g.Slug) {
+		return &FieldError{Field: "slug", Reason: fmt.Sprintf("`%s`, use lowercase letters, digits and dashes", g.Slug)}
+	}
+	if !slices.Contains(GroupKinds, g.Kind) {
+		return &FieldError{Field: "kind", Reason: fmt.Sprintf("`%s`, try: %v", g.Kind, GroupKinds)}
+	}
+	return nil
+}
+
+func (g Group) IsOwner(user string) bool {
+	return slices.Contains(g.Owners, user)
+}
+
+func (g Group) IsMember(user string) bool {
+	return slices.Contains(g.Members, user)
+}
+
+// Aggregated results of a group, so groups of different sizes can be compared
+type GroupStatistics struct {
+	Group      string  `json:"group"`
+	Kind       string  `json:"kind"`
+	Members    uint64  `json:"members"`
+	Correct    uint64  `json:"correct"`
+	Total      uint64  `json:"total"`
+	AvgCorrect float64 `json:"avg_correct"`
+	AvgTotal   float64 `json:"avg_total"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+type LeaderboardEntry struct {
+	Rank     uint64  `json:"rank"`
+	User     string  `json:"user"`
+	Correct  uint64  `json:"correct"`
+	Total    uint64  `json:"total"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// Accuracy is the ratio of correct answers, zero when nothing was answered
+func Accuracy(correct uint64, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(correct) / float64(total)
+}
@@ -0,0 +1,35 @@
+package quiz
+
+import "testing"
+
+func TestGroupValidate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		group Group
+		isErr bool
+	}{
+		{name: "valid", group: Group{Slug: "backend-team", Kind: "team"}, isErr: false},
+		{name: "invalid slug", group: Group{Slug: "Backend Team", Kind: "team"}, isErr: true},
+		{name: "invalid kind", group: Group{Slug: "backend-team", Kind: "guild"}, isErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.group.Validate()
+			if test.isErr != (err != nil) {
+				t.Fatalf("Validate() got: %v, want error: %t", err, test.isErr)
+			}
+		})
+	}
+}
+
+func TestAccuracy(t *testing.T) {
+	t.Parallel()
+	if got := Accuracy(0, 0); got != 0 {
+		t.Fatalf("Accuracy(0, 0) got: %f, want: 0", got)
+	}
+	if got := Accuracy(1, 4); got != 0.25 {
+		t.Fatalf("Accuracy(1, 4) got: %f, want: 0.25", got)
+	}
+}
@@ -0,0 +1,140 @@
+package quiz
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// DefaultLocale of the text and options of the questions and of the messages, the last
+// locale of every fallback chain
+var DefaultLocale = language.English
+
+// Translation of a question into a locale. Options maps every canonical option to its
+// translation, the options without one, like numbers, are the same in every locale.
+type Translation struct {
+	Text        string            `json:"text"`
+	Options     map[string]string `json:"options,omitempty"`
+	Explanation string            `json:"explanation,omitempty"`
+}
+
+// ParseLocales of `Accept-Language` headers or of single tags like `es-MX`, by preference.
+// Malformed values are skipped, the request falls back to the DefaultLocale.
+func ParseLocales(values ...string) []language.Tag {
+	locales := []language.Tag{}
+	for _, value := range values {
+		tags, _, err := language.ParseAcceptLanguage(value)
+		if err != nil {
+			continue
+		}
+		locales = append(locales, tags...)
+	}
+	return locales
+}
+
+// Localize the text and options of the question into the first of the preferred locales it has a
+// translation of, falling back from a regional locale to its language, like `es-MX` to `es`, and
+// finally to the canonical text. Locale tells which one was picked, answers are scored the same
+// in any of them.
+func (q Question) Localize(preferred []language.Tag) Question {
+	localized := q
+	localized.Locale = DefaultLocale.String()
+	if len(q.Translations) == 0 || len(preferred) == 0 {
+		return localized
+	}
+
+	keys := slices.Sorted(maps.Keys(q.Translations))
+	tags := []language.Tag{DefaultLocale}
+	for _, key := range keys {
+		tags = append(tags, language.Make(key))
+	}
+	_, index, confidence := language.NewMatcher(tags).Match(preferred...)
+	if index == 0 || confidence == language.No {
+		return localized
+	}
+
+	key := keys[index-1]
+	t := q.Translations[key]
+	localized.Locale = key
+	if t.Text != "" {
+		localized.Text = t.Text
+	}
+	if t.Explanation != "" {
+		localized.Explanation = t.Explanation
+	}
+	localized.Options = make([]string, len(q.Options))
+	for i, option := range q.Options {
+		localized.Options[i] = option
+		if translated, ok := t.Options[option]; ok {
+			localized.Options[i] = translated
+		}
+	}
+	return localized
+}
+
+// OptionIndex of the canonical Options an option is given as, by its ID of WithOptionIDs or by its
+// text in any locale of the question. It is -1 when the option is none of them, or when its text
+// is the translation of several options, only the ID tells those apart.
+func (q Question) OptionIndex(option string) int {
+	if index, ok := strings.CutPrefix(option, canonicalOptionPrefix); ok {
+		if i, err := strconv.Atoi(index); err == nil && i >= 0 && i < len(q.Options) {
+			return i
+		}
+	}
+	if i := slices.Index(q.Options, option); i >= 0 {
+		return i
+	}
+
+	found := -1
+	for _, t := range q.Translations {
+		for i, canonical := range q.Options {
+			if translated, ok := t.Options[canonical]; !ok || translated != option || i == found {
+				continue
+			}
+			if found >= 0 {
+				return -1
+			}
+			found = i
+		}
+	}
+	return found
+}
+
+// messages translated from English, the messages of the server and of the cli are their own keys
+var messageCatalog = func() catalog.Catalog {
+	b := catalog.NewBuilder(catalog.Fallback(DefaultLocale))
+	for tag, translations := range messages {
+		for key, translation := range translations {
+			b.SetString(tag, key, translation)
+		}
+	}
+	return b
+}()
+
+// NewPrinter of the messages in the first of the preferred locales they are translated to,
+// English when none is
+func NewPrinter(preferred ...language.Tag) *message.Printer {
+	return message.NewPrinter(MessageLocale(preferred...), message.Catalog(messageCatalog))
+}
+
+// MessageLocale is the locale NewPrinter prints the messages in
+func MessageLocale(preferred ...language.Tag) language.Tag {
+	// the default goes first, the matcher falls back to it
+	locales := []language.Tag{DefaultLocale}
+	for _, tag := range messageCatalog.Languages() {
+		if tag != DefaultLocale {
+			locales = append(locales, tag)
+		}
+	}
+
+	_, index, confidence := language.NewMatcher(locales).Match(preferred...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return locales[index]
+}
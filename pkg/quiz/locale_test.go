@@ -0,0 +1,87 @@
+package quiz
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestQuestionLocalize(t *testing.T) {
+	t.Parallel()
+	q := Question{
+		ID:      0,
+		Text:    "What is the capital of France?",
+		Options: []string{"London", "Paris", "4"},
+		Answer:  1,
+		Translations: map[string]Translation{
+			"es":    {Text: "¿Cuál es la capital de Francia?", Options: map[string]string{"London": "Londres", "Paris": "París"}},
+			"pt-BR": {Text: "Qual é a capital da França?", Options: map[string]string{"London": "Londres"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		locales string
+		locale  string
+		text    string
+		options []string
+	}{
+		{name: "none", locales: "", locale: "en", text: q.Text, options: q.Options},
+		{name: "language", locales: "es", locale: "es", text: "¿Cuál es la capital de Francia?", options: []string{"Londres", "París", "4"}},
+		{name: "region falls back to its language", locales: "es-MX", locale: "es", text: "¿Cuál es la capital de Francia?", options: []string{"Londres", "París", "4"}},
+		{name: "region", locales: "pt-BR", locale: "pt-BR", text: "Qual é a capital da França?", options: []string{"Londres", "Paris", "4"}},
+		{name: "by preference", locales: "de, pt-BR;q=0.5, es;q=0.9", locale: "es", text: "¿Cuál es la capital de Francia?", options: []string{"Londres", "París", "4"}},
+		{name: "untranslated", locales: "de", locale: "en", text: q.Text, options: q.Options},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := q.Localize(ParseLocales(tt.locales))
+			if got.Locale != tt.locale || got.Text != tt.text || !slices.Equal(got.Options, tt.options) {
+				t.Fatalf("expected %s %q %v, got %s %q %v", tt.locale, tt.text, tt.options, got.Locale, got.Text, got.Options)
+			}
+		})
+	}
+
+	if !slices.Equal(q.Options, []string{"London", "Paris", "4"}) {
+		t.Fatalf("expected the canonical options untouched, got %v", q.Options)
+	}
+}
+
+func TestQuestionOptionIndex(t *testing.T) {
+	t.Parallel()
+	// both options are a gift in Spanish, only their IDs tell them apart
+	q := Question{
+		Options:      []string{"London", "Paris", "Gift", "Present"},
+		Answer:       3,
+		Translations: map[string]Translation{"es": {Options: map[string]string{"London": "Londres", "Paris": "París", "Gift": "Regalo", "Present": "Regalo"}}},
+	}
+
+	tests := map[string]int{"Paris": 1, "París": 1, "Londres": 0, "opt_3": 3, "opt_4": -1, "Regalo": -1, "Berlin": -1, "": -1}
+	for option, want := range tests {
+		if got := q.OptionIndex(option); got != want {
+			t.Fatalf("OptionIndex(%q) expected %d, got %d", option, want, got)
+		}
+	}
+
+	for i, id := range q.WithOptionIDs().OptionIDs {
+		if got := q.OptionIndex(id); got != i || q.IsCorrect(got) != (i == 3) {
+			t.Fatalf("OptionIndex(%q) expected %d, got %d", id, i, got)
+		}
+	}
+}
+
+func TestNewPrinter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		locales string
+		want    string
+	}{
+		{locales: "", want: "quiz not found"},
+		{locales: "es-AR", want: "cuestionario no encontrado"},
+		{locales: "fr", want: "quiz not found"},
+	}
+	for _, tt := range tests {
+		if got := NewPrinter(ParseLocales(tt.locales)...).Sprintf("quiz not found"); got != tt.want {
+			t.Fatalf("Sprintf() in %q expected %q, got %q", tt.locales, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package quiz
+
+import "golang.org/x/text/language"
+
+// messages are the translations of the messages of the server and of the cli, keyed by the
+// English message. Messages without a translation are printed in English.
+var messages = map[language.Tag]map[string]string{
+	language.Spanish: {
+		// titles of the problems
+		"Bad Request":              "Solicitud incorrecta",
+		"Unauthorized":             "No autorizado",
+		"Forbidden":                "Prohibido",
+		"Not Found":                "No encontrado",
+		"Not Acceptable":           "No aceptable",
+		"Conflict":                 "Conflicto",
+		"Request Entity Too Large": "Entidad de solicitud demasiado grande",
+		"Unsupported Media Type":   "Tipo de medio no soportado",
+		"Unprocessable Entity":     "Entidad no procesable",
+		"Internal Server Error":    "Error interno del servidor",
+		"Service Unavailable":      "Servicio no disponible",
+
+		// details of the problems with a code
+		"user already exists":             "el usuario ya existe",
+		"user not found":                  "usuario no encontrado",
+		"not enough users for statistics": "no hay suficientes usuarios para las estadísticas",
+		"session not found":               "sesión no encontrada",
+		"question not in session":         "la pregunta no está en la sesión",
+		"question already answered":       "la pregunta ya está respondida",
+		"invalid option":                  "opción no válida",
+		"answer one question at a time under a question time limit": "responde una pregunta cada vez cuando hay un límite de tiempo por pregunta",
+		"question not found":                              "pregunta no encontrada",
+		"quiz not found":                                  "cuestionario no encontrado",
+		"quiz is closed":                                  "el cuestionario está cerrado",
+		"max attempts reached":                            "se alcanzó el máximo de intentos",
+		"group not found":                                 "grupo no encontrado",
+		"group already exists":                            "el grupo ya existe",
+		"not a group owner":                               "no es propietario del grupo",
+		"group needs at least one owner":                  "el grupo necesita al menos un propietario",
+		"webhook not found":                               "webhook no encontrado",
+		"delivery not found":                              "entrega no encontrada",
+		"invalid archive":                                 "archivo no válido",
+		"record already exists":                           "el registro ya existe",
+		"query too expensive":                             "consulta demasiado costosa",
+		"idempotency key reused with a different request": "clave de idempotencia reutilizada con otra solicitud",
+		"a request with the same idempotency key is in progress": "hay una solicitud en curso con la misma clave de idempotencia",
+		"not acceptable":          "no aceptable",
+		"room not found":          "sala no encontrada",
+		"unsupported API-Version": "API-Version no soportada",
+		"blob not found":          "blob no encontrado",
+		"blob too large":          "blob demasiado grande",
+		"unsupported media type":  "tipo de medio no soportado",
+
+		// cli
+		"Time is up!\n": "¡Se acabó el tiempo!\n",
+		"\n%d answers arrived too late and score zero\n": "\n%d respuestas llegaron tarde y no puntúan\n",
+		"\nAnswers submitted successfully!\n":            "\n¡Respuestas enviadas!\n",
+		"Nothing to review, come back later!\n":          "Nada que repasar, ¡vuelve más tarde!\n",
+		"%d questions to review\n":                       "%d preguntas para repasar\n",
+		"\nPractice submitted successfully!\n":           "\n¡Repaso enviado!\n",
+		"CATEGORY\tCORRECT\tTOTAL\tACCURACY\t\n":         "CATEGORÍA\tACIERTOS\tTOTAL\tPRECISIÓN\t\n",
+		"CATEGORY\tCORRECT\tTOTAL\tACCURACY\tAVG\t\n":    "CATEGORÍA\tACIERTOS\tTOTAL\tPRECISIÓN\tMEDIA\t\n",
+		"all": "todas",
+		"ATTEMPT\tSTARTED\tCORRECT\tTOTAL\tPASSED\t\n": "INTENTO\tINICIO\tACIERTOS\tTOTAL\tAPROBADO\t\n",
+		"User not found, answer a quiz first\n":        "Usuario no encontrado, responde antes un cuestionario\n",
+		"Not enough users for statistics yet\n":        "Aún no hay suficientes usuarios para las estadísticas\n",
+		"Quiz not found\n":                             "Cuestionario no encontrado\n",
+		"Quiz is closed\n":                             "El cuestionario está cerrado\n",
+		"No attempts left for this quiz\n":             "No quedan intentos para este cuestionario\n",
+		"Room not found, check the code\n":             "Sala no encontrada, revisa el código\n",
+		"Room closed\n":                                "Sala cerrada\n",
+		"Room code: %s, %d questions of %s each\n":     "Código de la sala: %s, %d preguntas de %s cada una\n",
+		"Press enter to start once everyone joined\n":  "Pulsa intro para empezar cuando todos se hayan unido\n",
+		"Players: %s\n":                                "Jugadores: %s\n",
+		"\nQuestion %d/%d: %s\n":                       "\nPregunta %d/%d: %s\n",
+		"\nQuestion %d/%d\n":                           "\nPregunta %d/%d\n",
+		"\nFinal ranking\n":                            "\nClasificación final\n",
+		"Waiting for the host to start, players: %s\n": "Esperando a que el anfitrión empiece, jugadores: %s\n",
+		"Waiting for the others...\n":                  "Esperando a los demás...\n",
+		"Correct!\n":                                   "¡Correcto!\n",
+		"Wrong!\n":                                     "¡Incorrecto!\n",
+		"RANK\tPLAYER\tSCORE\tCORRECT\tPOINTS\t\n":     "PUESTO\tJUGADOR\tPUNTOS\tACIERTOS\tGANADOS\t\n",
+		"RECORDS\tCREATED\tOVERWRITTEN\tSKIPPED\t\n":   "REGISTROS\tCREADOS\tSOBRESCRITOS\tOMITIDOS\t\n",
+		"blobs":     "blobs",
+		"questions": "preguntas",
+		"users":     "usuarios",
+		"results":   "resultados",
+		"attempts":  "intentos",
+		"\nDry run of %d records, nothing was imported\n": "\nSimulación de %d registros, no se importó nada\n",
+		"\n%d records read\n":                             "\n%d registros leídos\n",
+		"Your answer":                                     "Tu respuesta",
+		"Attachment: %s\n":                                "Adjunto: %s\n",
+		"Uploaded %s of %d bytes as %s\n":                 "Subido %s de %d bytes como %s\n",
+		"USER\tBEFORE\tAFTER\tTOTAL\t\n":                  "USUARIO\tANTES\tDESPUÉS\tTOTAL\t\n",
+		"Dry run of %d answers against version %d, nothing was regraded\n": "Simulación de %d respuestas con la versión %d, no se recalificó nada\n",
+		"%d answers regraded against version %d\n":                         "%d respuestas recalificadas con la versión %d\n",
+		"No audit events\n": "No hay eventos de auditoría\n",
+		"ID\tTIME\tACTOR\tACTION\tTARGET\tOUTCOME\tSOURCE\n": "ID\tHORA\tACTOR\tACCIÓN\tOBJETIVO\tRESULTADO\tORIGEN\n",
+	},
+}
@@ -1,10 +1,67 @@
 package quiz
 
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
 type Question struct {
-	ID      uint64   `json:"id"`
+	ID uint64 `json:"id"`
+	// Version of the question, every edit makes a new one, see QuestionVersion
+	Version uint64 `json:"version"`
+	// Text in Markdown, code goes in fenced blocks with its language
 	Text    string   `json:"text"`
 	Options []string `json:"options"`
-	Answer  string   `json:"-"`
+	// Answer is the index of the right one of the canonical Options, texts can repeat once translated
+	Answer int `json:"-"`
+	// Explanation of the answer in Markdown, only sent once the question is answered
+	Explanation string `json:"-"`
+	// Attachments are the IDs of the images of the question, see Blob
+	Attachments []string `json:"attachments,omitempty"`
+	// OptionIDs[i] identifies Options[i], random inside a session, see WithOptionIDs outside of one
+	OptionIDs []string `json:"option_ids,omitempty"`
+	Category  string   `json:"category,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	// Locale of the text and options, see Localize
+	Locale string `json:"locale,omitempty"`
+	// Translations per locale like `es` or `pt-BR`, the api sends only the one of Locale
+	Translations map[string]Translation `json:"-"`
+}
+
+// IsCorrect tells if the option at index of the canonical Options is the answer
+func (q Question) IsCorrect(index int) bool {
+	return index >= 0 && index < len(q.Options) && index == q.Answer
+}
+
+// OptionAt index of the canonical Options, empty when there is none like for an unanswered question
+func (q Question) OptionAt(index int) string {
+	if index < 0 || index >= len(q.Options) {
+		return ""
+	}
+	return q.Options[index]
+}
+
+// canonicalOptionPrefix of the IDs of the options in their canonical order
+const canonicalOptionPrefix = "opt_"
+
+// WithOptionIDs identifies the options by their canonical index, `opt_0` and on, so that
+// answers outside of a session don't depend on the text of the options
+func (q Question) WithOptionIDs() Question {
+	q.OptionIDs = make([]string, len(q.Options))
+	for i := range q.Options {
+		q.OptionIDs[i] = canonicalOptionPrefix + strconv.Itoa(i)
+	}
+	return q
+}
+
+func (q Question) HasTags(tags ...string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(q.Tags, tag) {
+			return false
+		}
+	}
+	return true
 }
 
 // nontyped to have something different
@@ -15,15 +72,49 @@ type User struct {
 	Name    string `json:"name"`
 	Correct uint64 `json:"correct"`
 	Total   uint64 `json:"total"`
+	// TokenHash is the SHA-256 in hex of the bearer token of the user, empty when it has none
+	TokenHash string `json:"-"`
+}
+
+// UserToken is the bearer token of a new user, only sent when it is created
+type UserToken struct {
+	User  string `json:"user"`
+	Token string `json:"token"`
 }
 
 // View over User results
 type QuizResults struct {
-	Correct uint64 `json:"correct"`
-	Total   uint64 `json:"total"`
+	Correct    uint64            `json:"correct"`
+	Total      uint64            `json:"total"`
+	Categories []CategoryResults `json:"categories,omitempty"`
+	Attempts   []AttemptResults  `json:"attempts,omitempty"`
+}
+
+// One session of a named quiz
+type AttemptResults struct {
+	Session   uint64    `json:"session"`
+	StartedAt time.Time `json:"started_at"`
+	Correct   uint64    `json:"correct"`
+	Total     uint64    `json:"total"`
+	Passed    bool      `json:"passed"`
+}
+
+type CategoryResults struct {
+	Category string `json:"category"`
+	Correct  uint64 `json:"correct"`
+	Total    uint64 `json:"total"`
 }
 
 type StatisticsResults struct {
+	Correct    uint64               `json:"correct"`
+	Total      uint64               `json:"total"`
+	AvgCorrect float64              `json:"avg_correct"`
+	AvgTotal   float64              `json:"avg_total"`
+	Categories []CategoryStatistics `json:"categories,omitempty"`
+}
+
+type CategoryStatistics struct {
+	Category   string  `json:"category"`
 	Correct    uint64  `json:"correct"`
 	Total      uint64  `json:"total"`
 	AvgCorrect float64 `json:"avg_correct"`
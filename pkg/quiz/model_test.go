@@ -8,11 +8,12 @@ import (
 )
 
 func TestQuestionMarshalJSON(t *testing.T) {
+	t.Parallel()
 	question := Question{
 		ID:      1,
 		Text:    "What is the capital of France?",
 		Options: []string{"London", "Paris", "Berlin", "Madrid"},
-		Answer:  "Paris",
+		Answer:  1,
 	}
 
 	body := bytes.NewBuffer(nil)
@@ -36,12 +37,13 @@ func TestQuestionMarshalJSON(t *testing.T) {
 	if !slices.Equal(got.Options, question.Options) {
 		t.Fatalf("Question Options do not match, got: %+v, want: %+v", got.Options, question.Options)
 	}
-	if got.Answer != "" {
-		t.Fatalf("Question Answer does not match, got: %s, want: %s", got.Answer, question.Answer)
+	if got.Answer != 0 {
+		t.Fatalf("Question Answer does not match, got: %d, want: %d", got.Answer, 0)
 	}
 }
 
 func TestQuizAnswerMarshalJSON(t *testing.T) {
+	t.Parallel()
 	answer := QuizAnswer{
 		1: "Paris",
 	}
@@ -70,6 +72,7 @@ func TestQuizAnswerMarshalJSON(t *testing.T) {
 }
 
 func TestUserMarshalJSON(t *testing.T) {
+	t.Parallel()
 	user := User{
 		ID:      1,
 		Name:    "John Doe",
@@ -93,3 +96,38 @@ func TestUserMarshalJSON(t *testing.T) {
 		t.Fatalf("User do not match, got: %+v, want: %+v", got, user)
 	}
 }
+
+func TestQuestionHasTags(t *testing.T) {
+	t.Parallel()
+	question := Question{ID: 1, Tags: []string{"capitals", "europe"}}
+
+	tests := []struct {
+		name string
+		tags []string
+		want bool
+	}{
+		{name: "no tags", tags: nil, want: true},
+		{name: "one tag", tags: []string{"capitals"}, want: true},
+		{name: "all tags", tags: []string{"europe", "capitals"}, want: true},
+		{name: "missing tag", tags: []string{"capitals", "asia"}, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := question.HasTags(test.tags...); got != test.want {
+				t.Fatalf("HasTags(%v) got: %t, want: %t", test.tags, got, test.want)
+			}
+		})
+	}
+}
+
+func TestQuestionIsCorrect(t *testing.T) {
+	t.Parallel()
+	question := Question{ID: 1, Options: []string{"London", "Paris"}, Answer: 1}
+
+	for index, want := range map[int]bool{-1: false, 0: false, 1: true, 2: false} {
+		if got := question.IsCorrect(index); got != want {
+			t.Fatalf("IsCorrect(%d) got: %t, want: %t", index, got, want)
+		}
+	}
+}
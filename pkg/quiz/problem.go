@@ -0,0 +1,84 @@
+package quiz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Codes of the problems the server answers, clients should switch on them rather than on the status
+const (
+	CodeInvalidRequest          = "invalid_request"
+	CodeUnauthorized            = "unauthorized"
+	CodeForbidden               = "forbidden"
+	CodeNotFound                = "not_found"
+	CodeInternal                = "internal_error"
+	CodeUserAlreadyExists       = "user_already_exists"
+	CodeUserNotFound            = "user_not_found"
+	CodeNotEnoughUsers          = "not_enough_users_for_statistics"
+	CodeSessionNotFound         = "session_not_found"
+	CodeQuestionNotInSession    = "question_not_in_session"
+	CodeQuestionAlreadyAnswered = "question_already_answered"
+	CodeInvalidOption           = "invalid_option"
+	CodeBatchedAnswers          = "batched_answers"
+	CodeQuestionNotFound        = "question_not_found"
+	CodeQuizNotFound            = "quiz_not_found"
+	CodeQuizClosed              = "quiz_closed"
+	CodeMaxAttemptsReached      = "max_attempts_reached"
+	CodeGroupNotFound           = "group_not_found"
+	CodeGroupAlreadyExists      = "group_already_exists"
+	CodeNotGroupOwner           = "not_group_owner"
+	CodeLastGroupOwner          = "last_group_owner"
+	CodeRoomNotFound            = "room_not_found"
+	CodeUnsupportedVersion      = "unsupported_version"
+	CodeQueryTooExpensive       = "query_too_expensive"
+	CodeWebhookNotFound         = "webhook_not_found"
+	CodeDeliveryNotFound        = "delivery_not_found"
+	CodeIdempotencyKeyReused    = "idempotency_key_reused"
+	CodeIdempotencyKeyInUse     = "idempotency_key_in_use"
+	CodeNotAcceptable           = "not_acceptable"
+	CodeInvalidArchive          = "invalid_archive"
+	CodeImportConflict          = "import_conflict"
+	CodeBlobNotFound            = "blob_not_found"
+	CodeBlobTooLarge            = "blob_too_large"
+	CodeUnsupportedMediaType    = "unsupported_media_type"
+)
+
+const (
+	ContentTypeProblem = "application/problem+json"
+	// problems are told apart by their code, so their type has no further semantics
+	ProblemTypeDefault = "about:blank"
+)
+
+// Problem is an RFC 7807 error response, extended with a stable code and the request ID
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+func (p *Problem) Error() string {
+	if len(p.Errors) == 0 {
+		return fmt.Sprintf("%s: %s", p.Code, p.Detail)
+	}
+
+	fields := make([]string, 0, len(p.Errors))
+	for _, e := range p.Errors {
+		fields = append(fields, e.Error())
+	}
+	return fmt.Sprintf("%s: %s", p.Code, strings.Join(fields, ", "))
+}
+
+// FieldError tells which field of a request is invalid and why
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
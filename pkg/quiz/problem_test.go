@@ -0,0 +1,23 @@
+package quiz
+
+import "testing"
+
+func TestProblemError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		problem Problem
+		want    string
+	}{
+		{name: "detail", problem: Problem{Code: CodeUserNotFound, Detail: "user not found"}, want: "user_not_found: user not found"},
+		{name: "fields", problem: Problem{Code: CodeInvalidRequest, Detail: "bad", Errors: []FieldError{{Field: "slug", Reason: "`Bad`"}, {Field: "pass_mark", Reason: "2"}}}, want: "invalid_request: invalid slug: `Bad`, invalid pass_mark: 2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.problem.Error(); got != test.want {
+				t.Fatalf("Error() got: %s, want: %s", got, test.want)
+			}
+		})
+	}
+}
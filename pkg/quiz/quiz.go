@@ -0,0 +1,63 @@
+package quiz
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var slugRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Quiz is a named quiz like "security-101", with either a fixed list of questions
+// or a number of questions sampled from a category and tags
+type Quiz struct {
+	Slug                string     `json:"slug"`
+	Title               string     `json:"title"`
+	QuestionIDs         []uint64   `json:"question_ids,omitempty"`
+	Sample              uint64     `json:"sample,omitempty"`
+	Category            string     `json:"category,omitempty"`
+	Tags                []string   `json:"tags,omitempty"`
+	PassMark            float64    `json:"pass_mark"`
+	TimeLimitMs         int64      `json:"time_limit_ms,omitempty"`
+	QuestionTimeLimitMs int64      `json:"question_time_limit_ms,omitempty"`
+	MaxAttempts         uint64     `json:"max_attempts,omitempty"`
+	OpensAt             *time.Time `json:"opens_at,omitempty"`
+	ClosesAt            *time.Time `json:"closes_at,omitempty"`
+}
+
+func (q Quiz) Validate() error {
+	if !slugRegexp.MatchString(q.Slug) {
+		return &FieldError{Field: "slug", Reason: fmt.Sprintf("`%s`, use lowercase letters, digits and dashes", q.Slug)}
+	}
+	if len(q.QuestionIDs) > 0 && q.Sample > 0 {
+		return &FieldError{Field: "question_ids", Reason: "use either question_ids or sample, not both"}
+	}
+	if q.PassMark < 0 || q.PassMark > 1 {
+		return &FieldError{Field: "pass_mark", Reason: fmt.Sprintf("`%v`, use a ratio between 0 and 1", q.PassMark)}
+	}
+	if q.TimeLimitMs < 0 || q.QuestionTimeLimitMs < 0 {
+		return &FieldError{Field: "time_limit_ms", Reason: "time limits cannot be negative"}
+	}
+	if q.OpensAt != nil && q.ClosesAt != nil && !q.OpensAt.Before(*q.ClosesAt) {
+		return &FieldError{Field: "closes_at", Reason: "opens_at must be before closes_at"}
+	}
+	return nil
+}
+
+func (q Quiz) IsOpen(now time.Time) bool {
+	if q.OpensAt != nil && now.Before(*q.OpensAt) {
+		return false
+	}
+	if q.ClosesAt != nil && !now.Before(*q.ClosesAt) {
+		return false
+	}
+	return true
+}
+
+// Passed tells if correct answers out of total questions reach the pass mark
+func (q Quiz) Passed(correct uint64, total uint64) bool {
+	if total == 0 {
+		return false
+	}
+	return float64(correct)/float64(total) >= q.PassMark
+}
@@ -0,0 +1,78 @@
+package quiz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuizValidate(t *testing.T) {
+	t.Parallel()
+	opensAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	closesAt := opensAt.Add(24 * time.Hour)
+
+	tests := []struct {
+		name  string
+		quiz  Quiz
+		isErr bool
+	}{
+		{name: "valid", quiz: Quiz{Slug: "security-101", PassMark: 0.8, OpensAt: &opensAt, ClosesAt: &closesAt}, isErr: false},
+		{name: "invalid slug", quiz: Quiz{Slug: "Security 101"}, isErr: true},
+		{name: "fixed and sampled", quiz: Quiz{Slug: "a", QuestionIDs: []uint64{1}, Sample: 2}, isErr: true},
+		{name: "pass mark", quiz: Quiz{Slug: "a", PassMark: 80}, isErr: true},
+		{name: "negative time limit", quiz: Quiz{Slug: "a", TimeLimitMs: -1}, isErr: true},
+		{name: "dates", quiz: Quiz{Slug: "a", OpensAt: &closesAt, ClosesAt: &opensAt}, isErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.quiz.Validate()
+			if test.isErr != (err != nil) {
+				t.Fatalf("Validate() got: %v, want error: %t", err, test.isErr)
+			}
+		})
+	}
+}
+
+func TestQuizIsOpen(t *testing.T) {
+	t.Parallel()
+	opensAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	closesAt := opensAt.Add(24 * time.Hour)
+	quiz := Quiz{Slug: "a", OpensAt: &opensAt, ClosesAt: &closesAt}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{name: "before", now: opensAt.Add(-time.Second), want: false},
+		{name: "opens", now: opensAt, want: true},
+		{name: "closes", now: closesAt, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := quiz.IsOpen(test.now); got != test.want {
+				t.Fatalf("IsOpen(%v) got: %t, want: %t", test.now, got, test.want)
+			}
+		})
+	}
+
+	if !(Quiz{Slug: "a"}).IsOpen(opensAt) {
+		t.Fatalf("Expected quiz without dates to be open")
+	}
+}
+
+func TestQuizPassed(t *testing.T) {
+	t.Parallel()
+	quiz := Quiz{Slug: "a", PassMark: 0.5}
+
+	if quiz.Passed(0, 0) {
+		t.Fatalf("Expected empty attempt not to pass")
+	}
+	if !quiz.Passed(1, 2) {
+		t.Fatalf("Expected 1 out of 2 to pass")
+	}
+	if quiz.Passed(1, 3) {
+		t.Fatalf("Expected 1 out of 3 not to pass")
+	}
+}
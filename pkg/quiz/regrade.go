@@ -0,0 +1,34 @@
+package quiz
+
+import "time"
+
+// QuestionVersion is a question as an edit left it, versions are never changed once recorded
+type QuestionVersion struct {
+	Version  uint64    `json:"version"`
+	EditedAt time.Time `json:"edited_at"`
+	// Question with its answer, like in an archive
+	Question ArchiveQuestion `json:"question"`
+}
+
+// RegradeReport tells how scoring the answers of a question again against its latest version
+// changed the results of the users, or how it would change them in a dry run
+type RegradeReport struct {
+	QuestionID uint64 `json:"question_id"`
+	// Version the answers were scored against
+	Version uint64 `json:"version"`
+	DryRun  bool   `json:"dry_run"`
+	// Answers scored again, the ones recorded against an older version
+	Answers uint64 `json:"answers"`
+	// Users whose results changed, by name
+	Users []RegradeUser `json:"users"`
+}
+
+// RegradeUser is the diff of the results of a user, a regrade never changes the total
+type RegradeUser struct {
+	User          string `json:"user"`
+	CorrectBefore uint64 `json:"correct_before"`
+	CorrectAfter  uint64 `json:"correct_after"`
+	Total         uint64 `json:"total"`
+	// Sessions whose attempts changed
+	Sessions []uint64 `json:"sessions"`
+}
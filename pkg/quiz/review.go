@@ -0,0 +1,69 @@
+package quiz
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// SM-2 starting ease, and the floor it can never go under
+	DefaultEaseFactor = 2.5
+	MinEaseFactor     = 1.3
+
+	QualityCorrect = 4
+	QualityWrong   = 1
+)
+
+// Review is the spaced repetition state of one question for one user
+type Review struct {
+	QuestionID  uint64        `json:"question_id"`
+	Repetitions uint64        `json:"repetitions"`
+	EaseFactor  float64       `json:"ease_factor"`
+	Interval    time.Duration `json:"interval"`
+	Due         time.Time     `json:"due"`
+}
+
+// NewReview returns the state of a question never seen before, due right away
+func NewReview(questionID uint64, now time.Time) Review {
+	return Review{QuestionID: questionID, EaseFactor: DefaultEaseFactor, Due: now}
+}
+
+func (r Review) IsDue(now time.Time) bool {
+	return !r.Due.After(now)
+}
+
+// Next schedules the review using SM-2, quality goes from 0 (blackout) to 5 (perfect).
+// Wrong answers reset the repetitions so the question comes back the next day,
+// right answers push the question further away every time.
+func (r Review) Next(quality int, now time.Time) Review {
+	quality = min(max(quality, 0), 5)
+
+	if quality < 3 {
+		r.Repetitions = 0
+		r.Interval = 24 * time.Hour
+	} else {
+		switch r.Repetitions {
+		case 0:
+			r.Interval = 24 * time.Hour
+		case 1:
+			r.Interval = 6 * 24 * time.Hour
+		default:
+			days := math.Round(r.Interval.Hours() / 24 * r.EaseFactor)
+			r.Interval = time.Duration(days) * 24 * time.Hour
+		}
+		r.Repetitions++
+	}
+
+	q := float64(5 - quality)
+	r.EaseFactor = max(r.EaseFactor+(0.1-q*(0.08+q*0.02)), MinEaseFactor)
+	r.Due = now.Add(r.Interval)
+	return r
+}
+
+// QualityOf maps a plain right/wrong answer to a SM-2 quality
+func QualityOf(correct bool) int {
+	if correct {
+		return QualityCorrect
+	}
+	return QualityWrong
+}
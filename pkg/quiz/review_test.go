@@ -0,0 +1,57 @@
+package quiz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReviewNext(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	review := NewReview(1, now)
+	if !review.IsDue(now) {
+		t.Fatalf("Expected new review to be due")
+	}
+
+	tests := []struct {
+		name         string
+		correct      bool
+		wantInterval time.Duration
+		wantReps     uint64
+	}{
+		{name: "first correct", correct: true, wantInterval: day, wantReps: 1},
+		{name: "second correct", correct: true, wantInterval: 6 * day, wantReps: 2},
+		{name: "third correct", correct: true, wantInterval: 15 * day, wantReps: 3},
+		{name: "wrong resets", correct: false, wantInterval: day, wantReps: 0},
+	}
+
+	for _, test := range tests {
+		review = review.Next(QualityOf(test.correct), now)
+		if review.Interval != test.wantInterval {
+			t.Fatalf("%s: expected interval %v, got %v", test.name, test.wantInterval, review.Interval)
+		}
+		if review.Repetitions != test.wantReps {
+			t.Fatalf("%s: expected repetitions %d, got %d", test.name, test.wantReps, review.Repetitions)
+		}
+		if !review.Due.Equal(now.Add(test.wantInterval)) {
+			t.Fatalf("%s: expected due %v, got %v", test.name, now.Add(test.wantInterval), review.Due)
+		}
+		if review.IsDue(now) {
+			t.Fatalf("%s: expected review not to be due", test.name)
+		}
+	}
+}
+
+func TestReviewEaseFactorFloor(t *testing.T) {
+	t.Parallel()
+	review := NewReview(1, time.Now())
+	for range 10 {
+		review = review.Next(QualityWrong, time.Now())
+	}
+
+	if review.EaseFactor != MinEaseFactor {
+		t.Fatalf("Expected ease factor %f, got %f", MinEaseFactor, review.EaseFactor)
+	}
+}
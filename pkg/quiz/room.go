@@ -0,0 +1,73 @@
+package quiz
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Types of the messages exchanged in a live room, the comment tells who sends them
+const (
+	RoomMessageRoom     = "room"     // server: the code players join with
+	RoomMessagePlayers  = "players"  // server: who is in the room
+	RoomMessageStart    = "start"    // host: start the quiz
+	RoomMessageQuestion = "question" // server: the question everyone answers before the time limit
+	RoomMessageAnswer   = "answer"   // player: option index of the current question
+	RoomMessageRanking  = "ranking"  // server: correct option of the question and the ranking so far
+	RoomMessageFinished = "finished" // server: final ranking, the room is closed
+	RoomMessageError    = "error"    // server: what went wrong
+)
+
+// RoomMaxPoints of a correct answer given right away, half of it rewards speed
+const RoomMaxPoints = 1000
+
+// RoomMessage is the only message of the room websocket, Type tells which fields are set
+type RoomMessage struct {
+	Type        string      `json:"type"`
+	Code        string      `json:"code,omitempty"`
+	Players     []string    `json:"players,omitempty"`
+	Question    *Question   `json:"question,omitempty"`
+	Number      int         `json:"number,omitempty"`
+	Count       int         `json:"count,omitempty"`
+	TimeLimitMs int64       `json:"time_limit_ms,omitempty"`
+	Option      *int        `json:"option,omitempty"`
+	Ranking     []RoomScore `json:"ranking,omitempty"`
+	Error       string      `json:"error,omitempty"`
+}
+
+func (m RoomMessage) TimeLimit() time.Duration {
+	return time.Duration(m.TimeLimitMs) * time.Millisecond
+}
+
+type RoomScore struct {
+	Rank    uint64 `json:"rank"`
+	Player  string `json:"player"`
+	Score   uint64 `json:"score"`
+	Correct uint64 `json:"correct"`
+	// points of the last question
+	Points uint64 `json:"points"`
+}
+
+// RoomPoints gives half the points for a correct answer and the other half the faster it came
+func RoomPoints(correct bool, taken time.Duration, limit time.Duration) uint64 {
+	if !correct || taken > limit {
+		return 0
+	}
+	if limit <= 0 {
+		return RoomMaxPoints
+	}
+	speed := float64(limit-max(taken, 0)) / float64(limit)
+	return RoomMaxPoints/2 + uint64(speed*RoomMaxPoints/2)
+}
+
+// Rank sorts the scores, highest first, and numbers them
+func Rank(scores []RoomScore) []RoomScore {
+	slices.SortStableFunc(scores, func(a, b RoomScore) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Player, b.Player))
+	})
+	for i := range scores {
+		scores[i].Rank = uint64(i + 1)
+	}
+	return scores
+}
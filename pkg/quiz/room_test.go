@@ -0,0 +1,41 @@
+package quiz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoomPoints(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		correct bool
+		taken   time.Duration
+		want    uint64
+	}{
+		{name: "wrong", correct: false, taken: 0, want: 0},
+		{name: "right away", correct: true, taken: 0, want: RoomMaxPoints},
+		{name: "half time", correct: true, taken: 5 * time.Second, want: 750},
+		{name: "last moment", correct: true, taken: 10 * time.Second, want: RoomMaxPoints / 2},
+		{name: "too late", correct: true, taken: 11 * time.Second, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := RoomPoints(test.correct, test.taken, 10*time.Second); got != test.want {
+				t.Fatalf("RoomPoints() got: %d, want: %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	t.Parallel()
+	ranking := Rank([]RoomScore{{Player: "b", Score: 500}, {Player: "c", Score: 900}, {Player: "a", Score: 500}})
+	want := []string{"c", "a", "b"}
+	for i, score := range ranking {
+		if score.Player != want[i] || score.Rank != uint64(i+1) {
+			t.Fatalf("Rank() got: %+v, want order: %v", ranking, want)
+		}
+	}
+}
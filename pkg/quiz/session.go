@@ -0,0 +1,84 @@
+package quiz
+
+import "time"
+
+// Session is one attempt at a quiz, time limits are enforced with the server clock
+type Session struct {
+	ID                  uint64         `json:"id"`
+	Quiz                string         `json:"quiz,omitempty"`
+	Questions           []Question     `json:"questions"`
+	StartedAt           time.Time      `json:"started_at"`
+	TimeLimitMs         int64          `json:"time_limit_ms,omitempty"`
+	QuestionTimeLimitMs int64          `json:"question_time_limit_ms,omitempty"`
+	Answers             []AnswerRecord `json:"answers"`
+}
+
+// AnswerRecord is what the server saw when an answer arrived, late answers score zero
+type AnswerRecord struct {
+	QuestionID  uint64    `json:"question_id"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	TimeTakenMs int64     `json:"time_taken_ms"`
+	Late        bool      `json:"late"`
+	Correct     bool      `json:"-"`
+	// QuestionVersion the answer was scored against, zero when unknown like in the answers of
+	// archives before version 3, which are never regraded
+	QuestionVersion uint64 `json:"question_version,omitempty"`
+	// Option chosen, its index in the canonical options of the question version the answer was
+	// scored against, -1 when unanswered
+	Option int `json:"-"`
+	// Explanation of the answer of the question, in the locale of the request
+	Explanation string `json:"explanation,omitempty"`
+}
+
+// Correct counts the right answers so far
+func (s Session) Correct() uint64 {
+	correct := uint64(0)
+	for _, a := range s.Answers {
+		if a.Correct {
+			correct++
+		}
+	}
+	return correct
+}
+
+func (s Session) TimeLimit() time.Duration {
+	return time.Duration(s.TimeLimitMs) * time.Millisecond
+}
+
+func (s Session) QuestionTimeLimit() time.Duration {
+	return time.Duration(s.QuestionTimeLimitMs) * time.Millisecond
+}
+
+// Deadline of the whole session, false if the session is not timed
+func (s Session) Deadline() (time.Time, bool) {
+	if s.TimeLimitMs <= 0 {
+		return time.Time{}, false
+	}
+	return s.StartedAt.Add(s.TimeLimit()), true
+}
+
+// LastActivity is when the clock of the next question started
+func (s Session) LastActivity() time.Time {
+	if len(s.Answers) == 0 {
+		return s.StartedAt
+	}
+	return s.Answers[len(s.Answers)-1].SubmittedAt
+}
+
+func (s Session) HasQuestion(questionID uint64) bool {
+	for _, q := range s.Questions {
+		if q.ID == questionID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Session) IsAnswered(questionID uint64) bool {
+	for _, a := range s.Answers {
+		if a.QuestionID == questionID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package quiz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionDeadline(t *testing.T) {
+	t.Parallel()
+	startedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	session := Session{StartedAt: startedAt}
+	if _, ok := session.Deadline(); ok {
+		t.Fatalf("Expected session without time limit to have no deadline")
+	}
+
+	session.TimeLimitMs = time.Minute.Milliseconds()
+	deadline, ok := session.Deadline()
+	if !ok || !deadline.Equal(startedAt.Add(time.Minute)) {
+		t.Fatalf("Deadline does not match, got: %v, want: %v", deadline, startedAt.Add(time.Minute))
+	}
+}
+
+func TestSessionLastActivity(t *testing.T) {
+	t.Parallel()
+	startedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	session := Session{StartedAt: startedAt, Questions: []Question{{ID: 1}, {ID: 2}}}
+
+	if !session.LastActivity().Equal(startedAt) {
+		t.Fatalf("LastActivity does not match, got: %v, want: %v", session.LastActivity(), startedAt)
+	}
+
+	submittedAt := startedAt.Add(time.Second)
+	session.Answers = append(session.Answers, AnswerRecord{QuestionID: 1, SubmittedAt: submittedAt})
+	if !session.LastActivity().Equal(submittedAt) {
+		t.Fatalf("LastActivity does not match, got: %v, want: %v", session.LastActivity(), submittedAt)
+	}
+
+	if !session.IsAnswered(1) || session.IsAnswered(2) {
+		t.Fatalf("Expected only question 1 to be answered")
+	}
+
+	if !session.HasQuestion(2) || session.HasQuestion(3) {
+		t.Fatalf("Expected session to have question 2 and not 3")
+	}
+}
@@ -0,0 +1,11 @@
+package quiz
+
+import "time"
+
+// APIVersions is served by the discovery endpoint so clients pick a version both sides support
+type APIVersions struct {
+	Current   string   `json:"current"`
+	Supported []string `json:"supported"`
+	// the unversioned paths are deprecated aliases of Current until then
+	Sunset time.Time `json:"sunset"`
+}
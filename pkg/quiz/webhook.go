@@ -0,0 +1,140 @@
+package quiz
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EventUserCreated      = "user.created"
+	EventAttemptSubmitted = "attempt.submitted"
+	EventQuizPassed       = "quiz.passed"
+)
+
+var WebhookEvents = []string{EventUserCreated, EventAttemptSubmitted, EventQuizPassed}
+
+// Headers of the webhook deliveries
+const (
+	HeaderWebhookEvent     = "X-Quiz-Event"
+	HeaderWebhookDelivery  = "X-Quiz-Delivery"
+	HeaderWebhookSignature = "X-Quiz-Signature"
+)
+
+const (
+	DeliveryPending = "pending"
+	DeliveryDead    = "dead"
+)
+
+// webhookSecretMinLength keeps the signatures out of reach of a brute force
+const webhookSecretMinLength = 16
+
+// Webhook subscribes a URL to some events, every payload is signed with the secret
+type Webhook struct {
+	Slug   string   `json:"slug"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is only sent by the client, the server never answers it back
+	Secret string `json:"secret,omitempty"`
+}
+
+func (w Webhook) Validate() error {
+	if !slugRegexp.MatchString(w.Slug) {
+		return &FieldError{Field: "slug", Reason: fmt.Sprintf("`%s`, use lowercase letters, digits and dashes", w.Slug)}
+	}
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &FieldError{Field: "url", Reason: fmt.Sprintf("`%s`, use an absolute http or https url", w.URL)}
+	}
+	if len(w.Events) == 0 {
+		return &FieldError{Field: "events", Reason: fmt.Sprintf("subscribe to at least one of %v", WebhookEvents)}
+	}
+	for _, event := range w.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return &FieldError{Field: "events", Reason: fmt.Sprintf("`%s`, try: %v", event, WebhookEvents)}
+		}
+	}
+	if len(w.Secret) < webhookSecretMinLength {
+		return &FieldError{Field: "secret", Reason: fmt.Sprintf("use at least %d characters", webhookSecretMinLength)}
+	}
+	return nil
+}
+
+func (w Webhook) Subscribes(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// WebhookEvent is the body of a delivery
+type WebhookEvent struct {
+	ID        uint64           `json:"id"`
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Data      WebhookEventData `json:"data"`
+}
+
+// WebhookEventData is about a user, attempts are the ones of a completed session
+type WebhookEventData struct {
+	User    string          `json:"user"`
+	Quiz    string          `json:"quiz,omitempty"`
+	Attempt *AttemptResults `json:"attempt,omitempty"`
+}
+
+// WebhookDelivery of an event to one webhook, retried until delivered or dead
+type WebhookDelivery struct {
+	ID        uint64       `json:"id"`
+	Webhook   string       `json:"webhook"`
+	Event     WebhookEvent `json:"event"`
+	Status    string       `json:"status"`
+	Attempts  uint64       `json:"attempts"`
+	LastError string       `json:"last_error,omitempty"`
+}
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// SignWebhook signs the body sent at t, the signature goes in the HeaderWebhookSignature header
+// as `t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">`
+func SignWebhook(secret string, body []byte, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + signature(secret, timestamp, body)
+}
+
+// VerifyWebhook checks the signature header of a delivery, deliveries signed more than tolerance
+// before now are refused so a captured delivery cannot be replayed later
+func VerifyWebhook(secret string, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp, v1 string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			v1 = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || v1 == "" {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: signed %s ago", ErrInvalidSignature, age)
+	}
+	if !hmac.Equal([]byte(v1), []byte(signature(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func signature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
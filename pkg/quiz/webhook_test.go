@@ -0,0 +1,63 @@
+package quiz
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWebhookValidate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		webhook Webhook
+		isErr   bool
+	}{
+		{name: "valid", webhook: Webhook{Slug: "chat", URL: "https://chat.example.com/hooks", Events: []string{EventQuizPassed}, Secret: "0123456789abcdef"}, isErr: false},
+		{name: "invalid slug", webhook: Webhook{Slug: "Chat", URL: "https://chat.example.com/hooks", Events: []string{EventQuizPassed}, Secret: "0123456789abcdef"}, isErr: true},
+		{name: "relative url", webhook: Webhook{Slug: "chat", URL: "/hooks", Events: []string{EventQuizPassed}, Secret: "0123456789abcdef"}, isErr: true},
+		{name: "no events", webhook: Webhook{Slug: "chat", URL: "https://chat.example.com/hooks", Secret: "0123456789abcdef"}, isErr: true},
+		{name: "unknown event", webhook: Webhook{Slug: "chat", URL: "https://chat.example.com/hooks", Events: []string{"quiz.failed"}, Secret: "0123456789abcdef"}, isErr: true},
+		{name: "short secret", webhook: Webhook{Slug: "chat", URL: "https://chat.example.com/hooks", Events: []string{EventQuizPassed}, Secret: "secret"}, isErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.webhook.Validate()
+			if test.isErr != (err != nil) {
+				t.Fatalf("Validate() got: %v, want error: %t", err, test.isErr)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":0}`)
+	header := SignWebhook("0123456789abcdef", body, now)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		isErr  bool
+	}{
+		{name: "valid", secret: "0123456789abcdef", header: header, body: body, now: now.Add(time.Minute), isErr: false},
+		{name: "other secret", secret: "fedcba9876543210", header: header, body: body, now: now, isErr: true},
+		{name: "other body", secret: "0123456789abcdef", header: header, body: []byte(`{"id":1}`), now: now, isErr: true},
+		{name: "replayed", secret: "0123456789abcdef", header: header, body: body, now: now.Add(time.Hour), isErr: true},
+		{name: "malformed", secret: "0123456789abcdef", header: "v1=abc", body: body, now: now, isErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := VerifyWebhook(test.secret, test.header, test.body, test.now, 5*time.Minute)
+			if test.isErr != errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("VerifyWebhook() got: %v, want error: %t", err, test.isErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+// Package quizpb holds the protobuf messages of proto/quiz/v1/quiz.proto and the QuizService
+// client and server of gRPC, both generated by protoc.
+package quizpb
+
+//go:generate protoc -I ../../proto --go_out=. --go_opt=module=github.com/vrnvu/temp/pkg/quizpb --go-grpc_out=. --go-grpc_opt=module=github.com/vrnvu/temp/pkg/quizpb quiz/v1/quiz.proto